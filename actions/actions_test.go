@@ -1,10 +1,13 @@
 package actions
 
 import (
+	"buftester/models"
 	"testing"
+	"time"
 
 	_ "github.com/gobuffalo/helpers"
 	"github.com/gobuffalo/helpers/paths"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/packr/v2"
 	"github.com/gobuffalo/suite"
 	_ "github.com/gobuffalo/tags"
@@ -26,6 +29,40 @@ func Test_ActionSuite(t *testing.T) {
 	suite.Run(t, as)
 }
 
+// login creates a user and signs them in for the requests that follow.
+func (as *ActionSuite) login(email string) *models.User {
+	user := &models.User{Email: email, Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+	as.Session.Set("current_user_id", user.ID)
+	return user
+}
+
+// newBoss creates a boss owned by the user.
+func (as *ActionSuite) newBoss(user *models.User, name string) *models.Boss {
+	b := &models.Boss{Name: name, UserID: nulls.NewUUID(user.ID)}
+	as.NoError(models.DB.Create(b))
+	return b
+}
+
+// newContract gives the user an hourly contract with a boss of their own.
+func (as *ActionSuite) newContract(user *models.User, boss string) *models.Contract {
+	b := as.newBoss(user, boss)
+	contract := &models.Contract{Rate: 60, BossID: b.ID, UserID: user.ID}
+	as.NoError(models.DB.Create(contract))
+	contract.Boss = b
+	return contract
+}
+
+// newTask logs an hour on the contract today.
+func (as *ActionSuite) newTask(contract *models.Contract, description string) *models.Task {
+	now := time.Now()
+	task := &models.Task{Rate: contract.Rate, Duration: 60, StartTime: now, EndTime: now, ContractID: contract.ID, Description: description}
+	as.NoError(models.DB.Create(task))
+	return task
+}
+
 func Test_Helpers(t *testing.T) {
 	type Test struct {
 		input string
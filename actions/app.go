@@ -2,6 +2,7 @@ package actions
 
 import (
 	"log"
+	"net/http"
 	"reflect"
 
 	"github.com/gobuffalo/buffalo"
@@ -17,6 +18,7 @@ import (
 	csrf "github.com/gobuffalo/mw-csrf"
 	i18n "github.com/gobuffalo/mw-i18n"
 	"github.com/gobuffalo/packr/v2"
+	"github.com/pkg/errors"
 )
 
 // ENV is used to help switch settings based on where the
@@ -51,6 +53,9 @@ func App() *buffalo.App {
 		// Log request parameters (filters apply).
 		app.Use(paramlogger.ParameterLogger)
 
+		// Turn away oversized uploads before anything reads the form.
+		app.Use(limitBody(maxRequestSize))
+
 		// Protect against CSRF attacks. https://www.owasp.org/index.php/Cross-Site_Request_Forgery_(CSRF)
 		// Remove to disable this.
 		app.Use(csrf.New)
@@ -79,12 +84,76 @@ func App() *buffalo.App {
 
 		app.GET("/users/{user_id}", Authorize(IsOwner(UsersShow)))
 
+		// Calendar feeds are authenticated by their secret token.
+		app.GET("/calendar/{token}/tasks.ics", CalendarFeed)
+
+		// Client portals are authenticated by their secret token.
+		app.GET("/portal/{token}", PortalShow)
+		app.GET("/portal/{token}/invoices/{invoice_id}/pdf", PortalInvoicePDF)
+
+		// Boss contacts review timesheets through an emailed link.
+		app.GET("/timesheets/review/{token}", TimesheetsReviewShow)
+		app.POST("/timesheets/review/{token}", TimesheetsReviewUpdate)
+
 		c := app.Group("/users")
 		c.POST("/{user_id}", UsersUpdate)
 		c.GET("/{user_id}/contracts", UsersContractsIndex)
 		c.POST("/{user_id}/contracts", UsersContractCreate)
 		c.GET("/{user_id}/contracts/new", UsersContractsNew)
 		c.GET("/{user_id}/contracts/{contract_id}", UsersContractShow)
+		c.POST("/{user_id}/contracts/{contract_id}/invoices", IsOwner(UsersInvoicesCreate))
+		c.GET("/{user_id}/invoices", IsOwner(UsersInvoicesIndex))
+		c.GET("/{user_id}/invoices/{invoice_id}", IsOwner(UsersInvoicesShow))
+		c.GET("/{user_id}/invoices/{invoice_id}/pdf", IsOwner(UsersInvoicesPDF))
+		c.POST("/{user_id}/invoices/{invoice_id}/email", IsOwner(UsersInvoicesEmail))
+		c.POST("/{user_id}/invoices/{invoice_id}/send", IsOwner(UsersInvoicesSend))
+		c.POST("/{user_id}/invoices/{invoice_id}/void", IsOwner(UsersInvoicesVoid))
+		c.POST("/{user_id}/invoices/{invoice_id}/payments", IsOwner(UsersInvoicesPaymentsCreate))
+		c.POST("/{user_id}/invoices/{invoice_id}/attachments", IsOwner(UsersInvoiceAttachmentsCreate))
+		c.POST("/{user_id}/tasks/{task_id}/attachments", IsOwner(UsersTaskAttachmentsCreate))
+		c.GET("/{user_id}/attachments/{attachment_id}", IsOwner(UsersAttachmentShow))
+		c.DELETE("/{user_id}/attachments/{attachment_id}", IsOwner(UsersAttachmentDestroy))
+		c.GET("/{user_id}/receivables", IsOwner(UsersReceivables))
+		c.GET("/{user_id}/taxes", IsOwner(UsersTaxesIndex))
+		c.POST("/{user_id}/tax_rates", IsOwner(UsersTaxRatesCreate))
+		c.DELETE("/{user_id}/tax_rates/{tax_rate_id}", IsOwner(UsersTaxRatesDestroy))
+		c.POST("/{user_id}/tax_rounding", IsOwner(UsersTaxRoundingUpdate))
+		c.POST("/{user_id}/contracts/{contract_id}/taxes", IsOwner(UsersContractTaxesUpdate))
+		c.POST("/{user_id}/contracts/{contract_id}/portal", IsOwner(UsersContractPortalCreate))
+		c.DELETE("/{user_id}/contracts/{contract_id}/portal", IsOwner(UsersContractPortalDestroy))
+		c.POST("/{user_id}/contracts/{contract_id}/cap", IsOwner(UsersContractCapUpdate))
+		c.POST("/{user_id}/contracts/{contract_id}/dates", IsOwner(UsersContractDatesUpdate))
+		c.POST("/{user_id}/contracts/{contract_id}/archive", IsOwner(UsersContractArchive))
+		c.DELETE("/{user_id}/contracts/{contract_id}/archive", IsOwner(UsersContractUnarchive))
+		c.POST("/{user_id}/contracts/{contract_id}/terms", IsOwner(UsersContractTermsUpdate))
+		c.POST("/{user_id}/contracts/{contract_id}/projects", IsOwner(UsersContractProjectsCreate))
+		c.DELETE("/{user_id}/contracts/{contract_id}/projects/{project_id}", IsOwner(UsersContractProjectDestroy))
+		c.POST("/{user_id}/contracts/{contract_id}/milestones", IsOwner(UsersContractMilestonesCreate))
+		c.POST("/{user_id}/contracts/{contract_id}/milestones/{milestone_id}/complete", IsOwner(UsersContractMilestoneComplete))
+		c.DELETE("/{user_id}/contracts/{contract_id}/milestones/{milestone_id}/complete", IsOwner(UsersContractMilestoneReopen))
+		c.DELETE("/{user_id}/contracts/{contract_id}/milestones/{milestone_id}", IsOwner(UsersContractMilestoneDestroy))
+		c.POST("/{user_id}/contracts/{contract_id}/expenses", IsOwner(UsersContractExpensesCreate))
+		c.POST("/{user_id}/contracts/{contract_id}/expenses/{expense_id}/receipt", IsOwner(UsersContractExpenseReceiptCreate))
+		c.DELETE("/{user_id}/contracts/{contract_id}/expenses/{expense_id}", IsOwner(UsersContractExpenseDestroy))
+		c.POST("/{user_id}/contracts/{contract_id}/mileage", IsOwner(UsersContractMileageUpdate))
+		c.GET("/{user_id}/notifications", IsOwner(UsersNotificationsIndex))
+		c.POST("/{user_id}/notifications/read", IsOwner(UsersNotificationsRead))
+		c.GET("/{user_id}/tasks/export", IsOwner(UsersTasksExport))
+		c.GET("/{user_id}/tags", IsOwner(UsersTagsIndex))
+		c.GET("/{user_id}/search", IsOwner(UsersSearch))
+		c.GET("/{user_id}/imports/new", IsOwner(UsersImportsNew))
+		c.POST("/{user_id}/imports/preview", IsOwner(UsersImportsPreview))
+		c.POST("/{user_id}/imports", IsOwner(UsersImportsCreate))
+		c.POST("/{user_id}/imports/external", IsOwner(UsersImportsExternal))
+		c.GET("/{user_id}/calendar_imports/new", IsOwner(UsersCalendarImportsNew))
+		c.POST("/{user_id}/calendar_imports/preview", IsOwner(UsersCalendarImportsPreview))
+		c.POST("/{user_id}/calendar_imports", IsOwner(UsersCalendarImportsCreate))
+		c.POST("/{user_id}/calendar_rules", IsOwner(UsersCalendarRulesCreate))
+		c.DELETE("/{user_id}/calendar_rules/{rule_id}", IsOwner(UsersCalendarRulesDestroy))
+		c.GET("/{user_id}/timesheets", IsOwner(UsersTimesheetsIndex))
+		c.POST("/{user_id}/timesheets", IsOwner(UsersTimesheetsCreate))
+		c.POST("/{user_id}/calendar_token", IsOwner(UsersCalendarTokenCreate))
+		c.DELETE("/{user_id}/calendar_token", IsOwner(UsersCalendarTokenDestroy))
 		c.Use(Authorize)
 
 		b := app.Group("/bosses")
@@ -92,14 +161,33 @@ func App() *buffalo.App {
 		b.GET("/new", BossesNew)
 		b.POST("/create", BossesCreate)
 		b.GET("/{boss_id}", BossesShow)
+		b.GET("/{boss_id}/edit", BossesEdit)
+		b.POST("/{boss_id}/edit", BossesUpdate)
+		b.POST("/{boss_id}/shares", BossesSharesCreate)
+		b.DELETE("/{boss_id}/shares/{user_id}", BossesSharesDestroy)
 		b.Use(Authorize)
 
+		o := app.Group("/organizations")
+		o.GET("/index", OrganizationsIndex)
+		o.POST("/create", OrganizationsCreate)
+		o.GET("/{organization_id}", OrganizationsShow)
+		o.POST("/{organization_id}/members", OrganizationsMembersCreate)
+		o.POST("/{organization_id}/members/{user_id}", OrganizationsMembersUpdate)
+		o.DELETE("/{organization_id}/members/{user_id}", OrganizationsMembersDestroy)
+		o.Use(Authorize)
+
+		ts := app.Group("/timesheets")
+		ts.GET("/approvals", TimesheetsApprovals)
+		ts.POST("/{timesheet_id}/review", TimesheetsReview)
+		ts.Use(Authorize)
+
 		app.POST("/users/{user_id}/contracts/{contract_id}/task/create", Authorize(UserTaskCreate))
 
 		t := app.Group("/tasks")
 		t.GET("/{task_id}", TasksShow)
 		t.GET("/{task_id}/edit", TasksEdit)
 		t.POST("/{task_id}/edit", TasksUpdate)
+		t.POST("/{task_id}/comments", TasksCommentsCreate)
 		t.Use(Authorize)
 
 		admin := app.Group("/admin")
@@ -140,6 +228,26 @@ func forceSSL() buffalo.MiddlewareFunc {
 	})
 }
 
+// maxRequestSize caps request bodies: the largest attachment with room to
+// spare for the rest of the form.
+const maxRequestSize = models.MaxAttachmentSize + 1024*1024
+
+// limitBody will return a middleware that stops reading a request body
+// past max bytes, so an oversized upload is rejected as it arrives instead
+// of being buffered first.
+func limitBody(max int64) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			req := c.Request()
+			if req.ContentLength > max {
+				return c.Error(http.StatusRequestEntityTooLarge, errors.New("request body is too large"))
+			}
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, max)
+			return next(c)
+		}
+	}
+}
+
 // Register event listeners.
 func init() {
 	events.Listen(func(e events.Event) {
@@ -0,0 +1,182 @@
+package actions
+
+import (
+	"buftester/models"
+	"buftester/storage"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// attachmentStore keeps the attached files. ATTACHMENTS_DIR sets where
+// they are written on disk.
+var attachmentStore storage.Store = storage.NewDisk(envy.Get("ATTACHMENTS_DIR", "tmp/attachments"))
+
+// saveAttachment stores the uploaded File for the attachment's task or
+// invoice. Validation errors, including a missing file, come back as
+// verrs; the file is only written once the row is saved.
+func saveAttachment(c buffalo.Context, tx *pop.Connection, userID uuid.UUID, attach func(a *models.Attachment)) (*validate.Errors, error) {
+	f, err := c.File("File")
+	if err != nil || !f.Valid() {
+		verrs := validate.NewErrors()
+		verrs.Add("file", "Choose a file to attach.")
+		return verrs, nil
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, errors.WithStack(err)
+	}
+	a := models.NewAttachment(userID, f.Filename, f.Size, head[:n])
+	attach(a)
+
+	verrs, err := tx.ValidateAndCreate(a)
+	if err != nil || verrs.HasAny() {
+		return verrs, errors.WithStack(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return verrs, errors.WithStack(attachmentStore.Put(a.StorageKey, f))
+}
+
+// UsersTaskAttachmentsCreate attaches a file to one of the user's tasks.
+func UsersTaskAttachmentsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	userID, err := uuid.FromString(c.Param("user_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+	task := &models.Task{}
+	err = tx.Where("contract_id IN (SELECT id FROM contracts WHERE user_id = ?)", userID).Find(task, c.Param("task_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that task.")
+		return c.Redirect(303, "/users/%s/contracts", userID)
+	}
+
+	verrs, err := saveAttachment(c, tx, userID, func(a *models.Attachment) {
+		a.TaskID = nulls.NewInt(task.ID)
+	})
+	if err != nil {
+		return err
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+	} else {
+		c.Flash().Add("success", "File attached.")
+	}
+	return c.Redirect(303, "/tasks/%d", task.ID)
+}
+
+// UsersInvoiceAttachmentsCreate attaches a file to one of the user's
+// invoices.
+func UsersInvoiceAttachmentsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	inv, err := loadInvoice(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that invoice.")
+		return c.Redirect(303, "/users/%s/invoices", c.Param("user_id"))
+	}
+
+	verrs, err := saveAttachment(c, tx, inv.UserID, func(a *models.Attachment) {
+		a.InvoiceID = nulls.NewInt(inv.ID)
+	})
+	if err != nil {
+		return err
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+	} else {
+		c.Flash().Add("success", "File attached.")
+	}
+	return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+}
+
+// attachmentReturnPath is the page an attachment is listed on. Receipts
+// are listed on their expense's contract.
+func attachmentReturnPath(tx *pop.Connection, a *models.Attachment) string {
+	switch {
+	case a.TaskID.Valid:
+		return fmt.Sprintf("/tasks/%d", a.TaskID.Int)
+	case a.ExpenseID.Valid:
+		e := &models.Expense{}
+		if err := tx.Find(e, a.ExpenseID.Int); err != nil {
+			return fmt.Sprintf("/users/%s/contracts", a.UserID)
+		}
+		return fmt.Sprintf("/users/%s/contracts/%d#expenses", a.UserID, e.ContractID)
+	}
+	return fmt.Sprintf("/users/%s/invoices/%d", a.UserID, a.InvoiceID.Int)
+}
+
+// findAttachment loads one of the route user's attachments.
+func findAttachment(c buffalo.Context, tx *pop.Connection) (*models.Attachment, error) {
+	userID, err := uuid.FromString(c.Param("user_id"))
+	if err != nil {
+		return nil, err
+	}
+	return models.FindAttachment(tx, userID, c.Param("attachment_id"))
+}
+
+// UsersAttachmentShow downloads an attachment. Files are always sent as
+// downloads so they are never rendered as part of the site.
+func UsersAttachmentShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	a, err := findAttachment(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that file.")
+		return c.Redirect(307, "/")
+	}
+	f, err := attachmentStore.Open(a.StorageKey)
+	if err != nil {
+		if errors.Cause(err) == storage.ErrNotFound {
+			c.Flash().Add("warning", "That file is missing.")
+			return c.Redirect(307, attachmentReturnPath(tx, a))
+		}
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+
+	res := c.Response()
+	res.Header().Set("Content-Type", a.ContentType)
+	res.Header().Set("Content-Disposition", a.Disposition())
+	res.Header().Set("Content-Length", strconv.Itoa(a.Size))
+	res.Header().Set("X-Content-Type-Options", "nosniff")
+	res.WriteHeader(http.StatusOK)
+	_, err = io.Copy(res, f)
+	return errors.WithStack(err)
+}
+
+// UsersAttachmentDestroy removes an attachment and its file.
+func UsersAttachmentDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	a, err := findAttachment(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that file.")
+		return c.Redirect(303, "/")
+	}
+	if err := tx.Destroy(a); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := attachmentStore.Delete(a.StorageKey); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "File removed.")
+	return c.Redirect(303, attachmentReturnPath(tx, a))
+}
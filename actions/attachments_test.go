@@ -0,0 +1,134 @@
+package actions
+
+import (
+	"buftester/models"
+	"buftester/storage"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/httptest"
+)
+
+func (as *ActionSuite) Test_Users_Attachment_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/attachments/1").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+// useTempStore keeps attachments in a temporary directory until the
+// returned func is called.
+func (as *ActionSuite) useTempStore() func() {
+	dir, err := ioutil.TempDir("", "attachments")
+	as.NoError(err)
+	store := attachmentStore
+	attachmentStore = storage.NewDisk(dir)
+	return func() {
+		attachmentStore = store
+		os.RemoveAll(dir)
+	}
+}
+
+// notes is a plain text file to upload.
+func notes() httptest.File {
+	return httptest.File{Reader: strings.NewReader("Call notes"), ParamName: "File", FileName: "notes.txt"}
+}
+
+func (as *ActionSuite) Test_Users_Task_Attachments() {
+	defer as.useTempStore()()
+	user := as.login("files@example.com")
+	task := as.newTask(as.newContract(user, "ACME"), "Kickoff call")
+
+	res, err := as.HTML("/users/%s/tasks/%d/attachments", user.ID, task.ID).MultiPartPost(&struct{}{}, notes())
+	as.NoError(err)
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/tasks/%d", task.ID), res.Location())
+
+	a := &models.Attachment{}
+	as.NoError(models.DB.Where("task_id = ?", task.ID).First(a))
+	as.Equal("notes.txt", a.Filename)
+	as.Equal("text/plain", a.ContentType)
+
+	res = as.HTML("/users/%s/attachments/%d", user.ID, a.ID).Get()
+	as.Equal(200, res.Code)
+	as.Equal("Call notes", res.Body.String())
+	as.Contains(res.Header().Get("Content-Disposition"), "attachment")
+
+	res = as.HTML("/users/%s/attachments/%d", user.ID, a.ID).Delete()
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/tasks/%d", task.ID), res.Location())
+	count, err := models.DB.Where("task_id = ?", task.ID).Count(&models.Attachment{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_Users_Invoice_Attachments() {
+	defer as.useTempStore()()
+	user := as.login("files@example.com")
+	contract := as.newContract(user, "ACME")
+	now := time.Now()
+	inv := &models.Invoice{Number: "1", Status: models.InvoiceDraft, UserID: user.ID, ContractID: contract.ID, IssuedOn: now, DueOn: now}
+	as.NoError(models.DB.Create(inv))
+
+	res, err := as.HTML("/users/%s/invoices/%d/attachments", user.ID, inv.ID).MultiPartPost(&struct{}{}, notes())
+	as.NoError(err)
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/invoices/%d", user.ID, inv.ID), res.Location())
+
+	count, err := models.DB.Where("invoice_id = ?", inv.ID).Count(&models.Attachment{})
+	as.NoError(err)
+	as.Equal(1, count)
+}
+
+func (as *ActionSuite) Test_Users_Attachment_Other_User() {
+	defer as.useTempStore()()
+	owner := as.login("files@example.com")
+	task := as.newTask(as.newContract(owner, "ACME"), "Kickoff call")
+	_, err := as.HTML("/users/%s/tasks/%d/attachments", owner.ID, task.ID).MultiPartPost(&struct{}{}, notes())
+	as.NoError(err)
+	a := &models.Attachment{}
+	as.NoError(models.DB.Where("task_id = ?", task.ID).First(a))
+
+	other := as.login("other@example.com")
+	res := as.HTML("/users/%s/attachments/%d", owner.ID, a.ID).Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+
+	res = as.HTML("/users/%s/attachments/%d", other.ID, a.ID).Get()
+	as.Equal(307, res.Code)
+	as.Equal("/", res.Location())
+	as.NotContains(res.Body.String(), "Call notes")
+
+	res = as.HTML("/users/%s/attachments/%d", other.ID, a.ID).Delete()
+	as.Equal(303, res.Code)
+	as.NoError(models.DB.Find(&models.Attachment{}, a.ID))
+}
+
+func (as *ActionSuite) Test_Users_Task_Attachments_Other_User() {
+	defer as.useTempStore()()
+	owner := as.login("files@example.com")
+	task := as.newTask(as.newContract(owner, "ACME"), "Kickoff call")
+
+	other := as.login("other@example.com")
+	res, err := as.HTML("/users/%s/tasks/%d/attachments", other.ID, task.ID).MultiPartPost(&struct{}{}, notes())
+	as.NoError(err)
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/contracts", other.ID), res.Location())
+
+	count, err := models.DB.Where("task_id = ?", task.ID).Count(&models.Attachment{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_Request_Too_Large() {
+	u := "/users/00000000-0000-0000-0000-000000000000/tasks/1/attachments"
+	req, err := http.NewRequest("POST", u, strings.NewReader("x"))
+	as.NoError(err)
+	req.ContentLength = maxRequestSize + 1
+
+	res := as.HTML(u).Perform(req)
+	as.Equal(http.StatusRequestEntityTooLarge, res.Code)
+}
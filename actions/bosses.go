@@ -2,17 +2,22 @@ package actions
 
 import (
 	"buftester/models"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 )
 
-// BossesIndex shows all bosses with a param pager.
+// BossesIndex shows the bosses the current user can see with a param pager.
 func BossesIndex(c buffalo.Context) error {
 	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
 	bosses := []models.Boss{}
 
 	// Use paginator.
@@ -31,7 +36,7 @@ func BossesIndex(c buffalo.Context) error {
 		}
 	}
 
-	q := tx.Paginate(page, perPage)
+	q := tx.Scope(models.BossesVisibleTo(user.ID)).Order("name asc").Paginate(page, perPage)
 	q.Paginator.PerPage = 5
 	err := q.All(&bosses)
 	if err != nil {
@@ -59,6 +64,10 @@ func BossesCreate(c buffalo.Context) error {
 
 	newContract := boss.CreateContract
 
+	// The boss belongs to whoever creates it.
+	user := c.Value("current_user").(*models.User)
+	boss.UserID = nulls.NewUUID(user.ID)
+
 	tx := c.Value("tx").(*pop.Connection)
 	// Validate the data from the html form.
 	verrs, err := tx.ValidateAndCreate(boss)
@@ -78,36 +87,29 @@ func BossesCreate(c buffalo.Context) error {
 		return c.Redirect(303, "/bosses/%d", boss.ID)
 	}
 
-	uid := c.Session().Get("current_user_id")
-	if uid == nil {
-		return errors.WithStack(err)
-	}
-
-	u := &models.User{}
-	err = tx.Find(u, uid)
-	if err != nil {
-		return errors.WithStack(err)
-	}
 	c.Flash().Add("warning", "Set a rate for this contract")
-	return c.Redirect(303, "/users/%s/contracts/new?bid=%d", u.ID, boss.ID)
+	return c.Redirect(303, "/users/%s/contracts/new?bid=%d", user.ID, boss.ID)
 }
 
 // BossesShow returns detail for a single Boss.
 func BossesShow(c buffalo.Context) error {
 	tx := c.Value("tx").(*pop.Connection)
 
-	boss := models.Boss{}
-	err := tx.Find(&boss, c.Param("boss_id"))
+	user := c.Value("current_user").(*models.User)
+
+	boss, err := models.FindBoss(tx, user.ID, c.Param("boss_id"))
 	if err != nil {
 		c.Flash().Add("warning", "Cannot find that boss.")
 		return c.Redirect(307, "/")
 	}
+	if err := tx.Load(boss, "Contacts"); err != nil {
+		return errors.WithStack(err)
+	}
 
-	user := c.Value("current_user").(*models.User)
-
-	// Get contracts for this user only.
+	// Get contracts for this user only. There may be several, told apart
+	// by name.
 	cs := models.Contracts{}
-	q := tx.Where("user_id = ?", user.ID).Where("boss_id = ?", boss.ID)
+	q := tx.Where("user_id = ?", user.ID).Where("boss_id = ?", boss.ID).Order("archived_at IS NOT NULL, name asc")
 	err = q.Eager("User").All(&cs)
 	if err != nil {
 		c.Flash().Add("warning", "Cannot find contracts.")
@@ -116,7 +118,204 @@ func BossesShow(c buffalo.Context) error {
 
 	boss.Contracts = cs
 
+	shares := models.BossShares{}
+	if boss.OwnedBy(user.ID) {
+		if shares, err = boss.Shares(tx); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
 	c.Set("user", user)
 	c.Set("boss", boss)
+	c.Set("shares", shares)
 	return c.Render(http.StatusOK, r.HTML("bosses/show.html"))
 }
+
+// bossContactRow is one contact on the edit form; blank rows add contacts.
+type bossContactRow struct {
+	Index   int
+	Contact models.BossContact
+}
+
+// contactRows lists the contacts followed by a couple of blank rows.
+func contactRows(contacts models.BossContacts) []bossContactRow {
+	rows := []bossContactRow{}
+	for i, bc := range contacts {
+		rows = append(rows, bossContactRow{Index: i, Contact: bc})
+	}
+	for i := 0; i < 2; i++ {
+		rows = append(rows, bossContactRow{Index: len(rows)})
+	}
+	return rows
+}
+
+// ownedBoss loads the boss in the path, provided the current user owns it.
+func ownedBoss(c buffalo.Context, tx *pop.Connection) (*models.Boss, bool) {
+	user := c.Value("current_user").(*models.User)
+	boss, err := models.FindBoss(tx, user.ID, c.Param("boss_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that boss.")
+		return nil, false
+	}
+	if !boss.OwnedBy(user.ID) {
+		c.Flash().Add("warning", "Only the owner can change that boss.")
+		return nil, false
+	}
+	return boss, true
+}
+
+// BossesEdit shows the form to edit a Boss profile and contacts.
+func BossesEdit(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	boss, ok := ownedBoss(c, tx)
+	if !ok {
+		return c.Redirect(307, "/bosses/index")
+	}
+	if err := tx.Load(boss, "Contacts"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	user := c.Value("current_user").(*models.User)
+	orgs, err := models.LoadOrganizations(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("boss", boss)
+	c.Set("contacts", contactRows(boss.Contacts))
+	c.Set("organizations", orgs)
+	return c.Render(http.StatusOK, r.HTML("bosses/edit.html"))
+}
+
+// BossesUpdate responds to POST to update a Boss. Contact rows left without
+// a name are removed.
+func BossesUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	boss, ok := ownedBoss(c, tx)
+	if !ok {
+		return c.Redirect(303, "/bosses/index")
+	}
+
+	user := c.Value("current_user").(*models.User)
+	orgs, err := models.LoadOrganizations(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Only organizations the owner belongs to can take the boss.
+	orgID := nulls.Int{}
+	for _, o := range orgs {
+		if c.Param("OrganizationID") == strconv.Itoa(o.ID) {
+			orgID = nulls.NewInt(o.ID)
+		}
+	}
+
+	// Bind entity to the HTML form.
+	if err := c.Bind(boss); err != nil {
+		return err
+	}
+	boss.PaymentTerms = nulls.Int{}
+	badTerms := false
+	if v := strings.TrimSpace(c.Param("PaymentTerms")); v != "" {
+		terms, err := strconv.Atoi(v)
+		if err != nil {
+			badTerms = true
+		} else {
+			boss.PaymentTerms = nulls.NewInt(terms)
+		}
+	}
+
+	contacts := models.BossContacts{}
+	count, _ := strconv.Atoi(c.Param("ContactCount"))
+	for i := 0; i < count; i++ {
+		bc := models.BossContact{Name: strings.TrimSpace(c.Param(fmt.Sprintf("ContactName%d", i)))}
+		if bc.Name == "" {
+			continue
+		}
+		if v := strings.TrimSpace(c.Param(fmt.Sprintf("ContactEmail%d", i))); v != "" {
+			bc.Email = nulls.NewString(v)
+		}
+		if v := strings.TrimSpace(c.Param(fmt.Sprintf("ContactPhone%d", i))); v != "" {
+			bc.Phone = nulls.NewString(v)
+		}
+		contacts = append(contacts, bc)
+	}
+
+	// Validate the data from the html form.
+	verrs, err := tx.ValidateAndUpdate(boss)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if badTerms {
+		verrs.Add("payment_terms", "Enter the payment terms as a number of days.")
+	}
+	if !verrs.HasAny() {
+		verrs, err = boss.SetContacts(tx, contacts)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if !verrs.HasAny() && orgID != boss.OrganizationID {
+		if err := boss.SetOrganization(tx, orgID); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if verrs.HasAny() {
+		// Nothing is saved; the transaction is rolled back on 422.
+		c.Set("boss", boss)
+		c.Set("contacts", contactRows(contacts))
+		c.Set("organizations", orgs)
+		// Make the errors available inside the html template
+		c.Set("errors", verrs)
+		return c.Render(422, r.HTML("bosses/edit.html"))
+	}
+
+	c.Flash().Add("success", "Boss updated.")
+	return c.Redirect(303, "/bosses/%d", boss.ID)
+}
+
+// BossesSharesCreate gives another user, found by email, access to a boss.
+func BossesSharesCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	boss, ok := ownedBoss(c, tx)
+	if !ok {
+		return c.Redirect(303, "/bosses/index")
+	}
+
+	user, err := boss.ShareWith(tx, c.Param("Email"))
+	if errors.Cause(err) == models.ErrNoSuchUser {
+		c.Flash().Add("warning", "Nobody has signed up with that email.")
+		return c.Redirect(303, "/bosses/%d", boss.ID)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", fmt.Sprintf("%s can now see %s.", user.Email, boss.Name))
+	return c.Redirect(303, "/bosses/%d", boss.ID)
+}
+
+// BossesSharesDestroy takes a user's access to a boss away.
+func BossesSharesDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	boss, ok := ownedBoss(c, tx)
+	if !ok {
+		return c.Redirect(303, "/bosses/index")
+	}
+
+	uid, err := uuid.FromString(c.Param("user_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(303, "/bosses/%d", boss.ID)
+	}
+	if err := boss.Unshare(tx, uid); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Access removed.")
+	return c.Redirect(303, "/bosses/%d", boss.ID)
+}
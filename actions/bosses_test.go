@@ -1,5 +1,10 @@
 package actions
 
+import (
+	"buftester/models"
+	"fmt"
+)
+
 func (as *ActionSuite) Test_Bosses_Show() {
 	as.Fail("Not Implemented!")
 }
@@ -17,3 +22,66 @@ func (as *ActionSuite) Test_Bosses_New() {
 	as.Fail("Not Implemented!")
 }
 
+func (as *ActionSuite) Test_Bosses_Edit_Requires_Login() {
+	res := as.HTML("/bosses/1/edit").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+func (as *ActionSuite) Test_Bosses_Update_Other_Users_Boss() {
+	owner := as.login("owner@example.com")
+	boss := as.newBoss(owner, "Initech")
+
+	other := as.login("other@example.com")
+	own := as.newBoss(other, "ACME")
+
+	res := as.HTML("/bosses/%d/edit", boss.ID).Post(map[string]string{"Name": "Hacked"})
+	as.Equal(303, res.Code)
+	as.Equal("/bosses/index", res.Location())
+
+	// The ID in the form must not redirect the update to another boss.
+	res = as.HTML("/bosses/%d/edit", own.ID).Post(map[string]string{"ID": fmt.Sprint(boss.ID), "Name": "Hacked"})
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/bosses/%d", own.ID), res.Location())
+
+	as.NoError(models.DB.Reload(boss))
+	as.Equal("Initech", boss.Name)
+	as.NoError(models.DB.Reload(own))
+	as.Equal("Hacked", own.Name)
+}
+
+func (as *ActionSuite) Test_Bosses_Show_Other_User() {
+	owner := as.login("owner@example.com")
+	boss := as.newBoss(owner, "Initech")
+
+	as.login("other@example.com")
+	res := as.HTML("/bosses/%d", boss.ID).Get()
+	as.Equal(307, res.Code)
+	as.Equal("/", res.Location())
+
+	res = as.HTML("/bosses/%d/edit", boss.ID).Get()
+	as.Equal(307, res.Code)
+	as.Equal("/bosses/index", res.Location())
+}
+
+func (as *ActionSuite) Test_Bosses_Shared_User() {
+	owner := as.login("owner@example.com")
+	boss := as.newBoss(owner, "Initech")
+	shared := as.login("shared@example.com")
+	_, err := boss.ShareWith(models.DB, shared.Email)
+	as.NoError(err)
+
+	res := as.HTML("/bosses/%d", boss.ID).Get()
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "Initech")
+
+	res = as.HTML("/bosses/%d/edit", boss.ID).Get()
+	as.Equal(307, res.Code)
+	as.Equal("/bosses/index", res.Location())
+
+	res = as.HTML("/bosses/%d/edit", boss.ID).Post(map[string]string{"Name": "Renamed"})
+	as.Equal(303, res.Code)
+	as.Equal("/bosses/index", res.Location())
+	as.NoError(models.DB.Reload(boss))
+	as.Equal("Initech", boss.Name)
+}
@@ -0,0 +1,77 @@
+package actions
+
+import (
+	"buftester/models"
+	"database/sql"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// CalendarFeed serves the tasks of the user owning the token as iCalendar.
+// The token is the only credential, so no session is required.
+func CalendarFeed(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	err := tx.Where("calendar_token = ?", c.Param("token")).First(user)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return c.Error(http.StatusNotFound, errors.New("calendar not found"))
+		}
+		return errors.WithStack(err)
+	}
+
+	res := c.Response()
+	res.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	res.Header().Set("Content-Disposition", "inline; filename=tasks.ics")
+	res.WriteHeader(http.StatusOK)
+
+	cw := models.NewCalendarWriter(res, user.FullName()+" tasks")
+	filter := models.TaskFilter{UserID: user.ID}
+	err = filter.EachRow(tx, exportBatchSize, cw.WriteTask)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(cw.Close())
+}
+
+// UsersCalendarTokenCreate issues a new feed token, invalidating the old URL.
+func UsersCalendarTokenCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	err := tx.Find(user, c.Param("user_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	if err := user.ResetCalendarToken(tx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Calendar feed address created.")
+	return c.Redirect(303, "/users/%s", user.ID)
+}
+
+// UsersCalendarTokenDestroy turns the calendar feed off.
+func UsersCalendarTokenDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	err := tx.Find(user, c.Param("user_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	if err := user.RevokeCalendarToken(tx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Calendar feed revoked.")
+	return c.Redirect(303, "/users/%s", user.ID)
+}
@@ -0,0 +1,281 @@
+package actions
+
+import (
+	"buftester/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// calendarImportRow is one event on the review screen.
+type calendarImportRow struct {
+	Index      int
+	Event      models.CalendarEvent
+	ContractID int
+	Selected   bool
+	Imported   bool
+	Error      string
+}
+
+// importedExternalIDs returns which of ids already exist on the user's tasks.
+func importedExternalIDs(tx *pop.Connection, userID uuid.UUID, ids []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	if len(ids) == 0 {
+		return found, nil
+	}
+	args := []interface{}{}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	tasks := []models.Task{}
+	q := tx.Where("contract_id IN (SELECT id FROM contracts WHERE user_id = ?)", userID).
+		Where("external_id IN (?)", args...)
+	if err := q.Select("external_id").All(&tasks); err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		found[t.ExternalID.String] = true
+	}
+	return found, nil
+}
+
+// setCalendarImportData loads what the review and rules forms need.
+func setCalendarImportData(c buffalo.Context, tx *pop.Connection, user *models.User) error {
+	if err := user.GetActiveContracts(tx); err != nil {
+		return err
+	}
+	rules, err := models.LoadCalendarRules(tx, user.ID)
+	if err != nil {
+		return err
+	}
+	c.Set("user", user)
+	c.Set("rules", rules)
+	c.Set("rule", &models.CalendarRule{})
+	return nil
+}
+
+// UsersCalendarImportsNew shows the .ics upload form and the matching rules.
+func UsersCalendarImportsNew(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+	if err := setCalendarImportData(c, tx, user); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Render(http.StatusOK, r.HTML("calendar_imports/new.html"))
+}
+
+// UsersCalendarImportsPreview parses the uploaded calendar and shows each
+// event with a contract suggested by the user's rules.
+func UsersCalendarImportsPreview(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	f, err := c.File("File")
+	if err != nil || !f.Valid() {
+		c.Flash().Add("warning", "Please choose a calendar file to import.")
+		return c.Redirect(303, "/users/%s/calendar_imports/new", user.ID)
+	}
+	defer f.Close()
+	if f.Size > maxImportSize {
+		c.Flash().Add("warning", "File is too large.")
+		return c.Redirect(303, "/users/%s/calendar_imports/new", user.ID)
+	}
+
+	events, err := models.ParseCalendar(f)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot read that calendar: "+err.Error())
+		return c.Redirect(303, "/users/%s/calendar_imports/new", user.ID)
+	}
+
+	if err := setCalendarImportData(c, tx, user); err != nil {
+		return errors.WithStack(err)
+	}
+	rules := c.Value("rules").(models.CalendarRules)
+
+	ids := []string{}
+	for _, e := range events {
+		ids = append(ids, e.ExternalID())
+	}
+	imported, err := importedExternalIDs(tx, user.ID, ids)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	rows := []calendarImportRow{}
+	for i, e := range events {
+		row := calendarImportRow{
+			Index:      i,
+			Event:      e,
+			ContractID: rules.ContractFor(e.Summary),
+			Imported:   imported[e.ExternalID()],
+		}
+		row.Selected = row.ContractID != 0 && !row.Imported
+		rows = append(rows, row)
+	}
+
+	c.Set("rows", rows)
+	return c.Render(http.StatusOK, r.HTML("calendar_imports/preview.html"))
+}
+
+// UsersCalendarImportsCreate turns the selected events into tasks.
+func UsersCalendarImportsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+	if err := setCalendarImportData(c, tx, user); err != nil {
+		return errors.WithStack(err)
+	}
+
+	contracts := map[int]*models.Contract{}
+	for i := range user.Contracts {
+		contracts[user.Contracts[i].ID] = &user.Contracts[i]
+	}
+
+	count, _ := strconv.Atoi(c.Param("Count"))
+	rows := []calendarImportRow{}
+	failed := false
+	created := models.Tasks{}
+	for i := 0; i < count; i++ {
+		row := calendarImportRow{Index: i}
+		row.Event.UID = c.Param(fmt.Sprintf("UID%d", i))
+		row.Event.Summary = c.Param(fmt.Sprintf("Summary%d", i))
+		row.Event.Start, _ = time.Parse(time.RFC3339, c.Param(fmt.Sprintf("Start%d", i)))
+		row.Event.End, _ = time.Parse(time.RFC3339, c.Param(fmt.Sprintf("End%d", i)))
+		row.ContractID, _ = strconv.Atoi(c.Param(fmt.Sprintf("Contract%d", i)))
+		row.Selected = c.Param(fmt.Sprintf("Selected%d", i)) != ""
+		rows = append(rows, row)
+
+		if !row.Selected {
+			continue
+		}
+		contract, ok := contracts[row.ContractID]
+		if !ok {
+			row.Error = "Choose a contract."
+			rows[i] = row
+			failed = true
+			continue
+		}
+
+		imported, err := importedExternalIDs(tx, user.ID, []string{row.Event.ExternalID()})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if imported[row.Event.ExternalID()] {
+			continue
+		}
+
+		task := &models.Task{
+			Rate:        contract.TaskRate(),
+			Description: row.Event.Summary,
+			StartTime:   row.Event.Start,
+			EndTime:     row.Event.End,
+			Duration:    row.Event.Duration(),
+			ContractID:  contract.ID,
+			ExternalID:  nulls.NewString(row.Event.ExternalID()),
+		}
+		verrs, err := tx.ValidateAndCreate(task)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if verrs.HasAny() {
+			row.Error = verrs.Error()
+			rows[i] = row
+			failed = true
+			continue
+		}
+		created = append(created, *task)
+	}
+
+	if failed {
+		// Nothing is saved; the transaction is rolled back on 422.
+		c.Set("rows", rows)
+		return c.Render(422, r.HTML("calendar_imports/preview.html"))
+	}
+	if err := models.CheckCapAlertsFor(tx, created); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", fmt.Sprintf("%d events imported as tasks.", len(created)))
+	return c.Redirect(303, "/users/%s/contracts", user.ID)
+}
+
+// UsersCalendarRulesCreate adds a title matching rule.
+func UsersCalendarRulesCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+	if err := user.GetActiveContracts(tx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	rule := &models.CalendarRule{}
+	if err := c.Bind(rule); err != nil {
+		return err
+	}
+	rule.UserID = user.ID
+
+	owned := false
+	for _, contract := range user.Contracts {
+		if contract.ID == rule.ContractID {
+			owned = true
+		}
+	}
+	if !owned {
+		c.Flash().Add("warning", "Cannot find that contract.")
+		return c.Redirect(303, "/users/%s/calendar_imports/new", user.ID)
+	}
+
+	verrs, err := tx.ValidateAndCreate(rule)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+	} else {
+		c.Flash().Add("success", "Rule added.")
+	}
+	return c.Redirect(303, "/users/%s/calendar_imports/new", user.ID)
+}
+
+// UsersCalendarRulesDestroy removes a rule.
+func UsersCalendarRulesDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	rule := &models.CalendarRule{}
+	err := tx.Where("user_id = ?", c.Param("user_id")).Find(rule, c.Param("rule_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that rule.")
+		return c.Redirect(303, "/users/%s/calendar_imports/new", c.Param("user_id"))
+	}
+	if err := tx.Destroy(rule); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Rule removed.")
+	return c.Redirect(303, "/users/%s/calendar_imports/new", c.Param("user_id"))
+}
@@ -0,0 +1,14 @@
+package actions
+
+import "net/http"
+
+func (as *ActionSuite) Test_CalendarFeed_Unknown_Token() {
+	res := as.HTML("/calendar/nope/tasks.ics").Get()
+	as.Equal(http.StatusNotFound, res.Code)
+}
+
+func (as *ActionSuite) Test_Users_Calendar_Imports_New_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/calendar_imports/new").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
@@ -0,0 +1,73 @@
+package actions
+
+import (
+	"buftester/models"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// setComments loads the task's comments when the current user may take
+// part in them. Others see no comments and no form.
+func setComments(c buffalo.Context, tx *pop.Connection, task *models.Task) error {
+	user := c.Value("current_user").(*models.User)
+	ok, err := task.CanComment(tx, user.ID)
+	if err != nil {
+		return err
+	}
+	comments := models.Comments{}
+	if ok {
+		if comments, err = models.LoadTaskComments(tx, task.ID); err != nil {
+			return err
+		}
+	}
+	c.Set("can_comment", ok)
+	c.Set("comments", comments)
+	return nil
+}
+
+// TasksCommentsCreate posts a comment on a task as its owner or as a
+// manager of its organization.
+func TasksCommentsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	task := &models.Task{}
+	if err := tx.Eager("Contract.Boss").Find(task, c.Param("task_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that task.")
+		return c.Redirect(303, "/")
+	}
+	ok, err := task.CanComment(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !ok {
+		c.Flash().Add("warning", "You cannot comment on that task.")
+		return c.Redirect(303, "/")
+	}
+
+	comment, verrs, err := task.AddComment(tx, user, c.Param("Body"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		attachments, err := models.LoadTaskAttachments(tx, task.ID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := setComments(c, tx, task); err != nil {
+			return errors.WithStack(err)
+		}
+		c.Set("task", task)
+		c.Set("attachments", attachments)
+		c.Set("attachment_types", strings.Join(models.AttachmentTypes, ","))
+		c.Set("comment", comment)
+		c.Set("errors", verrs)
+		return c.Render(422, r.HTML("tasks/show.html"))
+	}
+
+	c.Flash().Add("success", "Comment posted.")
+	return c.Redirect(303, "/tasks/%d#comments", task.ID)
+}
@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"buftester/models"
+	"fmt"
+)
+
+func (as *ActionSuite) Test_Tasks_Comments_Requires_Login() {
+	res := as.HTML("/tasks/1/comments").Post(map[string]string{"Body": "Why two hours?"})
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+func (as *ActionSuite) Test_Tasks_Comments_Create() {
+	user := as.login("comments@example.com")
+	task := as.newTask(as.newContract(user, "ACME"), "Kickoff call")
+
+	res := as.HTML("/tasks/%d/comments", task.ID).Post(map[string]string{"Body": "Ran long, agenda was packed."})
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/tasks/%d#comments", task.ID), res.Location())
+
+	comment := &models.Comment{}
+	as.NoError(models.DB.Where("task_id = ?", task.ID).First(comment))
+	as.Equal("Ran long, agenda was packed.", comment.Body)
+	as.Equal(user.ID, comment.UserID)
+}
+
+func (as *ActionSuite) Test_Tasks_Comments_Create_Other_User() {
+	owner := as.login("comments@example.com")
+	task := as.newTask(as.newContract(owner, "ACME"), "Kickoff call")
+
+	as.login("other@example.com")
+	res := as.HTML("/tasks/%d/comments", task.ID).Post(map[string]string{"Body": "Why two hours?"})
+	as.Equal(303, res.Code)
+	as.Equal("/", res.Location())
+
+	count, err := models.DB.Where("task_id = ?", task.ID).Count(&models.Comment{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
@@ -0,0 +1,71 @@
+package actions
+
+import (
+	"buftester/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// setCap makes the contract's cap usage for the current period available to
+// the contract page.
+func setCap(c buffalo.Context, tx *pop.Connection, contract *models.Contract) error {
+	usage, err := contract.LoadCapUsage(tx, time.Now())
+	if err != nil {
+		return err
+	}
+	if usage == nil {
+		usage = &models.CapUsage{}
+	}
+	c.Set("cap", usage)
+	c.Set("cap_kinds", models.CapKinds)
+	c.Set("cap_periods", models.CapPeriods)
+	return nil
+}
+
+// UsersContractCapUpdate sets or removes the contract's cap. CapAmount is
+// decimal hours for an hours cap and dollars for a money cap.
+func UsersContractCapUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+
+	kind := c.Param("CapKind")
+	amount := 0
+	if v := strings.TrimSpace(c.Param("CapAmount")); kind != "" && v != "" {
+		var err error
+		if kind == models.CapMoney {
+			amount, err = models.ParseMoney(v)
+		} else {
+			var h float64
+			h, err = strconv.ParseFloat(v, 64)
+			amount = models.DurationFromHours(h)
+		}
+		if err != nil {
+			c.Flash().Add("warning", "Enter the cap as a number.")
+			return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+		}
+	}
+
+	verrs, err := contract.SetCap(tx, kind, amount, c.Param("CapPeriod"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	if err := contract.CheckCapAlerts(tx, time.Now()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Contract cap saved.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
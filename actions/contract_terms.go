@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"buftester/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// termsParams reads a retainer's monthly fee in dollars and its included
+// hours from the form. Other kinds carry neither.
+func termsParams(c buffalo.Context, contract *models.Contract) error {
+	contract.RetainerFee, contract.IncludedMinutes = 0, 0
+	if contract.Kind != models.ContractRetainer {
+		return nil
+	}
+	if v := strings.TrimSpace(c.Param("RetainerFee")); v != "" {
+		fee, err := models.ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		contract.RetainerFee = fee
+	}
+	if v := strings.TrimSpace(c.Param("IncludedHours")); v != "" {
+		h, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		contract.IncludedMinutes = models.DurationFromHours(h)
+	}
+	return nil
+}
+
+// setTerms makes the contract's milestones and current retainer month
+// available to the contract page.
+func setTerms(c buffalo.Context, tx *pop.Connection, contract *models.Contract) error {
+	milestones, err := models.LoadMilestones(tx, contract.ID)
+	if err != nil {
+		return err
+	}
+	month, err := contract.LoadRetainerMonth(tx, time.Now())
+	if err != nil {
+		return err
+	}
+	if month == nil {
+		month = &models.RetainerMonth{}
+	}
+	c.Set("contract_kinds", models.ContractKinds)
+	c.Set("milestones", milestones)
+	c.Set("retainer", month)
+	return nil
+}
+
+// UsersContractTermsUpdate changes how the contract is billed. Tasks
+// already logged keep their rate and are billed under the old terms.
+func UsersContractTermsUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+
+	contract.SetKind(c.Param("Kind"), time.Now())
+	rate, err := strconv.Atoi(strings.TrimSpace(c.Param("Rate")))
+	if err != nil {
+		c.Flash().Add("warning", "Enter the rate as whole dollars.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	contract.Rate = rate
+	if err := termsParams(c, contract); err != nil {
+		c.Flash().Add("warning", "Enter the fee and included hours as numbers.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	verrs, err := tx.ValidateAndUpdate(contract)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	c.Flash().Add("success", "Billing terms saved.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
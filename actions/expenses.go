@@ -0,0 +1,193 @@
+package actions
+
+import (
+	"buftester/models"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// setExpenses loads the contract's expenses and mileage with their totals.
+func setExpenses(c buffalo.Context, tx *pop.Connection, contract *models.Contract) error {
+	expenses, err := models.LoadExpenses(tx, contract.ID)
+	if err != nil {
+		return err
+	}
+	c.Set("expenses", expenses)
+	c.Set("expense_totals", expenses.Totals())
+	c.Set("expense_categories", models.ExpenseCategories)
+	c.Set("expense_currencies", models.ExpenseCurrencies)
+	c.Set("mileage_units", models.MileageUnits)
+	c.Set("billing_currency", models.BillingCurrency)
+	c.Set("attachment_types", strings.Join(models.AttachmentTypes, ","))
+	c.Set("today", time.Now().Format("2006-01-02"))
+	return nil
+}
+
+// UsersContractExpensesCreate logs an expense, or mileage when Kind is
+// "mileage", on the contract. Amounts are in dollars and Markup is a
+// percentage.
+func UsersContractExpensesCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	if contract.IsArchived() {
+		c.Flash().Add("warning", "That contract is archived. Restore it to log expenses.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	day, err := dateParam(c, "SpentOn")
+	if err != nil {
+		c.Flash().Add("warning", "Cannot read the date.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	e := &models.Expense{ContractID: contract.ID, Kind: c.Param("Kind")}
+	if e.Kind == models.ExpenseMileage {
+		distance, err := models.ParseDistance(c.Param("Distance"))
+		if err != nil {
+			c.Flash().Add("warning", "Enter the distance as a number.")
+			return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+		}
+		e = models.NewMileage(contract, day.Time, distance)
+	} else {
+		e.SpentOn = day.Time
+		e.Category = c.Param("Category")
+		e.Currency = c.Param("Currency")
+		if e.Amount, err = models.ParseMoney(c.Param("Amount")); err != nil {
+			c.Flash().Add("warning", "Enter the amount as a number.")
+			return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+		}
+		if v := strings.TrimSpace(c.Param("BaseAmount")); v != "" {
+			if e.BaseAmount, err = models.ParseMoney(v); err != nil {
+				c.Flash().Add("warning", "Enter the amount as a number.")
+				return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+			}
+		}
+	}
+	if v := strings.TrimSpace(c.Param("Description")); v != "" {
+		e.Description = nulls.NewString(v)
+	}
+	e.Billable = c.Param("Billable") != ""
+	if v := strings.TrimSpace(c.Param("Markup")); v != "" {
+		if e.Markup, err = models.ParsePercent(v); err != nil {
+			c.Flash().Add("warning", "Enter the markup as a percentage.")
+			return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+		}
+	}
+
+	verrs, err := tx.ValidateAndCreate(e)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	if e.IsMileage() {
+		c.Flash().Add("success", "Mileage logged.")
+	} else {
+		c.Flash().Add("success", "Expense logged. Attach the receipt below.")
+	}
+	return c.Redirect(303, "/users/%s/contracts/%d#expenses", contract.UserID, contract.ID)
+}
+
+// UsersContractExpenseReceiptCreate attaches a receipt to an expense.
+func UsersContractExpenseReceiptCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	e, err := models.FindExpense(tx, contract.ID, c.Param("expense_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that expense.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	verrs, err := saveAttachment(c, tx, contract.UserID, func(a *models.Attachment) {
+		a.ExpenseID = nulls.NewInt(e.ID)
+	})
+	if err != nil {
+		return err
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+	} else {
+		c.Flash().Add("success", "Receipt attached.")
+	}
+	return c.Redirect(303, "/users/%s/contracts/%d#expenses", contract.UserID, contract.ID)
+}
+
+// UsersContractExpenseDestroy removes an expense that is not invoiced, and
+// its receipt.
+func UsersContractExpenseDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	e, err := models.FindExpense(tx, contract.ID, c.Param("expense_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that expense.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	receipts, err := e.Remove(tx)
+	if errors.Cause(err) == models.ErrExpenseInvoiced {
+		c.Flash().Add("warning", "That expense is already invoiced.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, a := range receipts {
+		if err := attachmentStore.Delete(a.StorageKey); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	c.Flash().Add("success", "Expense removed.")
+	return c.Redirect(303, "/users/%s/contracts/%d#expenses", contract.UserID, contract.ID)
+}
+
+// UsersContractMileageUpdate sets the contract's mileage rate, in dollars
+// per mile or kilometer. Mileage already logged keeps its rate.
+func UsersContractMileageUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+
+	rate, err := models.ParseMoney(c.Param("MileageRate"))
+	if err != nil {
+		c.Flash().Add("warning", "Enter the mileage rate as a number.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	contract.MileageRate = rate
+	contract.MileageUnit = c.Param("MileageUnit")
+
+	verrs, err := tx.ValidateAndUpdate(contract)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	c.Flash().Add("success", "Mileage rate saved.")
+	return c.Redirect(303, "/users/%s/contracts/%d#expenses", contract.UserID, contract.ID)
+}
@@ -0,0 +1,97 @@
+package actions
+
+import (
+	"buftester/models"
+	"fmt"
+	"time"
+)
+
+func (as *ActionSuite) Test_Users_Contract_Expenses_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/contracts/1/expenses").Post(map[string]string{"Amount": "10"})
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+// travel is the form for a billable travel expense of $25.00.
+func travel() map[string]string {
+	return map[string]string{
+		"SpentOn":     time.Now().Format("2006-01-02"),
+		"Category":    "Travel",
+		"Currency":    models.BillingCurrency,
+		"Amount":      "25.00",
+		"Description": "Train to the client",
+		"Billable":    "true",
+		"Markup":      "10",
+	}
+}
+
+func (as *ActionSuite) Test_Users_Contract_Expenses_Create() {
+	user := as.login("expenses@example.com")
+	contract := as.newContract(user, "ACME")
+
+	res := as.HTML("/users/%s/contracts/%d/expenses", user.ID, contract.ID).Post(travel())
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/contracts/%d#expenses", user.ID, contract.ID), res.Location())
+
+	e := &models.Expense{}
+	as.NoError(models.DB.Where("contract_id = ?", contract.ID).First(e))
+	as.Equal("Travel", e.Category)
+	as.Equal(2500, e.BaseAmount)
+	as.Equal(1000, e.Markup)
+	as.True(e.Billable)
+}
+
+func (as *ActionSuite) Test_Users_Contract_Expenses_Create_Other_User() {
+	owner := as.login("expenses@example.com")
+	contract := as.newContract(owner, "ACME")
+
+	other := as.login("other@example.com")
+	res := as.HTML("/users/%s/contracts/%d/expenses", owner.ID, contract.ID).Post(travel())
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+
+	res = as.HTML("/users/%s/contracts/%d/expenses", other.ID, contract.ID).Post(travel())
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/contracts", other.ID), res.Location())
+
+	count, err := models.DB.Where("contract_id = ?", contract.ID).Count(&models.Expense{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_Users_Contract_Expense_Receipt_And_Destroy() {
+	defer as.useTempStore()()
+	user := as.login("expenses@example.com")
+	contract := as.newContract(user, "ACME")
+	now := time.Now()
+	e := &models.Expense{ContractID: contract.ID, Kind: models.ExpenseOther, SpentOn: now, Category: "Travel", Currency: models.BillingCurrency, Amount: 2500, BaseAmount: 2500}
+	as.NoError(models.DB.Create(e))
+
+	res, err := as.HTML("/users/%s/contracts/%d/expenses/%d/receipt", user.ID, contract.ID, e.ID).MultiPartPost(&struct{}{}, notes())
+	as.NoError(err)
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/contracts/%d#expenses", user.ID, contract.ID), res.Location())
+	count, err := models.DB.Where("expense_id = ?", e.ID).Count(&models.Attachment{})
+	as.NoError(err)
+	as.Equal(1, count)
+
+	res = as.HTML("/users/%s/contracts/%d/expenses/%d", user.ID, contract.ID, e.ID).Delete()
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/contracts/%d#expenses", user.ID, contract.ID), res.Location())
+	count, err = models.DB.Where("contract_id = ?", contract.ID).Count(&models.Expense{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_Users_Contract_Mileage_Update() {
+	user := as.login("expenses@example.com")
+	contract := as.newContract(user, "ACME")
+
+	res := as.HTML("/users/%s/contracts/%d/mileage", user.ID, contract.ID).Post(map[string]string{"MileageRate": "0.58", "MileageUnit": "km"})
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/contracts/%d#expenses", user.ID, contract.ID), res.Location())
+
+	as.NoError(models.DB.Reload(contract))
+	as.Equal(58, contract.MileageRate)
+	as.Equal("km", contract.MileageUnit)
+}
@@ -3,6 +3,7 @@ package actions
 import (
 	"buftester/models"
 	"net/http"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v5"
@@ -18,11 +19,17 @@ func HomeHandler(c buffalo.Context) error {
 		tx := c.Value("tx").(*pop.Connection)
 
 		user := c.Value("current_user").(*models.User)
-		err := user.GetContracts(tx)
+		err := user.GetActiveContracts(tx)
 		if err != nil {
 			return errors.WithStack(err)
 		}
 		c.Set("current_user", user)
+
+		dashboard, err := models.LoadDashboard(tx, user.ID, time.Now())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		c.Set("dashboard", dashboard)
 	}
 	return c.Render(http.StatusOK, r.HTML("home/index.html"))
 }
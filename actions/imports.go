@@ -0,0 +1,223 @@
+package actions
+
+import (
+	"buftester/models"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// maxImportSize caps the size of uploaded import files.
+const maxImportSize = 5 * 1024 * 1024
+
+// maxImportErrors caps how many failed entries are listed after an import.
+const maxImportErrors = 5
+
+// importState is the parsed import form shared by preview and create.
+type importState struct {
+	Data       string
+	Headers    []string
+	Mapping    models.ImportMapping
+	ContractID int
+	Rows       models.ImportRows
+}
+
+// readImportData returns the CSV text from the uploaded file, or from the
+// Data field when the form is resubmitted from the preview page.
+func readImportData(c buffalo.Context) (string, error) {
+	f, err := c.File("File")
+	if err == nil && f.Valid() {
+		defer f.Close()
+		if f.Size > maxImportSize {
+			return "", errors.New("File is too large.")
+		}
+		b, err := ioutil.ReadAll(f)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return string(b), nil
+	}
+	return c.Param("Data"), nil
+}
+
+// loadImport parses the import form and runs the dry run against the user's
+// contracts.
+func loadImport(c buffalo.Context, tx *pop.Connection, user *models.User) (*importState, error) {
+	data, err := readImportData(c)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(data) == "" {
+		return nil, errors.New("Please choose a CSV file to import.")
+	}
+
+	headers, records, err := models.ReadCSV(strings.NewReader(data))
+	if err != nil {
+		return nil, errors.New("Cannot read that file as CSV.")
+	}
+
+	state := &importState{Data: data, Headers: headers}
+
+	// The mapping form is only present once a preview has been shown.
+	if c.Param("Mapped") == "" {
+		state.Mapping = models.GuessImportMapping(headers)
+	} else {
+		state.Mapping = models.ImportMapping{}
+		for _, field := range models.ImportFields {
+			i, err := strconv.Atoi(c.Param("Map" + field))
+			if err == nil && i >= 0 && i < len(headers) {
+				state.Mapping[field] = i
+			}
+		}
+	}
+
+	target := models.ImportTarget{Contracts: user.Contracts}
+	if v := c.Param("ContractID"); v != "" {
+		id, _ := strconv.Atoi(v)
+		for i := range user.Contracts {
+			if user.Contracts[i].ID == id {
+				target.Contract = &user.Contracts[i]
+				state.ContractID = id
+			}
+		}
+		if target.Contract == nil {
+			return nil, errors.New("Cannot find that contract.")
+		}
+	}
+
+	state.Rows, err = models.BuildImport(tx, records, state.Mapping, target)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return state, nil
+}
+
+// loadImportUser finds the user in the path along with their contracts.
+func loadImportUser(c buffalo.Context, tx *pop.Connection) (*models.User, error) {
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		return nil, err
+	}
+	if err := user.GetActiveContracts(tx); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UsersImportsNew shows the upload form.
+func UsersImportsNew(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user, err := loadImportUser(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	c.Set("user", user)
+	return c.Render(http.StatusOK, r.HTML("imports/new.html"))
+}
+
+// UsersImportsPreview parses the upload and shows a dry run.
+func UsersImportsPreview(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user, err := loadImportUser(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	state, err := loadImport(c, tx, user)
+	if err != nil {
+		c.Flash().Add("warning", err.Error())
+		return c.Redirect(303, "/users/%s/imports/new", user.ID)
+	}
+
+	c.Set("user", user)
+	c.Set("import", state)
+	c.Set("fields", models.ImportFields)
+	return c.Render(http.StatusOK, r.HTML("imports/preview.html"))
+}
+
+// UsersImportsCreate saves every previewed row, or none of them.
+func UsersImportsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user, err := loadImportUser(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	state, err := loadImport(c, tx, user)
+	if err != nil {
+		c.Flash().Add("warning", err.Error())
+		return c.Redirect(303, "/users/%s/imports/new", user.ID)
+	}
+
+	if state.Rows.HasErrors() {
+		c.Set("user", user)
+		c.Set("import", state)
+		c.Set("fields", models.ImportFields)
+		return c.Render(422, r.HTML("imports/preview.html"))
+	}
+
+	if err := models.SaveImport(tx, state.Rows); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", strconv.Itoa(len(state.Rows))+" tasks imported.")
+	return c.Redirect(303, "/users/%s/contracts", user.ID)
+}
+
+// UsersImportsExternal imports a Toggl or Harvest export. Entries that were
+// imported before are skipped.
+func UsersImportsExternal(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	f, err := c.File("File")
+	if err != nil || !f.Valid() {
+		c.Flash().Add("warning", "Please choose an export file to import.")
+		return c.Redirect(303, "/users/%s/imports/new", user.ID)
+	}
+	defer f.Close()
+	if f.Size > maxImportSize {
+		c.Flash().Add("warning", "File is too large.")
+		return c.Redirect(303, "/users/%s/imports/new", user.ID)
+	}
+
+	entries, err := models.ParseExternal(c.Param("Source"), f)
+	if err != nil {
+		c.Flash().Add("warning", err.Error())
+		return c.Redirect(303, "/users/%s/imports/new", user.ID)
+	}
+
+	res, err := models.ImportExternal(tx, user, entries)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", res.String())
+	// The flash lives in the session cookie, so only the first few are shown.
+	for i, f := range res.Failed {
+		if i == maxImportErrors {
+			c.Flash().Add("warning", fmt.Sprintf("and %d more.", len(res.Failed)-i))
+			break
+		}
+		c.Flash().Add("warning", f)
+	}
+	return c.Redirect(303, "/users/%s/contracts", user.ID)
+}
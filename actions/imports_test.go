@@ -0,0 +1,7 @@
+package actions
+
+func (as *ActionSuite) Test_Users_Imports_New_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/imports/new").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
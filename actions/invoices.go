@@ -0,0 +1,281 @@
+package actions
+
+import (
+	"buftester/mailers"
+	"buftester/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// loadInvoice finds the invoice in the path, scoped to the user in the path.
+func loadInvoice(c buffalo.Context, tx *pop.Connection) (*models.Invoice, error) {
+	userID, err := uuid.FromString(c.Param("user_id"))
+	if err != nil {
+		return nil, err
+	}
+	return models.LoadInvoice(tx, userID, c.Param("invoice_id"))
+}
+
+// UsersInvoicesIndex lists the user's invoices.
+func UsersInvoicesIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	invoices, err := models.LoadInvoices(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("user", user)
+	c.Set("invoices", invoices)
+	c.Set("now", time.Now())
+	return c.Render(http.StatusOK, r.HTML("invoices/index.html"))
+}
+
+// UsersInvoicesCreate bills the unbilled tasks of a contract, and its
+// milestones or retainer months.
+func UsersInvoicesCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	contract := &models.Contract{}
+	err := tx.Where("user_id = ?", user.ID).Find(contract, c.Param("contract_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that contract.")
+		return c.Redirect(303, "/users/%s/contracts", user.ID)
+	}
+
+	terms, err := strconv.Atoi(c.Param("PaymentTerms"))
+	if err != nil || terms < 0 {
+		c.Flash().Add("warning", "Payment terms must be a number of days.")
+		return c.Redirect(303, "/users/%s/contracts/%d", user.ID, contract.ID)
+	}
+
+	inv, verrs, err := models.CreateInvoice(tx, contract, time.Now(), terms)
+	if errors.Cause(err) == models.ErrNothingToInvoice {
+		c.Flash().Add("warning", "There is nothing approved and unbilled on this contract.")
+		return c.Redirect(303, "/users/%s/contracts/%d", user.ID, contract.ID)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/contracts/%d", user.ID, contract.ID)
+	}
+
+	if notes := strings.TrimSpace(c.Param("Notes")); notes != "" {
+		inv.Notes = nulls.NewString(notes)
+		if err := tx.UpdateColumns(inv, "notes"); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	c.Flash().Add("success", "Invoice "+inv.Number+" created.")
+	return c.Redirect(303, "/users/%s/invoices/%d", user.ID, inv.ID)
+}
+
+// UsersInvoicesShow shows an invoice with its line items.
+func UsersInvoicesShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	inv, err := loadInvoice(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that invoice.")
+		return c.Redirect(303, "/users/%s/invoices", c.Param("user_id"))
+	}
+
+	attachments, err := models.LoadInvoiceAttachments(tx, inv.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("invoice", inv)
+	c.Set("attachments", attachments)
+	c.Set("attachment_types", strings.Join(models.AttachmentTypes, ","))
+	c.Set("payment", &models.Payment{PaidOn: time.Now(), Method: models.PaymentMethods[0]})
+	c.Set("methods", models.PaymentMethods)
+	c.Set("now", time.Now())
+	return c.Render(http.StatusOK, r.HTML("invoices/show.html"))
+}
+
+// UsersInvoicesPDF downloads the invoice as a PDF.
+func UsersInvoicesPDF(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	inv, err := loadInvoice(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that invoice.")
+		return c.Redirect(303, "/users/%s/invoices", c.Param("user_id"))
+	}
+
+	res := c.Response()
+	res.Header().Set("Content-Type", "application/pdf")
+	res.Header().Set("Content-Disposition", "attachment; filename="+inv.PDFName())
+	res.WriteHeader(http.StatusOK)
+	return errors.WithStack(inv.WritePDF(res))
+}
+
+// UsersInvoicesEmail sends the invoice PDF to the address in the form.
+func UsersInvoicesEmail(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	inv, err := loadInvoice(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that invoice.")
+		return c.Redirect(303, "/users/%s/invoices", c.Param("user_id"))
+	}
+
+	to := strings.TrimSpace(c.Param("To"))
+	if !strings.Contains(to, "@") {
+		c.Flash().Add("warning", "Please enter an email address.")
+		return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+	}
+
+	if inv.Status == models.InvoiceVoid {
+		c.Flash().Add("warning", "A void invoice cannot be sent.")
+		return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+	}
+
+	// Mark the invoice sent before mailing it, so the client never gets an
+	// invoice that is still a draft here. A draft that fails to go out is
+	// put back.
+	status := inv.Status
+	if err := inv.MarkSent(tx); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := mailers.SendInvoice(inv, to); err != nil {
+		c.Logger().Errorf("sending invoice %d: %v", inv.ID, err)
+		if inv.Status != status {
+			inv.Status = status
+			if err := tx.UpdateColumns(inv, "status", "updated_at"); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		c.Flash().Add("warning", "The invoice could not be sent. Please try again later.")
+		return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+	}
+
+	c.Flash().Add("success", "Invoice sent to "+to+".")
+	return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+}
+
+// UsersInvoicesSend marks a draft as sent without emailing it, for invoices
+// delivered some other way.
+func UsersInvoicesSend(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	inv, err := loadInvoice(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that invoice.")
+		return c.Redirect(303, "/users/%s/invoices", c.Param("user_id"))
+	}
+
+	if err := inv.MarkSent(tx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Invoice marked as sent.")
+	return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+}
+
+// UsersInvoicesVoid cancels an unpaid invoice and releases its tasks.
+func UsersInvoicesVoid(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	inv, err := loadInvoice(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that invoice.")
+		return c.Redirect(303, "/users/%s/invoices", c.Param("user_id"))
+	}
+
+	err = inv.Void(tx)
+	if errors.Cause(err) == models.ErrInvalidTransition {
+		c.Flash().Add("warning", "Only unpaid invoices can be voided.")
+		return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Invoice voided. Its tasks can be billed again.")
+	return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+}
+
+// UsersInvoicesPaymentsCreate records a payment against an invoice.
+func UsersInvoicesPaymentsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	inv, err := loadInvoice(c, tx)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that invoice.")
+		return c.Redirect(303, "/users/%s/invoices", c.Param("user_id"))
+	}
+
+	payment := &models.Payment{Method: c.Param("Method")}
+	payment.PaidOn, err = time.Parse("2006-01-02", c.Param("PaidOn"))
+	if err != nil {
+		c.Flash().Add("warning", "Please enter the date the payment was received.")
+		return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+	}
+	payment.Amount, err = models.ParseMoney(c.Param("Amount"))
+	if err != nil {
+		c.Flash().Add("warning", "Please enter the amount received.")
+		return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+	}
+
+	verrs, err := inv.RecordPayment(tx, payment)
+	if errors.Cause(err) == models.ErrInvalidTransition {
+		c.Flash().Add("warning", "Payments can only be recorded on sent invoices.")
+		return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+	}
+
+	c.Flash().Add("success", "Payment recorded.")
+	return c.Redirect(303, "/users/%s/invoices/%d", inv.UserID, inv.ID)
+}
+
+// UsersReceivables shows what each boss owes, aged by invoice date.
+func UsersReceivables(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	rec, err := models.LoadReceivables(tx, user.ID, time.Now())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("user", user)
+	c.Set("receivables", rec)
+	c.Set("buckets", models.AgingBuckets)
+	return c.Render(http.StatusOK, r.HTML("invoices/receivables.html"))
+}
@@ -0,0 +1,13 @@
+package actions
+
+func (as *ActionSuite) Test_Users_Invoices_Index_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/invoices").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+func (as *ActionSuite) Test_Users_Receivables_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/receivables").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
@@ -0,0 +1,111 @@
+package actions
+
+import (
+	"buftester/models"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// UsersContractMilestonesCreate adds a milestone to a fixed-price contract.
+// Amount is in dollars and DueOn is optional.
+func UsersContractMilestonesCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	if !contract.IsFixed() {
+		c.Flash().Add("warning", "Only fixed-price contracts have milestones.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	m := &models.Milestone{ContractID: contract.ID, Name: c.Param("Name")}
+	amount, err := models.ParseMoney(c.Param("Amount"))
+	if err != nil {
+		c.Flash().Add("warning", "Enter the amount as a number.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	m.Amount = amount
+	if m.DueOn, err = dateParam(c, "DueOn"); err != nil {
+		c.Flash().Add("warning", "Cannot read the due date.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	verrs, err := tx.ValidateAndCreate(m)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	c.Flash().Add("success", "Milestone added.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
+
+// UsersContractMilestoneComplete marks a milestone done so it goes on the
+// next invoice.
+func UsersContractMilestoneComplete(c buffalo.Context) error {
+	return setMilestoneComplete(c, true, "Milestone completed.")
+}
+
+// UsersContractMilestoneReopen marks a milestone not done again.
+func UsersContractMilestoneReopen(c buffalo.Context) error {
+	return setMilestoneComplete(c, false, "Milestone reopened.")
+}
+
+func setMilestoneComplete(c buffalo.Context, done bool, msg string) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	m, err := models.FindMilestone(tx, contract.ID, c.Param("milestone_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that milestone.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	err = m.SetComplete(tx, done, time.Now())
+	if errors.Cause(err) == models.ErrMilestoneInvoiced {
+		c.Flash().Add("warning", "That milestone is already invoiced.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", msg)
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
+
+// UsersContractMilestoneDestroy removes a milestone that is not invoiced.
+func UsersContractMilestoneDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	m, err := models.FindMilestone(tx, contract.ID, c.Param("milestone_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that milestone.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	if m.IsInvoiced() {
+		c.Flash().Add("warning", "That milestone is already invoiced.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	if err := tx.Destroy(m); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Milestone removed.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
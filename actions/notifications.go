@@ -0,0 +1,47 @@
+package actions
+
+import (
+	"buftester/models"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// UsersNotificationsIndex lists the user's recent notifications.
+func UsersNotificationsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	list, err := models.LoadNotifications(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("user", user)
+	c.Set("notifications", list)
+	return c.Render(http.StatusOK, r.HTML("notifications/index.html"))
+}
+
+// UsersNotificationsRead marks all the user's notifications read.
+func UsersNotificationsRead(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(303, "/")
+	}
+
+	if err := models.MarkNotificationsRead(tx, user.ID, time.Now()); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Redirect(303, "/users/%s/notifications", user.ID)
+}
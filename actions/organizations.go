@@ -0,0 +1,219 @@
+package actions
+
+import (
+	"buftester/models"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// findMembership loads the organization in the path and the current user's
+// membership of it. It flashes and returns false when the user is not a
+// member, or not an owner when owner is set.
+func findMembership(c buffalo.Context, tx *pop.Connection, owner bool) (*models.Organization, *models.OrganizationMember, bool) {
+	user := c.Value("current_user").(*models.User)
+	org, member, err := models.FindMembership(tx, c.Param("organization_id"), user.ID)
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that organization.")
+		return nil, nil, false
+	}
+	if owner && !member.IsOwner() {
+		c.Flash().Add("warning", "Only owners can manage members.")
+		return nil, nil, false
+	}
+	return org, member, true
+}
+
+// OrganizationsIndex lists the current user's organizations.
+func OrganizationsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	orgs, err := models.LoadOrganizations(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("organizations", orgs)
+	c.Set("organization", &models.Organization{})
+	return c.Render(http.StatusOK, r.HTML("organizations/index.html"))
+}
+
+// OrganizationsCreate starts an organization owned by the current user.
+func OrganizationsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	org, verrs, err := models.CreateOrganization(tx, c.Param("Name"), user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/organizations/index")
+	}
+
+	c.Flash().Add("success", "Organization created.")
+	return c.Redirect(303, "/organizations/%d", org.ID)
+}
+
+// OrganizationsShow shows the members and the time logged for the
+// organization's bosses. Managers see every member; members only see
+// themselves. The period defaults to the current month; to is inclusive.
+func OrganizationsShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	org, member, ok := findMembership(c, tx, false)
+	if !ok {
+		return c.Redirect(307, "/organizations/index")
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if v := c.Param("from"); v != "" {
+		if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			from = t
+		}
+	}
+	if v := c.Param("to"); v != "" {
+		if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			to = t
+		}
+	}
+
+	filter := models.TaskFilter{OrganizationID: org.ID, From: from, To: to.AddDate(0, 0, 1)}
+	if !member.IsManager() {
+		filter.UserID = user.ID
+	}
+	if v := c.Param("project_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filter.ProjectID = id
+		}
+	}
+
+	report, err := models.LoadOrganizationReport(tx, filter)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tasks := []models.TaskRow{}
+	err = filter.EachRow(tx, exportBatchSize, func(t models.TaskRow) error {
+		tasks = append(tasks, t)
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	bosses := []models.Boss{}
+	if err := tx.Where("organization_id = ?", org.ID).Order("name asc").All(&bosses); err != nil {
+		return errors.WithStack(err)
+	}
+	projects, err := models.LoadOrganizationProjects(tx, org.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("org", org)
+	c.Set("member", member)
+	c.Set("roles", models.OrganizationRoles)
+	c.Set("report", report)
+	c.Set("report_to", to)
+	c.Set("tasks", tasks)
+	c.Set("bosses", bosses)
+	c.Set("projects", projects)
+	c.Set("project_id", filter.ProjectID)
+	return c.Render(http.StatusOK, r.HTML("organizations/show.html"))
+}
+
+// OrganizationsMembersCreate adds a user by email with a role.
+func OrganizationsMembersCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	org, _, ok := findMembership(c, tx, true)
+	if !ok {
+		return c.Redirect(303, "/organizations/index")
+	}
+
+	verrs, err := org.AddMember(tx, c.Param("Email"), c.Param("Role"))
+	return memberChanged(c, org, verrs, err, "Member added.")
+}
+
+// OrganizationsMembersUpdate changes a member's role.
+func OrganizationsMembersUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	org, _, ok := findMembership(c, tx, true)
+	if !ok {
+		return c.Redirect(303, "/organizations/index")
+	}
+	uid, err := uuid.FromString(c.Param("user_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that member.")
+		return c.Redirect(303, "/organizations/%d", org.ID)
+	}
+
+	verrs, err := org.SetRole(tx, uid, c.Param("Role"))
+	return memberChanged(c, org, verrs, err, "Role updated.")
+}
+
+// OrganizationsMembersDestroy removes a member. Members may also remove
+// themselves to leave the organization.
+func OrganizationsMembersDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	leaving := c.Param("user_id") == user.ID.String()
+	org, _, ok := findMembership(c, tx, !leaving)
+	if !ok {
+		return c.Redirect(303, "/organizations/index")
+	}
+	uid, err := uuid.FromString(c.Param("user_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that member.")
+		return c.Redirect(303, "/organizations/%d", org.ID)
+	}
+
+	err = org.RemoveMember(tx, uid)
+	if errors.Cause(err) == models.ErrLastOwner {
+		c.Flash().Add("warning", "Make someone else an owner first.")
+		return c.Redirect(303, "/organizations/%d", org.ID)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if leaving {
+		c.Flash().Add("success", "You left "+org.Name+".")
+		return c.Redirect(303, "/organizations/index")
+	}
+	c.Flash().Add("success", "Member removed.")
+	return c.Redirect(303, "/organizations/%d", org.ID)
+}
+
+// memberChanged flashes the outcome of a membership change.
+func memberChanged(c buffalo.Context, org *models.Organization, verrs *validate.Errors, err error, msg string) error {
+	switch {
+	case errors.Cause(err) == models.ErrNoSuchUser:
+		c.Flash().Add("warning", "Nobody has signed up with that email.")
+	case errors.Cause(err) == models.ErrLastOwner:
+		c.Flash().Add("warning", "Make someone else an owner first.")
+	case errors.Cause(err) == sql.ErrNoRows:
+		c.Flash().Add("warning", "Cannot find that member.")
+	case err != nil:
+		return errors.WithStack(err)
+	case verrs.HasAny():
+		c.Flash().Add("warning", verrs.Error())
+	default:
+		c.Flash().Add("success", msg)
+	}
+	return c.Redirect(303, "/organizations/%d", org.ID)
+}
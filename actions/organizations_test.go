@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"buftester/models"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (as *ActionSuite) Test_Organizations_Index_Requires_Login() {
+	res := as.HTML("/organizations/index").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+func (as *ActionSuite) Test_Organizations_Show_Rows() {
+	manager := as.login("manager@example.com")
+	member := as.login("member@example.com")
+	org, verrs, err := models.CreateOrganization(models.DB, "Agency", manager.ID)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+	verrs, err = org.AddMember(models.DB, member.Email, models.RoleMember)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	boss := as.newBoss(manager, "Umbrella")
+	as.NoError(boss.SetOrganization(models.DB, nulls.NewInt(org.ID)))
+	logged := map[*models.User]string{manager: "Quarterly planning", member: "Bug triage"}
+	for u, description := range logged {
+		contract := &models.Contract{Rate: 60, BossID: boss.ID, UserID: u.ID}
+		as.NoError(models.DB.Create(contract))
+		as.newTask(contract, description)
+	}
+
+	// Members see only their own rows.
+	res := as.HTML("/organizations/%d", org.ID).Get()
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "Bug triage")
+	as.NotContains(res.Body.String(), "Quarterly planning")
+
+	// Managers see everyone's.
+	as.Session.Set("current_user_id", manager.ID)
+	res = as.HTML("/organizations/%d", org.ID).Get()
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "Bug triage")
+	as.Contains(res.Body.String(), "Quarterly planning")
+}
@@ -0,0 +1,140 @@
+package actions
+
+import (
+	"buftester/models"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// findPortal loads the contract for the portal token in the path. Expired
+// and revoked links are not found.
+func findPortal(c buffalo.Context, tx *pop.Connection) (*models.Contract, error) {
+	contract, err := models.FindContractByPortalToken(tx, c.Param("token"), time.Now())
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, c.Error(http.StatusNotFound, errors.New("portal not found"))
+		}
+		return nil, errors.WithStack(err)
+	}
+	return contract, nil
+}
+
+// PortalShow is the read-only client view of a contract. The token is the
+// only credential, so no session is required.
+func PortalShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, err := findPortal(c, tx)
+	if err != nil {
+		return err
+	}
+
+	month := time.Now()
+	if m, err := time.ParseInLocation("2006-01", c.Param("month"), time.Local); err == nil {
+		month = m
+	}
+	portal, err := models.LoadPortal(tx, contract, month)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("portal", portal)
+	c.Set("token", c.Param("token"))
+	c.Set("now", time.Now())
+	return c.Render(http.StatusOK, r.HTML("portal/show.html"))
+}
+
+// PortalInvoicePDF downloads an issued invoice from the portal.
+func PortalInvoicePDF(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, err := findPortal(c, tx)
+	if err != nil {
+		return err
+	}
+
+	inv, err := models.LoadInvoice(tx, contract.UserID, c.Param("invoice_id"))
+	if err != nil || inv.ContractID != contract.ID || inv.Status == models.InvoiceDraft || inv.Status == models.InvoiceVoid {
+		return c.Error(http.StatusNotFound, errors.New("invoice not found"))
+	}
+
+	res := c.Response()
+	res.Header().Set("Content-Type", "application/pdf")
+	res.Header().Set("Content-Disposition", "attachment; filename="+inv.PDFName())
+	res.WriteHeader(http.StatusOK)
+	return errors.WithStack(inv.WritePDF(res))
+}
+
+// ownContract loads the contract in the path, scoped to the user in the path.
+func ownContract(c buffalo.Context, tx *pop.Connection) (*models.Contract, bool) {
+	contract := &models.Contract{}
+	err := tx.Where("user_id = ?", c.Param("user_id")).Find(contract, c.Param("contract_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that contract.")
+		return nil, false
+	}
+	return contract, true
+}
+
+// UsersContractPortalCreate issues a new portal link for the contract,
+// invalidating the old one. ExpiresIn is the number of days the link works
+// for; zero or blank never expires.
+func UsersContractPortalCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+
+	expires := nulls.Time{}
+	if days, _ := strconv.Atoi(c.Param("ExpiresIn")); days > 0 {
+		expires = nulls.NewTime(time.Now().AddDate(0, 0, days))
+	}
+	if err := contract.ResetPortalToken(tx, expires); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Client portal link created.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
+
+// UsersContractPortalDestroy turns the contract's portal off.
+func UsersContractPortalDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+
+	if err := contract.RevokePortalToken(tx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Client portal revoked.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
+
+// portalURL is the address clients open to see the contract's portal.
+func portalURL(contract *models.Contract) string {
+	return fmt.Sprintf("%s/portal/%s", App().Host, contract.PortalToken.String)
+}
+
+// setPortal makes the contract's portal controls available to the contract
+// page.
+func setPortal(c buffalo.Context, contract *models.Contract) {
+	c.Set("portal_url", "")
+	if contract.PortalActive(time.Now()) {
+		c.Set("portal_url", portalURL(contract))
+	}
+	c.Set("portal_expiries", models.PortalExpiries)
+}
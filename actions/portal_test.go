@@ -0,0 +1,8 @@
+package actions
+
+import "net/http"
+
+func (as *ActionSuite) Test_Portal_Unknown_Token() {
+	res := as.HTML("/portal/nope").Get()
+	as.Equal(http.StatusNotFound, res.Code)
+}
@@ -0,0 +1,81 @@
+package actions
+
+import (
+	"buftester/models"
+	"strconv"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// projectParam reads the optional ProjectID form field. A blank value
+// means no project.
+func projectParam(c buffalo.Context) (nulls.Int, error) {
+	v := c.Param("ProjectID")
+	if v == "" {
+		return nulls.Int{}, nil
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil {
+		return nulls.Int{}, errors.Errorf("invalid project %q", v)
+	}
+	return nulls.NewInt(id), nil
+}
+
+// setProjects makes the contract's per-project totals available to the
+// contract page. The projects themselves are loaded with the contract.
+func setProjects(c buffalo.Context, tx *pop.Connection, contract *models.Contract) error {
+	totals, err := models.LoadProjectTotals(tx, contract.ID)
+	if err != nil {
+		return err
+	}
+	c.Set("project_totals", totals)
+	return nil
+}
+
+// UsersContractProjectsCreate adds a project to the contract.
+func UsersContractProjectsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+
+	p := &models.Project{ContractID: contract.ID, Name: c.Param("Name")}
+	verrs, err := tx.ValidateAndCreate(p)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	c.Flash().Add("success", "Project added.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
+
+// UsersContractProjectDestroy removes a project. Its tasks stay on the
+// contract without a project.
+func UsersContractProjectDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	p, err := models.FindProject(tx, contract.ID, c.Param("project_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that project.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	if err := tx.Destroy(p); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Project removed.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
@@ -1,8 +1,11 @@
 package actions
 
 import (
+	"buftester/markdown"
+	"buftester/models"
 	"fmt"
 	"html/template"
+	"net/url"
 
 	"github.com/gobuffalo/buffalo/render"
 	"github.com/gobuffalo/packr/v2"
@@ -43,6 +46,25 @@ func init() {
 				}
 				return fmt.Sprintf("%dm", t)
 			},
+			"formatMoney": models.FormatMoney,
+			"dateInput":   models.DateInput,
+			"earnings":    models.Earnings,
+			"queryEscape": url.QueryEscape,
+			"snippet":     models.Snippet,
+			// renderMarkdown renders a task description, keeping only
+			// lists, links, emphasis and code.
+			"renderMarkdown": markdown.HTML,
+			"plainText":      markdown.Text,
+			// formatDecimal prints cents for number inputs, e.g. "12.50".
+			"formatDecimal": func(cents int) string {
+				return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+			},
+			"percentOf": func(part int, whole int) int {
+				if whole <= 0 {
+					return 0
+				}
+				return part * 100 / whole
+			},
 			"envStatus": func(help plush.HelperContext) (template.HTML, error) {
 				env := help.Context.Value("environment")
 				if env != "production" {
@@ -0,0 +1,52 @@
+package actions
+
+import (
+	"buftester/models"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// searchLimit is the most tasks a search returns.
+const searchLimit = 50
+
+// UsersSearch searches the descriptions of the user's tasks across all
+// their contracts. The boss and date filters are read like the export's.
+func UsersSearch(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	bosses := []models.Boss{}
+	err := tx.Where("id IN (SELECT boss_id FROM contracts WHERE user_id = ?)", user.ID).Order("name asc").All(&bosses)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	hits := []models.TaskHit{}
+	filter, err := taskFilterFromParams(c, user.ID)
+	if err != nil {
+		c.Flash().Add("warning", err.Error())
+	} else if q := c.Param("q"); q != "" {
+		hits, err = models.SearchTasks(tx, filter, q, searchLimit)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	c.Set("user", user)
+	c.Set("bosses", bosses)
+	c.Set("hits", hits)
+	c.Set("q", c.Param("q"))
+	c.Set("boss_id", filter.BossID)
+	c.Set("from", c.Param("from"))
+	c.Set("to", c.Param("to"))
+	c.Set("search_limit", searchLimit)
+	return c.Render(http.StatusOK, r.HTML("search/index.html"))
+}
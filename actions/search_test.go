@@ -0,0 +1,26 @@
+package actions
+
+func (as *ActionSuite) Test_Users_Search_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/search?q=migration").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+func (as *ActionSuite) Test_Users_Search() {
+	user := as.login("search@example.com")
+	as.newTask(as.newContract(user, "ACME"), "Wireframes for the checkout")
+
+	res := as.HTML("/users/%s/search?q=wireframes", user.ID).Get()
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "Wireframes for the checkout")
+}
+
+func (as *ActionSuite) Test_Users_Search_Other_User() {
+	owner := as.login("search@example.com")
+	as.newTask(as.newContract(owner, "ACME"), "Wireframes for the checkout")
+
+	as.login("other@example.com")
+	res := as.HTML("/users/%s/search?q=wireframes", owner.ID).Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
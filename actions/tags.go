@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"buftester/models"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// setTags loads the tags of the contract's tasks and narrows the worklog
+// to the tag given in the query string, if any. The user's tags are made
+// available to complete the task form.
+func setTags(c buffalo.Context, tx *pop.Connection, contract *models.Contract) error {
+	if err := models.LoadTaskTags(tx, contract.Tasks); err != nil {
+		return err
+	}
+	tag := c.Param("tag")
+	if tag != "" {
+		contract.Tasks = models.TasksTagged(contract.Tasks, tag)
+	}
+	tags, err := models.LoadTags(tx, contract.UserID)
+	if err != nil {
+		return err
+	}
+	c.Set("tag", tag)
+	c.Set("tags", tags)
+	return nil
+}
+
+// UsersTagsIndex reports the user's time and earnings per tag across all
+// their contracts. The report covers the current month unless from and to
+// are given as yyyy-mm-dd; to is inclusive.
+func UsersTagsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if v := c.Param("from"); v != "" {
+		if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			from = t
+		}
+	}
+	if v := c.Param("to"); v != "" {
+		if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			to = t
+		}
+	}
+
+	report, err := models.LoadTagReport(tx, models.TaskFilter{UserID: user.ID, From: from, To: to.AddDate(0, 0, 1)})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("user", user)
+	c.Set("report", report)
+	c.Set("report_from", from)
+	c.Set("report_to", to)
+	return c.Render(http.StatusOK, r.HTML("tags/index.html"))
+}
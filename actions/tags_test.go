@@ -0,0 +1,30 @@
+package actions
+
+import "buftester/models"
+
+func (as *ActionSuite) Test_Users_Tags_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/tags").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+func (as *ActionSuite) Test_Users_Tags_Index() {
+	user := as.login("tags@example.com")
+	task := as.newTask(as.newContract(user, "ACME"), "Wireframes")
+	as.NoError(task.SetTags(models.DB, user.ID, []string{"design"}))
+
+	res := as.HTML("/users/%s/tags", user.ID).Get()
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "design")
+}
+
+func (as *ActionSuite) Test_Users_Tags_Index_Other_User() {
+	owner := as.login("tags@example.com")
+	task := as.newTask(as.newContract(owner, "ACME"), "Wireframes")
+	as.NoError(task.SetTags(models.DB, owner.ID, []string{"design"}))
+
+	as.login("other@example.com")
+	res := as.HTML("/users/%s/tags", owner.ID).Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
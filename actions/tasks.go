@@ -4,6 +4,7 @@ import (
 	"buftester/models"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gobuffalo/buffalo"
@@ -16,12 +17,22 @@ func TasksShow(c buffalo.Context) error {
 	tx := c.Value("tx").(*pop.Connection)
 
 	task := &models.Task{}
-	err := tx.Find(task, c.Param("task_id"))
+	err := tx.Eager("Contract").Find(task, c.Param("task_id"))
 	if err != nil {
 		c.Flash().Add("warning", "Cannot find that task.")
 		return c.Redirect(307, "/")
 	}
+	attachments, err := models.LoadTaskAttachments(tx, task.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := setComments(c, tx, task); err != nil {
+		return errors.WithStack(err)
+	}
 	c.Set("task", task)
+	c.Set("attachments", attachments)
+	c.Set("attachment_types", strings.Join(models.AttachmentTypes, ","))
+	c.Set("comment", &models.Comment{})
 	return c.Render(http.StatusOK, r.HTML("tasks/show.html"))
 }
 
@@ -35,7 +46,20 @@ func TasksEdit(c buffalo.Context) error {
 		c.Flash().Add("warning", "Cannot find that task.")
 		return c.Redirect(307, "/")
 	}
+	projects, err := models.LoadProjects(tx, task.ContractID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := task.LoadTags(tx); err != nil {
+		return errors.WithStack(err)
+	}
+	tags, err := models.LoadTags(tx, task.Contract.UserID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
 	c.Set("task", task)
+	c.Set("projects", projects)
+	c.Set("tags", tags)
 	return c.Render(http.StatusOK, r.HTML("tasks/edit.html"))
 }
 
@@ -51,10 +75,26 @@ func TasksUpdate(c buffalo.Context) error {
 		return c.Redirect(307, "/")
 	}
 
+	// Tasks waiting for approval, or approved, cannot change.
+	locked, err := task.Locked(tx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if locked {
+		c.Flash().Add("warning", "That task is on a submitted timesheet and cannot be changed.")
+		return c.Redirect(303, "/users/%s/contracts/%d", task.Contract.UserID, task.Contract.ID)
+	}
+
 	// Bind entity to the HTML form.
 	if err := c.Bind(task); err != nil {
 		return err
 	}
+	if task.ProjectID, err = projectParam(c); err != nil {
+		c.Flash().Add("warning", "Cannot find that project.")
+		return c.Redirect(303, "/users/%s/contracts/%d", task.Contract.UserID, task.Contract.ID)
+	}
+
+	tags := models.ParseTags(c.Param("Tags"))
 
 	task.UpdatedAt = time.Now()
 
@@ -65,12 +105,31 @@ func TasksUpdate(c buffalo.Context) error {
 	}
 
 	if verrs.HasAny() {
+		projects, err := models.LoadProjects(tx, task.ContractID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		userTags, err := models.LoadTags(tx, task.Contract.UserID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		task.Tags = models.TagsNamed(tags)
 		c.Set("task", task)
+		c.Set("projects", projects)
+		c.Set("tags", userTags)
 		// Make the errors available inside the html template
 		c.Set("errors", verrs)
 		return c.Render(422, r.HTML("tasks/edit.html"))
 	}
 
+	if err := task.SetTags(tx, task.Contract.UserID, tags); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := task.Contract.CheckCapAlerts(tx, task.StartTime); err != nil {
+		return errors.WithStack(err)
+	}
+
 	c.Flash().Add("success", "Task updated.")
 	return c.Redirect(303, "/users/%s/contracts/%d", task.Contract.UserID, task.Contract.ID)
 }
@@ -0,0 +1,143 @@
+package actions
+
+import (
+	"buftester/markdown"
+	"buftester/models"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// exportBatchSize is the number of tasks loaded per query when exporting.
+const exportBatchSize = 500
+
+// csvCell keeps spreadsheets from reading a cell as a formula by quoting
+// text that starts with =, +, - or @.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// contractBosses lists the bosses of the contracts, once each, by name.
+func contractBosses(contracts []models.Contract) []models.Boss {
+	seen := map[int]bool{}
+	bosses := []models.Boss{}
+	for _, ct := range contracts {
+		if ct.Boss == nil || seen[ct.Boss.ID] {
+			continue
+		}
+		seen[ct.Boss.ID] = true
+		bosses = append(bosses, *ct.Boss)
+	}
+	sort.Slice(bosses, func(i, j int) bool { return bosses[i].Name < bosses[j].Name })
+	return bosses
+}
+
+// taskFilterFromParams reads the contract, boss, project and date filters
+// from the query string. Dates use the yyyy-mm-dd format and "to" is inclusive.
+func taskFilterFromParams(c buffalo.Context, userID uuid.UUID) (models.TaskFilter, error) {
+	f := models.TaskFilter{UserID: userID}
+
+	if v := c.Param("contract_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return f, errors.Errorf("invalid contract %q", v)
+		}
+		f.ContractID = id
+	}
+	if v := c.Param("boss_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return f, errors.Errorf("invalid boss %q", v)
+		}
+		f.BossID = id
+	}
+	if v := c.Param("project_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return f, errors.Errorf("invalid project %q", v)
+		}
+		f.ProjectID = id
+	}
+	if v := c.Param("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return f, errors.Errorf("invalid from date %q", v)
+		}
+		f.From = t
+	}
+	if v := c.Param("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return f, errors.Errorf("invalid to date %q", v)
+		}
+		f.To = t.AddDate(0, 0, 1)
+	}
+	return f, nil
+}
+
+// UsersTasksExport streams the user's tasks as CSV.
+func UsersTasksExport(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	err := tx.Find(user, c.Param("user_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	filter, err := taskFilterFromParams(c, user.ID)
+	if err != nil {
+		c.Flash().Add("warning", err.Error())
+		return c.Redirect(303, "/users/%s/contracts", user.ID)
+	}
+
+	res := c.Response()
+	res.Header().Set("Content-Type", "text/csv")
+	res.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=tasks-%s.csv", time.Now().Format("20060102")))
+	res.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(res)
+	w.Write([]string{"Date", "Boss", "Project", "Description", "Minutes", "Hours", "Rate", "Amount"})
+
+	count := 0
+	err = filter.EachRow(tx, exportBatchSize, func(t models.TaskRow) error {
+		w.Write([]string{
+			t.StartTime.Format("2006-01-02"),
+			csvCell(t.BossName),
+			csvCell(t.ProjectName),
+			csvCell(markdown.Text(t.Description)),
+			strconv.Itoa(t.Duration),
+			fmt.Sprintf("%.2f", float64(t.Duration)/60),
+			strconv.Itoa(t.Rate),
+			fmt.Sprintf("%.2f", float64(t.Amount())/100),
+		})
+		count++
+		// Push each batch out to the client as it is written.
+		if count%exportBatchSize == 0 {
+			w.Flush()
+			if f, ok := res.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		return w.Error()
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	w.Flush()
+	return errors.WithStack(w.Error())
+}
@@ -0,0 +1,26 @@
+package actions
+
+import "testing"
+
+func (as *ActionSuite) Test_Users_Tasks_Export_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/tasks/export").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+func Test_CSVCell(t *testing.T) {
+	tests := map[string]string{
+		"":               "",
+		"Fixed the form": "Fixed the form",
+		"=SUM(A1:A2)":    "'=SUM(A1:A2)",
+		"+1 555 0100":    "'+1 555 0100",
+		"-2 hours":       "'-2 hours",
+		"@admin":         "'@admin",
+		"a=b":            "a=b",
+	}
+	for in, want := range tests {
+		if got := csvCell(in); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
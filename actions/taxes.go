@@ -0,0 +1,188 @@
+package actions
+
+import (
+	"buftester/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// contractTax is a tax rate on the contract page, checked when it applies.
+type contractTax struct {
+	Rate    models.TaxRate
+	Checked bool
+}
+
+// contractTaxes pairs the user's tax rates with those on the contract.
+func contractTaxes(tx *pop.Connection, contract *models.Contract) ([]contractTax, error) {
+	all, err := models.LoadTaxRates(tx, contract.UserID)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := contract.GetTaxRates(tx)
+	if err != nil {
+		return nil, err
+	}
+	taxes := []contractTax{}
+	for _, rate := range all {
+		ct := contractTax{Rate: rate}
+		for _, a := range applied {
+			if a.ID == rate.ID {
+				ct.Checked = true
+			}
+		}
+		taxes = append(taxes, ct)
+	}
+	return taxes, nil
+}
+
+// UsersTaxesIndex shows the user's tax rates, rounding mode and the tax
+// report. The report covers the current year unless from and to are given
+// as yyyy-mm-dd; to is inclusive.
+func UsersTaxesIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	rates, err := models.LoadTaxRates(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := c.Param("from"); v != "" {
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			from = t
+		}
+	}
+	if v := c.Param("to"); v != "" {
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			to = t
+		}
+	}
+
+	report, err := models.LoadTaxReport(tx, user.ID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("user", user)
+	c.Set("tax_rates", rates)
+	c.Set("roundings", models.TaxRoundings)
+	c.Set("report", report)
+	c.Set("report_to", to)
+	return c.Render(http.StatusOK, r.HTML("taxes/index.html"))
+}
+
+// UsersTaxRatesCreate adds a tax rate.
+func UsersTaxRatesCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	rate := &models.TaxRate{
+		UserID:    user.ID,
+		Name:      c.Param("Name"),
+		Inclusive: c.Param("Inclusive") != "",
+	}
+	bp, err := models.ParsePercent(c.Param("Rate"))
+	if err != nil {
+		c.Flash().Add("warning", "Rate must be a percentage.")
+		return c.Redirect(303, "/users/%s/taxes", user.ID)
+	}
+	rate.Rate = bp
+
+	verrs, err := tx.ValidateAndCreate(rate)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+	} else {
+		c.Flash().Add("success", "Tax rate added.")
+	}
+	return c.Redirect(303, "/users/%s/taxes", user.ID)
+}
+
+// UsersTaxRatesDestroy removes a tax rate from the user and their
+// contracts. Invoices keep their copy of it.
+func UsersTaxRatesDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	rate := &models.TaxRate{}
+	err := tx.Where("user_id = ?", c.Param("user_id")).Find(rate, c.Param("tax_rate_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that tax rate.")
+		return c.Redirect(303, "/users/%s/taxes", c.Param("user_id"))
+	}
+	if err := tx.Destroy(rate); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Tax rate removed.")
+	return c.Redirect(303, "/users/%s/taxes", c.Param("user_id"))
+}
+
+// UsersTaxRoundingUpdate sets how new invoices round their taxes.
+func UsersTaxRoundingUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	mode := c.Param("TaxRounding")
+	if mode != models.TaxRoundInvoice && mode != models.TaxRoundLine {
+		c.Flash().Add("warning", "Unknown rounding mode.")
+		return c.Redirect(303, "/users/%s/taxes", user.ID)
+	}
+	user.TaxRounding = mode
+	if err := tx.UpdateColumns(user, "tax_rounding"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Rounding updated.")
+	return c.Redirect(303, "/users/%s/taxes", user.ID)
+}
+
+// UsersContractTaxesUpdate sets which tax rates apply to a contract.
+func UsersContractTaxesUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract := &models.Contract{}
+	err := tx.Where("user_id = ?", c.Param("user_id")).Find(contract, c.Param("contract_id"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that contract.")
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+
+	c.Request().ParseForm()
+	ids := []int{}
+	for _, v := range c.Request().Form["TaxRateIDs"] {
+		if id, err := strconv.Atoi(v); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if err := contract.SetTaxRates(tx, ids); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Contract taxes updated.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
@@ -0,0 +1,7 @@
+package actions
+
+func (as *ActionSuite) Test_Users_Taxes_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/taxes").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
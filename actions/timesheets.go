@@ -0,0 +1,200 @@
+package actions
+
+import (
+	"buftester/mailers"
+	"buftester/models"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// UsersTimesheetsIndex lists the weeks waiting to be submitted and past
+// timesheets.
+func UsersTimesheetsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(307, "/")
+	}
+
+	weeks, err := models.LoadPendingWeeks(tx, user)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sheets, err := models.LoadTimesheets(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("user", user)
+	c.Set("weeks", weeks)
+	c.Set("timesheets", sheets)
+	return c.Render(http.StatusOK, r.HTML("timesheets/index.html"))
+}
+
+// UsersTimesheetsCreate submits a week of tasks on a contract for approval.
+// The boss's billing contact is emailed a review link; managers of the
+// contract's organization can review it from their approvals page.
+func UsersTimesheetsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that user.")
+		return c.Redirect(303, "/")
+	}
+
+	contract := &models.Contract{}
+	err := tx.Where("user_id = ?", user.ID).Eager("Boss.Contacts").Find(contract, c.Param("ContractID"))
+	if err != nil {
+		c.Flash().Add("warning", "Cannot find that contract.")
+		return c.Redirect(303, "/users/%s/timesheets", user.ID)
+	}
+	week, err := time.ParseInLocation("2006-01-02", c.Param("Week"), time.Local)
+	if err != nil {
+		c.Flash().Add("warning", "Pick a week to submit.")
+		return c.Redirect(303, "/users/%s/timesheets", user.ID)
+	}
+
+	to := contract.Boss.BillingEmail()
+	if to == "" && !contract.OrganizationID.Valid {
+		c.Flash().Add("warning", "Nobody can approve this timesheet. Add a contact with an email to "+contract.Boss.Name+" first.")
+		return c.Redirect(303, "/users/%s/timesheets", user.ID)
+	}
+
+	ts, err := models.SubmitTimesheet(tx, contract, week, time.Now())
+	if errors.Cause(err) == models.ErrNothingToSubmit {
+		c.Flash().Add("warning", "There are no tasks to submit for that week.")
+		return c.Redirect(303, "/users/%s/timesheets", user.ID)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if to != "" {
+		err := tx.Eager("Contract.Boss", "Contract.User", "Tasks").Find(ts, ts.ID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		link := fmt.Sprintf("%s/timesheets/review/%s", App().Host, ts.Token.String)
+		if err := mailers.SendTimesheetReview(ts, to, link); err != nil {
+			c.Logger().Errorf("sending timesheet %d: %v", ts.ID, err)
+			c.Flash().Add("warning", "The review email could not be sent.")
+		}
+	}
+
+	c.Flash().Add("success", "Timesheet submitted for approval.")
+	return c.Redirect(303, "/users/%s/timesheets", user.ID)
+}
+
+// TimesheetsApprovals lists the timesheets waiting for the current user to
+// review as an organization manager.
+func TimesheetsApprovals(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	sheets, err := models.LoadApprovals(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("timesheets", sheets)
+	return c.Render(http.StatusOK, r.HTML("timesheets/approvals.html"))
+}
+
+// TimesheetsReview approves or rejects a timesheet as an organization
+// manager.
+func TimesheetsReview(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	ts := &models.Timesheet{}
+	if err := tx.Find(ts, c.Param("timesheet_id")); err != nil {
+		c.Flash().Add("warning", "Cannot find that timesheet.")
+		return c.Redirect(303, "/timesheets/approvals")
+	}
+	ok, err := ts.CanReview(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !ok {
+		c.Flash().Add("warning", "You cannot review that timesheet.")
+		return c.Redirect(303, "/timesheets/approvals")
+	}
+
+	if _, err := reviewTimesheet(c, tx, ts, user.Email); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Redirect(303, "/timesheets/approvals")
+}
+
+// TimesheetsReviewShow shows a timesheet to the boss contact holding its
+// review link.
+func TimesheetsReviewShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	ts, err := models.FindTimesheetByToken(tx, c.Param("token"))
+	if err != nil {
+		return c.Error(http.StatusNotFound, errors.New("timesheet not found"))
+	}
+
+	c.Set("timesheet", ts)
+	c.Set("token", c.Param("token"))
+	return c.Render(http.StatusOK, r.HTML("timesheets/review.html"))
+}
+
+// TimesheetsReviewUpdate records the boss contact's decision. The link
+// stops working once it is used.
+func TimesheetsReviewUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	token := c.Param("token")
+	ts, err := models.FindTimesheetByToken(tx, token)
+	if err != nil {
+		return c.Error(http.StatusNotFound, errors.New("timesheet not found"))
+	}
+
+	reviewer := ts.Contract.Boss.BillingEmail()
+	if reviewer == "" {
+		reviewer = ts.Contract.Boss.Name
+	}
+	ok, err := reviewTimesheet(c, tx, ts, reviewer)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !ok {
+		return c.Redirect(303, "/timesheets/review/%s", token)
+	}
+	return c.Redirect(303, "/")
+}
+
+// reviewTimesheet applies the Decision and Comment params to the timesheet,
+// flashing the outcome. It returns false when the review was refused.
+func reviewTimesheet(c buffalo.Context, tx *pop.Connection, ts *models.Timesheet, reviewer string) (bool, error) {
+	approve := c.Param("Decision") == "approve"
+	err := ts.Review(tx, approve, reviewer, c.Param("Comment"), time.Now())
+	switch errors.Cause(err) {
+	case nil:
+	case models.ErrCommentRequired:
+		c.Flash().Add("warning", "Say what needs to change when rejecting a timesheet.")
+		return false, nil
+	case models.ErrAlreadyReviewed:
+		c.Flash().Add("warning", "That timesheet has already been reviewed.")
+		return false, nil
+	default:
+		return false, err
+	}
+
+	if approve {
+		c.Flash().Add("success", "Timesheet approved.")
+	} else {
+		c.Flash().Add("success", "Timesheet sent back for changes.")
+	}
+	return true, nil
+}
@@ -0,0 +1,125 @@
+package actions
+
+import (
+	"buftester/models"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (as *ActionSuite) Test_Users_Timesheets_Requires_Login() {
+	res := as.HTML("/users/00000000-0000-0000-0000-000000000000/timesheets").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+func (as *ActionSuite) Test_Timesheets_Approvals_Requires_Login() {
+	res := as.HTML("/timesheets/approvals").Get()
+	as.Equal(302, res.Code)
+	as.Equal("/", res.Location())
+}
+
+// timesheetContract gives the user a contract whose boss has a billing
+// contact to review timesheets.
+func (as *ActionSuite) timesheetContract(user *models.User) *models.Contract {
+	contract := as.newContract(user, "Initech")
+	verrs, err := contract.Boss.SetContacts(models.DB, models.BossContacts{{Name: "Peter", Email: nulls.NewString("peter@initech.example")}})
+	as.NoError(err)
+	as.False(verrs.HasAny())
+	return contract
+}
+
+func (as *ActionSuite) Test_Users_Timesheets_Create() {
+	user := as.login("worker@example.com")
+	contract := as.timesheetContract(user)
+	task := as.newTask(contract, "Wireframes")
+
+	week := models.StartOfWeek(time.Now()).Format("2006-01-02")
+	res := as.HTML("/users/%s/timesheets", user.ID).Post(map[string]string{"ContractID": fmt.Sprint(contract.ID), "Week": week})
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/timesheets", user.ID), res.Location())
+
+	ts := &models.Timesheet{}
+	as.NoError(models.DB.Where("contract_id = ?", contract.ID).First(ts))
+	as.Equal(models.TimesheetSubmitted, ts.Status)
+	as.NoError(models.DB.Reload(task))
+	as.Equal(nulls.NewInt(ts.ID), task.TimesheetID)
+}
+
+func (as *ActionSuite) Test_Timesheets_Review_Token() {
+	user := as.login("worker@example.com")
+	contract := as.timesheetContract(user)
+	as.newTask(contract, "Wireframes")
+	now := time.Now()
+	ts, err := models.SubmitTimesheet(models.DB, contract, now, now)
+	as.NoError(err)
+	token := ts.Token.String
+
+	res := as.HTML("/timesheets/review/%s", token).Get()
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "Wireframes")
+
+	res = as.HTML("/timesheets/review/%s", token).Post(map[string]string{"Decision": "approve"})
+	as.Equal(303, res.Code)
+	as.Equal("/", res.Location())
+	as.NoError(models.DB.Reload(ts))
+	as.Equal(models.TimesheetApproved, ts.Status)
+	as.Equal("peter@initech.example", ts.Reviewer.String)
+
+	// The link stops working once it is used.
+	res = as.HTML("/timesheets/review/%s", token).Get()
+	as.Equal(404, res.Code)
+	res = as.HTML("/timesheets/review/%s", token).Post(map[string]string{"Decision": "reject", "Comment": "Too long"})
+	as.Equal(404, res.Code)
+	as.NoError(models.DB.Reload(ts))
+	as.Equal(models.TimesheetApproved, ts.Status)
+}
+
+func (as *ActionSuite) Test_Tasks_Update_Locked() {
+	user := as.login("worker@example.com")
+	contract := as.timesheetContract(user)
+	locked := as.newTask(contract, "Wireframes")
+	now := time.Now()
+	_, err := models.SubmitTimesheet(models.DB, contract, now, now)
+	as.NoError(err)
+	open := as.newTask(contract, "Mockups")
+
+	res := as.HTML("/tasks/%d/edit", locked.ID).Post(map[string]string{"Duration": "30", "Description": "Changed"})
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/contracts/%d", user.ID, contract.ID), res.Location())
+
+	// Naming the locked task in the form of another does not get around it.
+	res = as.HTML("/tasks/%d/edit", open.ID).Post(map[string]string{"ID": fmt.Sprint(locked.ID), "Duration": "30", "Description": "Changed"})
+	as.Equal(303, res.Code)
+
+	as.NoError(models.DB.Reload(locked))
+	as.Equal(60, locked.Duration)
+	as.Equal("Wireframes", locked.Description)
+	as.NoError(models.DB.Reload(open))
+	as.Equal("Changed", open.Description)
+}
+
+func (as *ActionSuite) Test_Invoices_Create_Skips_Unapproved() {
+	user := as.login("worker@example.com")
+	contract := as.timesheetContract(user)
+	task := as.newTask(contract, "Wireframes")
+	path := fmt.Sprintf("/users/%s/contracts/%d/invoices", user.ID, contract.ID)
+
+	res := as.HTML(path).Post(map[string]string{"PaymentTerms": "30"})
+	as.Equal(303, res.Code)
+	as.Equal(fmt.Sprintf("/users/%s/contracts/%d", user.ID, contract.ID), res.Location())
+	count, err := models.DB.Where("contract_id = ?", contract.ID).Count(&models.Invoice{})
+	as.NoError(err)
+	as.Equal(0, count)
+
+	now := time.Now()
+	ts, err := models.SubmitTimesheet(models.DB, contract, now, now)
+	as.NoError(err)
+	as.NoError(ts.Review(models.DB, true, "peter@initech.example", "", now))
+
+	res = as.HTML(path).Post(map[string]string{"PaymentTerms": "30"})
+	as.Equal(303, res.Code)
+	as.NoError(models.DB.Reload(task))
+	as.True(task.InvoiceID.Valid)
+}
@@ -24,6 +24,12 @@ func SetCurrentUser(next buffalo.Handler) buffalo.Handler {
 				return errors.WithStack(err)
 			}
 			c.Set("current_user", u)
+
+			unread, err := models.UnreadNotifications(tx, u.ID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			c.Set("unread_notifications", unread)
 		}
 		return next(c)
 	}
@@ -142,6 +148,10 @@ func UsersShow(c buffalo.Context) error {
 		return c.Redirect(307, "/")
 	}
 
+	if user.CalendarToken.Valid {
+		c.Set("calendar_url", fmt.Sprintf("%s/calendar/%s/tasks.ics", App().Host, user.CalendarToken.String))
+	}
+
 	c.Set("user", user)
 	return c.Render(http.StatusOK, r.HTML("users/show.html"))
 }
@@ -4,8 +4,11 @@ import (
 	"buftester/models"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/pkg/errors"
 )
@@ -27,6 +30,12 @@ func UsersContractsIndex(c buffalo.Context) error {
 	if err != nil {
 		c.Flash().Add("warning", "No contracts found.")
 	}
+	projects, err := models.LoadUserProjects(tx, user.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.Set("projects", projects)
+	c.Set("bosses", contractBosses(user.Contracts))
 
 	c.Set("current_user", user)
 	return c.Render(http.StatusOK, r.HTML("users/contracts_index.html"))
@@ -46,7 +55,7 @@ func UsersContractsNew(c buffalo.Context) error {
 
 	// Load bosses for select.
 	bosses := []models.Boss{}
-	err = tx.All(&bosses)
+	err = tx.Scope(models.BossesVisibleTo(user.ID)).Order("name asc").All(&bosses)
 	if err != nil || len(bosses) == 0 {
 		c.Flash().Add("warning", "No bosses found.")
 		return c.Redirect(307, "/bosses/index")
@@ -58,8 +67,7 @@ func UsersContractsNew(c buffalo.Context) error {
 	// Provide default value on select, if passed via query param.
 	bossID := c.Param("bid")
 	if bossID != "" {
-		boss := &models.Boss{}
-		err = tx.Find(boss, bossID)
+		boss, err := models.FindBoss(tx, user.ID, bossID)
 		if err != nil {
 			fmt.Printf("Cannot find boss %v", err)
 		} else {
@@ -75,6 +83,7 @@ func UsersContractsNew(c buffalo.Context) error {
 	c.Set("user", user)
 	c.Set("contract", contract)
 	c.Set("bosses", bosses)
+	c.Set("contract_kinds", models.ContractKinds)
 	return c.Render(http.StatusOK, r.HTML("users/contracts_new.html"))
 }
 
@@ -96,27 +105,27 @@ func UsersContractCreate(c buffalo.Context) error {
 	}
 	c.Set("user", user)
 	contract.UserID = user.ID
-
-	// Try to load boss.
-	boss := &models.Boss{}
-	err = tx.Find(boss, contract.BossID)
-	if err != nil {
-		c.Flash().Add("warning", "Cannot find that Employer.")
+	if contract.StartsOn, err = dateParam(c, "StartsOn"); err != nil {
+		c.Flash().Add("warning", "Cannot read the start date.")
 		return UsersContractsNew(c)
 	}
-
-	// Guard against duplicate combo user-boss to prevent duplicates.
-	previous := []models.Contract{}
-	err = tx.Where("user_id = ? AND boss_id = ?", user.ID, boss.ID).All(&previous)
-	if err != nil {
-		c.Flash().Add("warning", "Error finding user jobs.")
+	if contract.EndsOn, err = dateParam(c, "EndsOn"); err != nil {
+		c.Flash().Add("warning", "Cannot read the end date.")
+		return UsersContractsNew(c)
 	}
-	if len(previous) > 0 {
-		c.Flash().Add("warning", "Contract already exists.")
+	if err := termsParams(c, contract); err != nil {
+		c.Flash().Add("warning", "Enter the fee and included hours as numbers.")
 		return UsersContractsNew(c)
 	}
 
-	// Validate the data from the html form.
+	// Try to load boss.
+	if _, err := models.FindBoss(tx, user.ID, contract.BossID); err != nil {
+		c.Flash().Add("warning", "Cannot find that Employer.")
+		return UsersContractsNew(c)
+	}
+
+	// Validate the data from the html form. Contract names must be unique
+	// per user and boss.
 	verrs, err := tx.ValidateAndCreate(contract)
 	if err != nil {
 		return errors.WithStack(err)
@@ -157,9 +166,32 @@ func UsersContractShow(c buffalo.Context) error {
 	task := &models.Task{}
 	_ = task.CreateNew()
 
+	taxes, err := contractTaxes(tx, contract)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
 	c.Set("current_user", user)
 	c.Set("contract", contract)
 	c.Set("task", task)
+	c.Set("contract_taxes", taxes)
+	c.Set("cap_warning", "")
+	setPortal(c, contract)
+	if err := setCap(c, tx, contract); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := setTerms(c, tx, contract); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := setProjects(c, tx, contract); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := setExpenses(c, tx, contract); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := setTags(c, tx, contract); err != nil {
+		return errors.WithStack(err)
+	}
 	return c.Render(http.StatusOK, r.HTML("users/contract_show.html"))
 }
 
@@ -183,25 +215,156 @@ func UserTaskCreate(c buffalo.Context) error {
 		return c.Redirect(307, "/users/%s", user.ID)
 	}
 
+	if contract.IsArchived() {
+		c.Flash().Add("warning", "That contract is archived. Restore it to log time.")
+		return c.Redirect(303, "/users/%s/contracts/%d", user.ID, contract.ID)
+	}
+
 	task := &models.Task{}
 	if err := c.Bind(task); err != nil {
 		return err
 	}
 
 	task.ContractID = contract.ID
+	if task.ProjectID, err = projectParam(c); err != nil {
+		c.Flash().Add("warning", "Cannot find that project.")
+		return c.Redirect(303, "/users/%s/contracts/%d", user.ID, contract.ID)
+	}
+	tags := models.ParseTags(c.Param("Tags"))
 	// Validate the data from the html form.
 	verrs, err := tx.ValidateAndCreate(task)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	if verrs.HasAny() {
+	// Ask before logging time past the contract's cap.
+	capWarning := ""
+	if !verrs.HasAny() && c.Param("OverCap") == "" {
+		usage, err := contract.LoadCapUsage(tx, task.StartTime)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if usage != nil && usage.Over() {
+			capWarning = fmt.Sprintf("This task takes the contract to %s, over its %s cap.", usage.UsedLabel(), contract.CapLabel())
+		}
+	}
+
+	if verrs.HasAny() || capWarning != "" {
+		// Nothing is saved; the transaction is rolled back on 422.
+		taxes, err := contractTaxes(tx, contract)
+		if err != nil {
+			return errors.WithStack(err)
+		}
 		c.Set("contract", contract)
 		c.Set("task", task)
+		c.Set("contract_taxes", taxes)
+		c.Set("cap_warning", capWarning)
+		setPortal(c, contract)
+		if err := setCap(c, tx, contract); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := setTerms(c, tx, contract); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := setProjects(c, tx, contract); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := setExpenses(c, tx, contract); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := setTags(c, tx, contract); err != nil {
+			return errors.WithStack(err)
+		}
+		task.Tags = models.TagsNamed(tags)
 		// Make the errors available inside the html template
 		c.Set("errors", verrs)
 		return c.Render(422, r.HTML("users/contract_show.html"))
 	}
+	if err := task.SetTags(tx, user.ID, tags); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := contract.CheckCapAlerts(tx, task.StartTime); err != nil {
+		return errors.WithStack(err)
+	}
 	c.Flash().Add("success", "New task created")
 	return c.Redirect(303, "/users/%s/contracts/%d", user.ID, contract.ID)
 }
+
+// dateParam reads an optional date input. A blank value is null.
+func dateParam(c buffalo.Context, name string) (nulls.Time, error) {
+	v := strings.TrimSpace(c.Param(name))
+	if v == "" {
+		return nulls.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return nulls.Time{}, err
+	}
+	return nulls.NewTime(t), nil
+}
+
+// UsersContractDatesUpdate sets the contract's start and end dates. Either
+// may be left blank.
+func UsersContractDatesUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+
+	var err error
+	if contract.StartsOn, err = dateParam(c, "StartsOn"); err != nil {
+		c.Flash().Add("warning", "Cannot read the start date.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+	if contract.EndsOn, err = dateParam(c, "EndsOn"); err != nil {
+		c.Flash().Add("warning", "Cannot read the end date.")
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	verrs, err := tx.ValidateAndUpdate(contract)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("warning", verrs.Error())
+		return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+	}
+
+	c.Flash().Add("success", "Contract dates saved.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
+
+// UsersContractArchive hides the contract from the home page and the ways
+// of logging time. Its history stays available.
+func UsersContractArchive(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	if err := contract.SetArchived(tx, true, time.Now()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Contract archived.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
+
+// UsersContractUnarchive restores an archived contract.
+func UsersContractUnarchive(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	contract, ok := ownContract(c, tx)
+	if !ok {
+		return c.Redirect(303, "/users/%s/contracts", c.Param("user_id"))
+	}
+	if err := contract.SetArchived(tx, false, time.Now()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Contract restored.")
+	return c.Redirect(303, "/users/%s/contracts/%d", contract.UserID, contract.ID)
+}
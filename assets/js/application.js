@@ -3,5 +3,20 @@ require("bootstrap/dist/js/bootstrap.bundle.js");
 require("@fortawesome/fontawesome-free/js/all.js");
 
 $(() => {
-
+  // Tag inputs hold a comma-separated list. Prefix each suggestion with the
+  // tags already typed so the browser completes the last one.
+  $("input[data-tag-input]").each(function () {
+    const list = document.getElementById(this.getAttribute("list"));
+    const names = Array.from(list.options).map((o) => o.value);
+    $(this).on("input", function () {
+      const i = this.value.lastIndexOf(",");
+      const head = i < 0 ? "" : this.value.slice(0, i + 1) + " ";
+      list.innerHTML = "";
+      names.forEach((name) => {
+        const option = document.createElement("option");
+        option.value = head + name;
+        list.appendChild(option);
+      });
+    });
+  });
 });
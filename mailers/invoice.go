@@ -0,0 +1,37 @@
+package mailers
+
+import (
+	"bytes"
+
+	"buftester/models"
+
+	"github.com/gobuffalo/buffalo/mail"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/pkg/errors"
+)
+
+// SendInvoice emails the invoice to the given address with its PDF attached.
+// The invoice must be loaded as for rendering.
+func SendInvoice(inv *models.Invoice, to string) error {
+	var b bytes.Buffer
+	if err := inv.WritePDF(&b); err != nil {
+		return errors.WithStack(err)
+	}
+
+	m := mail.NewMessage()
+	m.Subject = "Invoice " + inv.Number
+	m.From = from
+	m.To = []string{to}
+	if inv.User != nil && inv.User.Email != "" {
+		m.SetHeader("Reply-To", inv.User.Email)
+	}
+
+	err := m.AddBody(r.HTML("invoice.plush.html"), render.Data{"invoice": inv})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := m.AddAttachment(inv.PDFName(), "application/pdf", &b); err != nil {
+		return errors.WithStack(err)
+	}
+	return smtp.Send(m)
+}
@@ -0,0 +1,28 @@
+package mailers
+
+import (
+	"buftester/models"
+
+	"github.com/gobuffalo/buffalo/mail"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/pkg/errors"
+)
+
+// SendTimesheetReview asks a boss contact to approve a submitted timesheet
+// through the review link. The timesheet must be loaded with its contract,
+// boss, user and tasks.
+func SendTimesheetReview(ts *models.Timesheet, to, link string) error {
+	m := mail.NewMessage()
+	m.Subject = "Timesheet for the week of " + ts.WeekStart.Format("January 2, 2006")
+	m.From = from
+	m.To = []string{to}
+	if ts.Contract.User != nil && ts.Contract.User.Email != "" {
+		m.SetHeader("Reply-To", ts.Contract.User.Email)
+	}
+
+	err := m.AddBody(r.HTML("timesheet_review.plush.html"), render.Data{"timesheet": ts, "link": link})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return smtp.Send(m)
+}
@@ -0,0 +1,130 @@
+// Package markdown renders task descriptions written in Markdown. Only a
+// safe subset is kept: paragraphs, lists, links, emphasis and code. Raw
+// HTML, images and anything else are reduced to their text.
+package markdown
+
+import (
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+
+	gfm "github.com/gobuffalo/github_flavored_markdown"
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/net/html"
+)
+
+// policy keeps the elements of the subset. Links must be absolute http,
+// https or mailto URLs and open in a new tab.
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "ul", "ol", "li", "em", "strong", "del", "code", "pre", "blockquote")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+// HTML renders src as sanitized HTML.
+func HTML(src string) template.HTML {
+	if strings.TrimSpace(src) == "" {
+		return ""
+	}
+	return template.HTML(policy.SanitizeBytes(gfm.Markdown([]byte(src))))
+}
+
+var (
+	spaces   = regexp.MustCompile(`\s+`)
+	lineEnds = regexp.MustCompile(` *\n+`)
+)
+
+// Text renders src as plain text for CSV exports and invoices. Blocks and
+// list items go on their own lines, list items are marked with "- " or
+// their number, and links are followed by their URL in parentheses.
+func Text(src string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(string(HTML(src))))
+	lists := []int{} // next number for each open list, 0 when unordered
+	pre := 0
+	href, linkStart := "", 0
+
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(lineEnds.ReplaceAllString(b.String(), "\n"))
+		case html.TextToken:
+			s := string(z.Text())
+			if pre == 0 {
+				s = spaces.ReplaceAllString(s, " ")
+				if b.Len() == 0 || strings.HasSuffix(b.String(), "\n") {
+					s = strings.TrimLeft(s, " ")
+				}
+			}
+			b.WriteString(s)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "p", "blockquote":
+				newline()
+			case "pre":
+				newline()
+				pre++
+			case "br":
+				b.WriteByte('\n')
+			case "ul":
+				newline()
+				lists = append(lists, 0)
+			case "ol":
+				newline()
+				lists = append(lists, 1)
+			case "li":
+				newline()
+				marker := "- "
+				if n := len(lists); n > 0 && lists[n-1] > 0 {
+					marker = strconv.Itoa(lists[n-1]) + ". "
+					lists[n-1]++
+				}
+				if n := len(lists); n > 1 {
+					b.WriteString(strings.Repeat("  ", n-1))
+				}
+				b.WriteString(marker)
+			case "a":
+				href, linkStart = "", b.Len()
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					if string(k) == "href" {
+						href = string(v)
+					}
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "p", "blockquote", "li":
+				newline()
+			case "pre":
+				pre--
+				newline()
+			case "ul", "ol":
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+				}
+				newline()
+			case "a":
+				text := b.String()[linkStart:]
+				if href != "" && strings.TrimPrefix(href, "mailto:") != strings.TrimSpace(text) {
+					b.WriteString(" (" + href + ")")
+				}
+				href = ""
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_HTML(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+		not  []string
+	}{
+		{"Fixed the *login* bug", []string{"<p>Fixed the <em>login</em> bug</p>"}, nil},
+		{"- one\n- two", []string{"<ul>", "<li>one</li>", "<li>two</li>"}, nil},
+		{"Run `make test`", []string{"<code>make test</code>"}, nil},
+		{"[docs](https://example.com)", []string{`<a href="https://example.com" rel="nofollow noopener" target="_blank">docs</a>`}, nil},
+		{"<script>alert(1)</script><b>bold</b>", []string{"bold"}, []string{"<script", "alert", "<b>"}},
+		{"[bad](javascript:alert(1)) ![img](https://example.com/a.png)", []string{"bad"}, []string{"javascript", "<img", "href"}},
+		{"# Title", []string{"Title"}, []string{"<h1", "href"}},
+	}
+	for _, tt := range tests {
+		got := string(HTML(tt.src))
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("HTML(%q) = %q, want it to contain %q", tt.src, got, w)
+			}
+		}
+		for _, n := range tt.not {
+			if strings.Contains(got, n) {
+				t.Errorf("HTML(%q) = %q, want no %q", tt.src, got, n)
+			}
+		}
+	}
+	if got := HTML("  "); got != "" {
+		t.Errorf("HTML of blank = %q, want empty", got)
+	}
+}
+
+func Test_Text(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"plain & simple < 3", "plain & simple < 3"},
+		{"Fixed the *login* bug\nfor good", "Fixed the login bug for good"},
+		{"Intro\n\n- one\n- two\n  1. first\n  2. second\n\nDone", "Intro\n- one\n- two\n  1. first\n  2. second\nDone"},
+		{"See [docs](https://example.com/docs) or https://example.com", "See docs (https://example.com/docs) or https://example.com"},
+		{"```\nmake  test\n```", "make  test"},
+		{"<script>alert(1)</script>", ""},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.src); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE tasks DROP INDEX tasks_description_fulltext_idx;
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD FULLTEXT INDEX tasks_description_fulltext_idx (description);
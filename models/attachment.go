@@ -0,0 +1,172 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// MaxAttachmentSize is the largest file that can be attached, in bytes.
+const MaxAttachmentSize = 10 * 1024 * 1024
+
+// AttachmentTypes lists the MIME types that can be attached: receipts,
+// screenshots and scanned or signed documents.
+var AttachmentTypes = []string{
+	"application/pdf",
+	"image/gif",
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"text/plain",
+}
+
+// Attachment is a file attached to a task, an invoice or an expense. The
+// file itself is kept in a storage.Store under StorageKey; only its owner
+// may read it.
+type Attachment struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      uuid.UUID `json:"-" db:"user_id"`
+	TaskID      nulls.Int `json:"task_id" db:"task_id"`
+	InvoiceID   nulls.Int `json:"invoice_id" db:"invoice_id"`
+	ExpenseID   nulls.Int `json:"expense_id" db:"expense_id"`
+	Filename    string    `json:"filename" db:"filename"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int       `json:"size" db:"size"`
+	StorageKey  string    `json:"-" db:"storage_key"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (a Attachment) String() string {
+	ja, _ := json.Marshal(a)
+	return string(ja)
+}
+
+// Attachments is not required by pop and may be deleted
+type Attachments []Attachment
+
+// NewAttachment describes an upload for the user. The MIME type is
+// sniffed from head, the first bytes of the file, rather than trusted
+// from the browser, and the file gets a fresh storage key.
+func NewAttachment(userID uuid.UUID, filename string, size int64, head []byte) *Attachment {
+	key := uuid.Must(uuid.NewV4()).String()
+	return &Attachment{
+		UserID:      userID,
+		Filename:    cleanFilename(filename),
+		ContentType: DetectAttachmentType(head),
+		Size:        int(size),
+		StorageKey:  key[:2] + "/" + key,
+	}
+}
+
+// DetectAttachmentType sniffs the MIME type of a file from its first
+// bytes, without parameters such as the charset.
+func DetectAttachmentType(head []byte) string {
+	t, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return t
+}
+
+// cleanFilename keeps the base name of an uploaded file and drops control
+// characters, so it is safe to show and to send back in headers.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == ".." || name == "/" {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (a *Attachment) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	errs := validate.Validate(
+		&validators.StringIsPresent{Field: a.Filename, Name: "Filename"},
+	)
+	owners := 0
+	for _, id := range []nulls.Int{a.TaskID, a.InvoiceID, a.ExpenseID} {
+		if id.Valid {
+			owners++
+		}
+	}
+	if owners != 1 {
+		errs.Add("task_id", "Attach the file to a task, an invoice or an expense.")
+	}
+	if a.Size <= 0 {
+		errs.Add("size", "The file is empty.")
+	} else if a.Size > MaxAttachmentSize {
+		errs.Add("size", fmt.Sprintf("Files can be up to %s.", formatBytes(MaxAttachmentSize)))
+	}
+	allowed := false
+	for _, t := range AttachmentTypes {
+		if a.ContentType == t {
+			allowed = true
+		}
+	}
+	if !allowed {
+		errs.Add("content_type", "Attach a PDF, an image or a text file.")
+	}
+	return errs, nil
+}
+
+// SizeLabel is the file size for display, e.g. "1.2 MB".
+func (a Attachment) SizeLabel() string {
+	return formatBytes(a.Size)
+}
+
+// Disposition is the Content-Disposition header for downloading the file
+// under its original name.
+func (a Attachment) Disposition() string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%d KB", n/1024)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// LoadTaskAttachments returns the files attached to a task, oldest first.
+func LoadTaskAttachments(tx *pop.Connection, taskID int) (Attachments, error) {
+	as := Attachments{}
+	err := tx.Where("task_id = ?", taskID).Order("created_at asc, id asc").All(&as)
+	return as, err
+}
+
+// LoadInvoiceAttachments returns the files attached to an invoice, oldest
+// first.
+func LoadInvoiceAttachments(tx *pop.Connection, invoiceID int) (Attachments, error) {
+	as := Attachments{}
+	err := tx.Where("invoice_id = ?", invoiceID).Order("created_at asc, id asc").All(&as)
+	return as, err
+}
+
+// FindAttachment finds one of the user's attachments.
+func FindAttachment(tx *pop.Connection, userID uuid.UUID, id interface{}) (*Attachment, error) {
+	a := &Attachment{}
+	err := tx.Where("user_id = ?", userID).Find(a, id)
+	return a, err
+}
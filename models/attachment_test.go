@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_NewAttachment() {
+	userID := uuid.Must(uuid.NewV4())
+	a := NewAttachment(userID, `C:\scans\receipt.pdf`, 2048, []byte("%PDF-1.4\n"))
+	ms.Equal("receipt.pdf", a.Filename)
+	ms.Equal("application/pdf", a.ContentType)
+	ms.Equal("2 KB", a.SizeLabel())
+	ms.True(strings.HasPrefix(a.StorageKey, a.StorageKey[3:5]+"/"))
+	ms.Equal(`attachment; filename=receipt.pdf`, a.Disposition())
+
+	ms.Equal("text/plain", DetectAttachmentType([]byte("hours for march")))
+	ms.Equal("", cleanFilename("../"))
+	ms.Equal("notes.txt", cleanFilename("dir/no\ttes.txt"))
+}
+
+func (ms *ModelSuite) Test_Attachment_Validate() {
+	a := NewAttachment(uuid.Must(uuid.NewV4()), "page.html", 10, []byte("<html><body>hi</body></html>"))
+	verrs, err := a.Validate(DB)
+	ms.NoError(err)
+	ms.NotEmpty(verrs.Get("task_id"))
+	ms.NotEmpty(verrs.Get("content_type"))
+
+	a = NewAttachment(a.UserID, "big.txt", MaxAttachmentSize+1, []byte("text"))
+	a.TaskID = nulls.NewInt(1)
+	verrs, err = a.Validate(DB)
+	ms.NoError(err)
+	ms.NotEmpty(verrs.Get("size"))
+	ms.Empty(verrs.Get("task_id"))
+	ms.Empty(verrs.Get("content_type"))
+}
@@ -2,20 +2,34 @@ package models
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
 )
 
+// DefaultPaymentTerms is the number of days to pay when a boss has no terms
+// of their own.
+const DefaultPaymentTerms = 30
+
 // Boss is used by pop to map your bosses database table to your go code.
 type Boss struct {
-	ID             int        `json:"id" db:"id"`
-	Name           string     `json:"name" db:"name"`
-	Contracts      []Contract `json:"contracts,omitempty" has_many:"contracts"`
-	CreateContract bool       `json:"-" db:"-"`
-	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	ID             int          `json:"id" db:"id" form:"-"`
+	Name           string       `json:"name" db:"name"`
+	UserID         nulls.UUID   `json:"-" db:"user_id" form:"-"`
+	OrganizationID nulls.Int    `json:"-" db:"organization_id" form:"-"`
+	Address        nulls.String `json:"address" db:"address"`
+	TaxID          nulls.String `json:"tax_id" db:"tax_id"`
+	PaymentTerms   nulls.Int    `json:"payment_terms" db:"payment_terms" form:"-"`
+	Notes          nulls.String `json:"notes" db:"notes"`
+	Contracts      []Contract   `json:"contracts,omitempty" has_many:"contracts"`
+	Contacts       BossContacts `json:"contacts,omitempty" has_many:"boss_contacts" order_by:"id asc"`
+	CreateContract bool         `json:"-" db:"-"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at" form:"-"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at" form:"-"`
 }
 
 // String is not required by pop and may be deleted
@@ -39,7 +53,28 @@ type Bosses []Boss
 
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 func (b *Boss) Validate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.NewErrors(), nil
+	errs := validate.Validate(
+		&validators.StringIsPresent{Field: b.Name, Name: "Name"},
+	)
+	if b.PaymentTerms.Valid && b.PaymentTerms.Int < 0 {
+		errs.Add("payment_terms", "Payment terms cannot be negative.")
+	}
+	return errs, nil
+}
+
+// BeforeSave stores blank profile fields as NULL.
+func (b *Boss) BeforeSave(tx *pop.Connection) error {
+	b.Address = blankToNull(b.Address)
+	b.TaxID = blankToNull(b.TaxID)
+	b.Notes = blankToNull(b.Notes)
+	return nil
+}
+
+func blankToNull(s nulls.String) nulls.String {
+	if strings.TrimSpace(s.String) == "" {
+		return nulls.String{}
+	}
+	return nulls.NewString(strings.TrimSpace(s.String))
 }
 
 // ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
@@ -57,3 +92,75 @@ func (b *Boss) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
 func (b *Boss) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.NewErrors(), nil
 }
+
+// InvoiceTerms is the number of days the boss has to pay an invoice.
+func (b *Boss) InvoiceTerms() int {
+	if b.PaymentTerms.Valid {
+		return b.PaymentTerms.Int
+	}
+	return DefaultPaymentTerms
+}
+
+// AddressLines splits the billing address into its non-blank lines.
+func (b *Boss) AddressLines() []string {
+	lines := []string{}
+	for _, l := range strings.Split(b.Address.String, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// BillingLines is the boss's name, address and tax ID as printed on an
+// invoice. The first contact, if loaded, is added as the recipient.
+func (b *Boss) BillingLines() []string {
+	lines := []string{b.Name}
+	if len(b.Contacts) > 0 {
+		lines = append(lines, "Attn: "+b.Contacts[0].Name)
+	}
+	lines = append(lines, b.AddressLines()...)
+	if b.TaxID.Valid {
+		lines = append(lines, "Tax ID: "+b.TaxID.String)
+	}
+	return lines
+}
+
+// BillingEmail is the first contact email address, where invoices are sent
+// by default.
+func (b *Boss) BillingEmail() string {
+	for _, c := range b.Contacts {
+		if c.Email.Valid {
+			return c.Email.String
+		}
+	}
+	return ""
+}
+
+// SetContacts replaces the boss's contacts. Nothing is changed when any of
+// them is invalid.
+func (b *Boss) SetContacts(tx *pop.Connection, contacts BossContacts) (*validate.Errors, error) {
+	verrs := validate.NewErrors()
+	for i := range contacts {
+		contacts[i].BossID = b.ID
+		errs, err := contacts[i].Validate(tx)
+		if err != nil {
+			return verrs, err
+		}
+		verrs.Append(errs)
+	}
+	if verrs.HasAny() {
+		return verrs, nil
+	}
+
+	if err := tx.RawQuery("DELETE FROM boss_contacts WHERE boss_id = ?", b.ID).Exec(); err != nil {
+		return verrs, err
+	}
+	for i := range contacts {
+		if err := tx.Create(&contacts[i]); err != nil {
+			return verrs, err
+		}
+	}
+	b.Contacts = contacts
+	return verrs, nil
+}
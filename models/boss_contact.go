@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// BossContact is a person to reach at a boss, such as whoever pays the
+// invoices.
+type BossContact struct {
+	ID        int          `json:"id" db:"id"`
+	BossID    int          `json:"-" db:"boss_id"`
+	Name      string       `json:"name" db:"name"`
+	Email     nulls.String `json:"email" db:"email"`
+	Phone     nulls.String `json:"phone" db:"phone"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (b BossContact) String() string {
+	jb, _ := json.Marshal(b)
+	return string(jb)
+}
+
+// BossContacts is not required by pop and may be deleted
+type BossContacts []BossContact
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (b *BossContact) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	errs := validate.Validate(
+		&validators.StringIsPresent{Field: b.Name, Name: "Name"},
+	)
+	if b.Email.Valid {
+		errs.Append(validate.Validate(&validators.EmailIsPresent{Field: b.Email.String, Name: "Email", Message: b.Name + " needs a valid email address."}))
+	}
+	return errs, nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrNoSuchUser is returned when sharing with an email nobody signed up with.
+var ErrNoSuchUser = errors.New("no user has that email address")
+
+// BossShare gives a user who does not own a boss access to it.
+type BossShare struct {
+	ID        int       `json:"id" db:"id"`
+	BossID    int       `json:"boss_id" db:"boss_id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	User      *User     `json:"user,omitempty" belongs_to:"users"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// BossShares is not required by pop and may be deleted
+type BossShares []BossShare
+
+// BossesVisibleTo limits a query to the bosses the user owns, has been given
+// access to, or that belong to one of the user's organizations. The
+// condition is parenthesized since pop joins where clauses with AND.
+func BossesVisibleTo(userID uuid.UUID) pop.ScopeFunc {
+	return func(q *pop.Query) *pop.Query {
+		return q.Where(`(bosses.user_id = ?
+			OR bosses.id IN (SELECT boss_id FROM boss_shares WHERE user_id = ?)
+			OR bosses.organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = ?))`,
+			userID, userID, userID)
+	}
+}
+
+// FindBoss loads a boss by id, provided the user can see it.
+func FindBoss(tx *pop.Connection, userID uuid.UUID, id interface{}) (*Boss, error) {
+	boss := &Boss{}
+	err := tx.Scope(BossesVisibleTo(userID)).Find(boss, id)
+	return boss, err
+}
+
+// OwnedBy reports whether the user owns the boss.
+func (b *Boss) OwnedBy(userID uuid.UUID) bool {
+	return b.UserID.Valid && b.UserID.UUID == userID
+}
+
+// Shares lists who the boss is shared with, by email.
+func (b *Boss) Shares(tx *pop.Connection) (BossShares, error) {
+	shares := BossShares{}
+	err := tx.Where("boss_id = ?", b.ID).Eager("User").All(&shares)
+	if err != nil {
+		return shares, err
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].User.Email < shares[j].User.Email
+	})
+	return shares, nil
+}
+
+// ShareWith gives the user with the given email access to the boss. Sharing
+// with the owner or someone who already has access does nothing.
+func (b *Boss) ShareWith(tx *pop.Connection, email string) (*User, error) {
+	user := &User{}
+	err := tx.Where("email = ?", strings.ToLower(strings.TrimSpace(email))).First(user)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return nil, ErrNoSuchUser
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if b.OwnedBy(user.ID) {
+		return user, nil
+	}
+
+	exists, err := tx.Where("boss_id = ? AND user_id = ?", b.ID, user.ID).Exists(&BossShare{})
+	if err != nil || exists {
+		return user, errors.WithStack(err)
+	}
+	return user, errors.WithStack(tx.Create(&BossShare{BossID: b.ID, UserID: user.ID}))
+}
+
+// Unshare removes the user's access. Their existing contracts are kept.
+func (b *Boss) Unshare(tx *pop.Connection, userID uuid.UUID) error {
+	return tx.RawQuery("DELETE FROM boss_shares WHERE boss_id = ? AND user_id = ?", b.ID, userID).Exec()
+}
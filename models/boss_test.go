@@ -1,5 +1,100 @@
 package models
 
+import "github.com/gobuffalo/nulls"
+
 func (ms *ModelSuite) Test_Boss() {
 	ms.Fail("This test needs to be implemented!")
 }
+
+func (ms *ModelSuite) Test_Boss_BillingLines() {
+	b := &Boss{
+		Name:    "ACME",
+		Address: nulls.NewString("1 Main St\n\n  Springfield  "),
+		TaxID:   nulls.NewString("US-123"),
+		Contacts: BossContacts{
+			{Name: "Ana"},
+			{Name: "Bo", Email: nulls.NewString("bo@acme.example")},
+		},
+	}
+	ms.Equal([]string{"ACME", "Attn: Ana", "1 Main St", "Springfield", "Tax ID: US-123"}, b.BillingLines())
+	ms.Equal("bo@acme.example", b.BillingEmail())
+	ms.Equal(DefaultPaymentTerms, b.InvoiceTerms())
+
+	b.PaymentTerms = nulls.NewInt(0)
+	ms.Equal(0, b.InvoiceTerms())
+}
+
+func (ms *ModelSuite) Test_Boss_SetContacts() {
+	boss := &Boss{Name: "Initech"}
+	ms.NoError(DB.Create(boss))
+
+	verrs, err := boss.SetContacts(DB, BossContacts{{Name: "Peter", Email: nulls.NewString("peter@initech.example")}})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	// An invalid contact leaves the existing ones alone.
+	verrs, err = boss.SetContacts(DB, BossContacts{{Name: "Bill", Email: nulls.NewString("not-an-email")}})
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+
+	verrs, err = boss.SetContacts(DB, BossContacts{{Name: "Milton"}, {Name: "Samir"}})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	saved := &Boss{}
+	ms.NoError(DB.Eager("Contacts").Find(saved, boss.ID))
+	ms.Len(saved.Contacts, 2)
+	ms.Equal("Milton", saved.Contacts[0].Name)
+}
+
+func (ms *ModelSuite) Test_Boss_Sharing() {
+	owner := &User{Email: "owner@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := owner.Create(DB)
+	ms.NoError(err)
+	other := &User{Email: "other@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err = other.Create(DB)
+	ms.NoError(err)
+
+	boss := &Boss{Name: "Globex", UserID: nulls.NewUUID(owner.ID)}
+	ms.NoError(DB.Create(boss))
+
+	_, err = FindBoss(DB, owner.ID, boss.ID)
+	ms.NoError(err)
+	_, err = FindBoss(DB, other.ID, boss.ID)
+	ms.Error(err)
+
+	_, err = boss.ShareWith(DB, "nobody@example.com")
+	ms.Equal(ErrNoSuchUser, err)
+
+	_, err = boss.ShareWith(DB, "Other@Example.com")
+	ms.NoError(err)
+	_, err = boss.ShareWith(DB, "other@example.com")
+	ms.NoError(err)
+	shares, err := boss.Shares(DB)
+	ms.NoError(err)
+	ms.Len(shares, 1)
+
+	found, err := FindBoss(DB, other.ID, boss.ID)
+	ms.NoError(err)
+	ms.False(found.OwnedBy(other.ID))
+
+	ms.NoError(boss.Unshare(DB, other.ID))
+	_, err = FindBoss(DB, other.ID, boss.ID)
+	ms.Error(err)
+}
+
+func (ms *ModelSuite) Test_FindBoss_Scoped_To_Id() {
+	owner := &User{Email: "owner@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := owner.Create(DB)
+	ms.NoError(err)
+
+	a := &Boss{Name: "Initech", UserID: nulls.NewUUID(owner.ID)}
+	ms.NoError(DB.Create(a))
+	b := &Boss{Name: "Initrode", UserID: nulls.NewUUID(owner.ID)}
+	ms.NoError(DB.Create(b))
+
+	found, err := FindBoss(DB, owner.ID, b.ID)
+	ms.NoError(err)
+	ms.Equal(b.ID, found.ID)
+	ms.Equal("Initrode", found.Name)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// CalendarRule assigns imported calendar events whose title contains
+// Pattern to a contract.
+type CalendarRule struct {
+	ID         int       `json:"id" db:"id"`
+	UserID     uuid.UUID `json:"-" db:"user_id"`
+	Pattern    string    `json:"pattern" db:"pattern"`
+	ContractID int       `json:"contract_id" db:"contract_id"`
+	Contract   *Contract `json:"contract,omitempty" belongs_to:"contract"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (c CalendarRule) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// CalendarRules is not required by pop and may be deleted
+type CalendarRules []CalendarRule
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (c *CalendarRule) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: c.Pattern, Name: "Pattern"},
+		&validators.IntIsPresent{Field: c.ContractID, Name: "ContractID"},
+	), nil
+}
+
+// Matches reports if the event title contains the pattern, ignoring case.
+func (c CalendarRule) Matches(title string) bool {
+	return strings.Contains(strings.ToLower(title), strings.ToLower(c.Pattern))
+}
+
+// ContractFor returns the contract of the first rule matching title, or 0.
+func (rules CalendarRules) ContractFor(title string) int {
+	for _, r := range rules {
+		if r.Matches(title) {
+			return r.ContractID
+		}
+	}
+	return 0
+}
+
+// LoadCalendarRules returns the user's rules in the order they were added.
+func LoadCalendarRules(tx *pop.Connection, userID uuid.UUID) (CalendarRules, error) {
+	rules := CalendarRules{}
+	err := tx.Where("user_id = ?", userID).Eager("Contract.Boss").Order("id asc").All(&rules)
+	return rules, err
+}
@@ -0,0 +1,18 @@
+package models
+
+func (ms *ModelSuite) Test_CalendarRules_ContractFor() {
+	rules := CalendarRules{
+		{Pattern: "acme", ContractID: 1},
+		{Pattern: "Standup", ContractID: 2},
+	}
+	ms.Equal(1, rules.ContractFor("ACME kickoff"))
+	ms.Equal(2, rules.ContractFor("Daily standup"))
+	ms.Equal(0, rules.ContractFor("Lunch"))
+}
+
+func (ms *ModelSuite) Test_CalendarRule_Validate() {
+	verrs, err := (&CalendarRule{}).Validate(DB)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	ms.NotEmpty(verrs.Get("pattern"))
+}
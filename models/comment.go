@@ -0,0 +1,126 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// MaxCommentLength is the longest comment that can be posted, in bytes.
+const MaxCommentLength = 4000
+
+// Comment is a note left on a task by the person who logged it or by a
+// manager of its organization, so questions about an entry stay with it.
+type Comment struct {
+	ID        int       `json:"id" db:"id"`
+	TaskID    int       `json:"task_id" db:"task_id"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	User      *User     `json:"user,omitempty" belongs_to:"user"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (c Comment) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// Comments is not required by pop and may be deleted
+type Comments []Comment
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (c *Comment) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	c.Body = strings.TrimSpace(c.Body)
+	errs := validate.Validate(
+		&validators.StringIsPresent{Field: c.Body, Name: "Body", Message: "Write a comment."},
+	)
+	if len(c.Body) > MaxCommentLength {
+		errs.Add("body", fmt.Sprintf("Comments can be up to %d characters.", MaxCommentLength))
+	}
+	return errs, nil
+}
+
+// AuthorName names the comment's author, falling back to their email. The
+// user must be loaded.
+func (c Comment) AuthorName() string {
+	if c.User == nil {
+		return ""
+	}
+	if c.User.FirstName == "" && c.User.LastName == "" {
+		return c.User.Email
+	}
+	return c.User.FullName()
+}
+
+// LoadTaskComments returns the comments on a task with their authors,
+// oldest first.
+func LoadTaskComments(tx *pop.Connection, taskID int) (Comments, error) {
+	cs := Comments{}
+	err := tx.Where("task_id = ?", taskID).Eager("User").Order("created_at asc, id asc").All(&cs)
+	return cs, err
+}
+
+// CanComment reports whether the user may read and post comments on the
+// task: the person who logged it or a manager of the contract's
+// organization. The task's contract must be loaded.
+func (t *Task) CanComment(tx *pop.Connection, userID uuid.UUID) (bool, error) {
+	if t.Contract.UserID == userID {
+		return true, nil
+	}
+	if !t.Contract.OrganizationID.Valid {
+		return false, nil
+	}
+	return tx.Where("organization_id = ? AND user_id = ? AND role IN (?, ?)",
+		t.Contract.OrganizationID.Int, userID, RoleOwner, RoleManager).Exists(&OrganizationMember{})
+}
+
+// AddComment posts a comment on the task and notifies the task's owner and
+// everyone who commented before, other than the author. The task's
+// contract and its boss must be loaded.
+func (t *Task) AddComment(tx *pop.Connection, author *User, body string) (*Comment, *validate.Errors, error) {
+	c := &Comment{TaskID: t.ID, UserID: author.ID, User: author, Body: body}
+	verrs, err := tx.ValidateAndCreate(c)
+	if err != nil || verrs.HasAny() {
+		return c, verrs, err
+	}
+
+	ids := []struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{}
+	err = tx.RawQuery("SELECT DISTINCT user_id FROM comments WHERE task_id = ? AND user_id <> ?", t.ID, author.ID).All(&ids)
+	if err != nil {
+		return c, verrs, err
+	}
+	notify := []uuid.UUID{}
+	if t.Contract.UserID != author.ID {
+		notify = append(notify, t.Contract.UserID)
+	}
+	for _, id := range ids {
+		if id.UserID != t.Contract.UserID {
+			notify = append(notify, id.UserID)
+		}
+	}
+
+	msg := fmt.Sprintf("%s commented on the %s task on %s.", c.AuthorName(), t.Contract.Label(), t.StartTime.Format("Jan 2"))
+	for _, id := range notify {
+		n := &Notification{
+			UserID:     id,
+			ContractID: nulls.NewInt(t.ContractID),
+			Message:    msg,
+			Link:       nulls.NewString(fmt.Sprintf("/tasks/%d#comments", t.ID)),
+		}
+		if _, err := Notify(tx, n); err != nil {
+			return c, verrs, err
+		}
+	}
+	return c, verrs, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_Comment_Validate() {
+	c := &Comment{Body: "  "}
+	verrs, err := c.Validate(DB)
+	ms.NoError(err)
+	ms.NotEmpty(verrs.Get("body"))
+
+	c = &Comment{Body: strings.Repeat("x", MaxCommentLength+1)}
+	verrs, err = c.Validate(DB)
+	ms.NoError(err)
+	ms.NotEmpty(verrs.Get("body"))
+}
+
+func (ms *ModelSuite) Test_Task_AddComment() {
+	now := time.Now()
+	newUser := func(email string) *User {
+		u := &User{FirstName: "A", LastName: email[:1], Email: email, Password: "secret", PasswordConfirmation: "secret"}
+		verrs, err := u.Create(DB)
+		ms.NoError(err)
+		ms.False(verrs.HasAny())
+		return u
+	}
+	owner := newUser("owner@example.com")
+	manager := newUser("manager@example.com")
+	other := newUser("other@example.com")
+
+	org, verrs, err := CreateOrganization(DB, "Agency", manager.ID)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	verrs, err = org.AddMember(DB, owner.Email, RoleMember)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: owner.ID}
+	ms.NoError(DB.Create(contract))
+	ms.NoError(boss.SetOrganization(DB, nulls.NewInt(org.ID)))
+	task := &Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID}
+	ms.NoError(DB.Create(task))
+	ms.NoError(DB.Eager("Contract.Boss").Find(task, task.ID))
+
+	for _, u := range []*User{owner, manager} {
+		ok, err := task.CanComment(DB, u.ID)
+		ms.NoError(err)
+		ms.True(ok)
+	}
+	ok, err := task.CanComment(DB, other.ID)
+	ms.NoError(err)
+	ms.False(ok)
+
+	// The manager's question notifies the owner.
+	_, verrs, err = task.AddComment(DB, manager, "Why two hours?")
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	n, err := UnreadNotifications(DB, owner.ID)
+	ms.NoError(err)
+	ms.Equal(1, n)
+
+	// The owner's answer notifies the manager, not the owner.
+	_, verrs, err = task.AddComment(DB, owner, "The migration ran long.")
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	n, err = UnreadNotifications(DB, manager.ID)
+	ms.NoError(err)
+	ms.Equal(1, n)
+	n, err = UnreadNotifications(DB, owner.ID)
+	ms.NoError(err)
+	ms.Equal(1, n)
+
+	comments, err := LoadTaskComments(DB, task.ID)
+	ms.NoError(err)
+	ms.Len(comments, 2)
+	ms.Equal("A m", comments[0].AuthorName())
+}
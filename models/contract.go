@@ -3,24 +3,44 @@ package models
 import (
 	"encoding/json"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
 	"github.com/gofrs/uuid"
 )
 
 // Contract is a User's record for a specific boss.
 type Contract struct {
-	ID        int       `json:"id" db:"id"`
-	Rate      int       `json:"rate" db:"rate"`
-	BossID    int       `json:"-" db:"boss_id"`
-	Boss      *Boss     `json:"boss" belongs_to:"boss"`
-	UserID    uuid.UUID `json:"-" db:"user_id"`
-	User      *User     `json:"user" belongs_to:"user"`
-	Tasks     []Task    `json:"tasks,omitempty" has_many:"tasks"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID              int          `json:"id" db:"id"`
+	Name            string       `json:"name" db:"name"`
+	Kind            string       `json:"kind" db:"kind"`
+	Rate            int          `json:"rate" db:"rate"`
+	RetainerFee     int          `json:"retainer_fee" db:"retainer_fee" form:"-"`
+	IncludedMinutes int          `json:"included_minutes" db:"included_minutes" form:"-"`
+	MileageRate     int          `json:"mileage_rate" db:"mileage_rate" form:"-"`
+	MileageUnit     string       `json:"mileage_unit" db:"mileage_unit" form:"-"`
+	KindChangedAt   nulls.Time   `json:"-" db:"kind_changed_at" form:"-"`
+	BossID          int          `json:"-" db:"boss_id"`
+	Boss            *Boss        `json:"boss" belongs_to:"boss"`
+	UserID          uuid.UUID    `json:"-" db:"user_id"`
+	User            *User        `json:"user" belongs_to:"user"`
+	OrganizationID  nulls.Int    `json:"-" db:"organization_id" form:"-"`
+	PortalToken     nulls.String `json:"-" db:"portal_token" form:"-"`
+	PortalExpiresAt nulls.Time   `json:"-" db:"portal_expires_at" form:"-"`
+	CapKind         nulls.String `json:"cap_kind" db:"cap_kind" form:"-"`
+	CapAmount       nulls.Int    `json:"cap_amount" db:"cap_amount" form:"-"`
+	CapPeriod       nulls.String `json:"cap_period" db:"cap_period" form:"-"`
+	StartsOn        nulls.Time   `json:"starts_on" db:"starts_on" form:"-"`
+	EndsOn          nulls.Time   `json:"ends_on" db:"ends_on" form:"-"`
+	ArchivedAt      nulls.Time   `json:"archived_at" db:"archived_at" form:"-"`
+	Tasks           []Task       `json:"tasks,omitempty" has_many:"tasks"`
+	Projects        Projects     `json:"projects,omitempty" has_many:"projects" order_by:"name asc"`
+	CreatedAt       time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at" db:"updated_at"`
 }
 
 func (c Contract) String() string {
@@ -32,7 +52,44 @@ type Contracts []Contract
 
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 func (c *Contract) Validate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.NewErrors(), nil
+	c.Name = strings.TrimSpace(c.Name)
+	var err error
+	errs := validate.Validate(
+		// A user can have several contracts with a boss as long as their
+		// names differ.
+		&validators.FuncValidator{
+			Field:   c.Name,
+			Name:    "Name",
+			Message: "%s is already used for another contract with this boss",
+			Fn: func() bool {
+				q := tx.Where("user_id = ? AND boss_id = ? AND name = ?", c.UserID, c.BossID, c.Name)
+				if c.ID != 0 {
+					q = q.Where("id != ?", c.ID)
+				}
+				var b bool
+				b, err = q.Exists(&Contract{})
+				return !b
+			},
+		},
+	)
+	if c.StartsOn.Valid && c.EndsOn.Valid && c.EndsOn.Time.Before(c.StartsOn.Time) {
+		errs.Add("ends_on", "The end date must be after the start date.")
+	}
+	c.validateKind(errs)
+	c.validateMileage(errs)
+	return errs, err
+}
+
+// Label names the contract by its boss and, when set, its own name, e.g.
+// "ACME - Website". The boss must be loaded.
+func (c *Contract) Label() string {
+	if c.Boss == nil {
+		return c.Name
+	}
+	if c.Name == "" {
+		return c.Boss.Name
+	}
+	return c.Boss.Name + " - " + c.Name
 }
 
 // ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
@@ -46,6 +103,29 @@ func (c *Contract) ValidateCreate(tx *pop.Connection) (*validate.Errors, error)
 	return validate.NewErrors(), nil
 }
 
+// BeforeCreate puts the contract in its boss's organization, if any and
+// the contract's user is a member of it.
+func (c *Contract) BeforeCreate(tx *pop.Connection) error {
+	if c.OrganizationID.Valid {
+		return nil
+	}
+	boss := &Boss{}
+	if err := tx.Find(boss, c.BossID); err != nil {
+		return err
+	}
+	if !boss.OrganizationID.Valid {
+		return nil
+	}
+	member, err := tx.Where("organization_id = ? AND user_id = ?", boss.OrganizationID.Int, c.UserID).Exists(&OrganizationMember{})
+	if err != nil {
+		return err
+	}
+	if member {
+		c.OrganizationID = boss.OrganizationID
+	}
+	return nil
+}
+
 // ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
 func (c *Contract) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.NewErrors(), nil
@@ -64,3 +144,53 @@ func (c *Contract) LoadContract(tx *pop.Connection, cid string) error {
 
 	return nil
 }
+
+// IsArchived reports whether the contract has been archived.
+func (c *Contract) IsArchived() bool {
+	return c.ArchivedAt.Valid
+}
+
+// Covers reports whether t falls on a day between the contract's start and
+// end dates. Open ends always match.
+func (c *Contract) Covers(t time.Time) bool {
+	day := t.Format("2006-01-02")
+	if c.StartsOn.Valid && day < c.StartsOn.Time.Format("2006-01-02") {
+		return false
+	}
+	if c.EndsOn.Valid && day > c.EndsOn.Time.Format("2006-01-02") {
+		return false
+	}
+	return true
+}
+
+// WindowLabel describes the contract's dates, e.g. "Sep 1, 2021 to Dec 31,
+// 2021".
+func (c *Contract) WindowLabel() string {
+	const f = "Jan 2, 2006"
+	switch {
+	case c.StartsOn.Valid && c.EndsOn.Valid:
+		return c.StartsOn.Time.Format(f) + " to " + c.EndsOn.Time.Format(f)
+	case c.StartsOn.Valid:
+		return "From " + c.StartsOn.Time.Format(f)
+	case c.EndsOn.Valid:
+		return "Until " + c.EndsOn.Time.Format(f)
+	}
+	return ""
+}
+
+// DateInput prints an optional date for a date input.
+func DateInput(t nulls.Time) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format("2006-01-02")
+}
+
+// SetArchived archives or restores the contract.
+func (c *Contract) SetArchived(tx *pop.Connection, archived bool, now time.Time) error {
+	c.ArchivedAt = nulls.Time{}
+	if archived {
+		c.ArchivedAt = nulls.NewTime(now)
+	}
+	return tx.UpdateColumns(c, "archived_at")
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// Cap kinds. Hour caps are stored in minutes and money caps in cents.
+const (
+	CapHours = "hours"
+	CapMoney = "money"
+)
+
+// Cap periods. A lifetime cap covers every task on the contract.
+const (
+	CapWeek     = "week"
+	CapMonth    = "month"
+	CapLifetime = "lifetime"
+)
+
+// CapKinds lists the kinds of cap a contract can carry.
+var CapKinds = []string{CapHours, CapMoney}
+
+// CapPeriods lists the periods a cap can apply to.
+var CapPeriods = []string{CapWeek, CapMonth, CapLifetime}
+
+// CapThresholds are the percentages of a cap that trigger a notification.
+var CapThresholds = []int{80, 100}
+
+// HasCap reports whether the contract has a budget or hour cap.
+func (c *Contract) HasCap() bool {
+	return c.CapKind.Valid && c.CapAmount.Valid && c.CapAmount.Int > 0
+}
+
+// SetCap saves the contract's cap. An empty kind removes it.
+func (c *Contract) SetCap(tx *pop.Connection, kind string, amount int, period string) (*validate.Errors, error) {
+	if kind == "" {
+		c.CapKind, c.CapAmount, c.CapPeriod = nulls.String{}, nulls.Int{}, nulls.String{}
+		return validate.NewErrors(), tx.UpdateColumns(c, "cap_kind", "cap_amount", "cap_period", "updated_at")
+	}
+
+	verrs := validate.Validate(
+		&validators.StringInclusion{Field: kind, Name: "CapKind", List: CapKinds, Message: "Pick hours or money."},
+		&validators.StringInclusion{Field: period, Name: "CapPeriod", List: CapPeriods, Message: "Pick a period for the cap."},
+		&validators.IntIsGreaterThan{Field: amount, Name: "CapAmount", Compared: 0, Message: "The cap must be greater than zero."},
+	)
+	if verrs.HasAny() {
+		return verrs, nil
+	}
+	c.CapKind = nulls.NewString(kind)
+	c.CapAmount = nulls.NewInt(amount)
+	c.CapPeriod = nulls.NewString(period)
+	return verrs, tx.UpdateColumns(c, "cap_kind", "cap_amount", "cap_period", "updated_at")
+}
+
+// CapLabel describes the cap, e.g. "40h per month".
+func (c *Contract) CapLabel() string {
+	if !c.HasCap() {
+		return ""
+	}
+	label := formatCap(c.CapKind.String, c.CapAmount.Int)
+	if c.CapPeriod.String == CapLifetime {
+		return label + " in total"
+	}
+	return label + " per " + c.CapPeriod.String
+}
+
+// CapInput is the cap as typed into the contract form: decimal hours or
+// dollars.
+func (c *Contract) CapInput() string {
+	if !c.HasCap() {
+		return ""
+	}
+	if c.CapKind.String == CapMoney {
+		return strconv.FormatFloat(float64(c.CapAmount.Int)/100, 'f', 2, 64)
+	}
+	return strconv.FormatFloat(float64(c.CapAmount.Int)/60, 'f', -1, 64)
+}
+
+// capPeriod returns the start and end of the cap period containing at. A
+// lifetime cap has zero bounds.
+func (c *Contract) capPeriod(at time.Time) (time.Time, time.Time) {
+	switch c.CapPeriod.String {
+	case CapWeek:
+		start := StartOfWeek(at)
+		return start, start.AddDate(0, 0, 7)
+	case CapMonth:
+		start := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+	return time.Time{}, time.Time{}
+}
+
+// CapUsage is how much of a contract's cap is used in one period.
+type CapUsage struct {
+	Kind  string
+	Limit int
+	Used  int
+	Start time.Time
+}
+
+// LoadCapUsage totals the tasks in the cap period containing at. It returns
+// nil when the contract has no cap.
+func (c *Contract) LoadCapUsage(tx *pop.Connection, at time.Time) (*CapUsage, error) {
+	if !c.HasCap() {
+		return nil, nil
+	}
+	start, end := c.capPeriod(at)
+	q := tx.Where("contract_id = ?", c.ID)
+	if !start.IsZero() {
+		q = q.Where("start_time >= ? AND start_time < ?", start, end)
+	}
+	tasks := Tasks{}
+	if err := q.All(&tasks); err != nil {
+		return nil, err
+	}
+
+	u := &CapUsage{Kind: c.CapKind.String, Limit: c.CapAmount.Int, Start: start}
+	for _, t := range tasks {
+		u.Used += u.cost(t)
+	}
+	return u, nil
+}
+
+// cost is how much of the cap a task uses.
+func (u *CapUsage) cost(t Task) int {
+	if u.Kind == CapMoney {
+		return Earnings(t.Rate, t.Duration)
+	}
+	return t.Duration
+}
+
+// Percent is the share of the cap used, which may be over 100.
+func (u *CapUsage) Percent() int {
+	if u.Limit <= 0 {
+		return 0
+	}
+	return u.Used * 100 / u.Limit
+}
+
+// BarPercent is Percent limited to a full progress bar.
+func (u *CapUsage) BarPercent() int {
+	if p := u.Percent(); p < 100 {
+		return p
+	}
+	return 100
+}
+
+// Over reports whether the cap has been exceeded.
+func (u *CapUsage) Over() bool {
+	return u.Used > u.Limit
+}
+
+// UsedLabel prints the amount used in the cap's unit.
+func (u *CapUsage) UsedLabel() string {
+	return formatCap(u.Kind, u.Used)
+}
+
+// LimitLabel prints the cap in its unit.
+func (u *CapUsage) LimitLabel() string {
+	return formatCap(u.Kind, u.Limit)
+}
+
+// formatCap prints minutes as decimal hours or cents as money.
+func formatCap(kind string, v int) string {
+	if kind == CapMoney {
+		return FormatMoney(v)
+	}
+	return strconv.FormatFloat(math.Round(float64(v)/60*100)/100, 'f', -1, 64) + "h"
+}
+
+// CheckCapAlerts notifies the contract's user when the cap period
+// containing at has crossed a threshold. Each threshold is sent once per
+// period and cap.
+func (c *Contract) CheckCapAlerts(tx *pop.Connection, at time.Time) error {
+	u, err := c.LoadCapUsage(tx, at)
+	if err != nil || u == nil {
+		return err
+	}
+	if c.Boss == nil {
+		c.Boss = &Boss{}
+		if err := tx.Find(c.Boss, c.BossID); err != nil {
+			return err
+		}
+	}
+
+	period := CapLifetime
+	if !u.Start.IsZero() {
+		period = u.Start.Format("2006-01-02")
+	}
+	for _, t := range CapThresholds {
+		if u.Percent() < t {
+			continue
+		}
+		n := &Notification{
+			UserID:     c.UserID,
+			ContractID: nulls.NewInt(c.ID),
+			Message:    fmt.Sprintf("%s has used %d%% of its %s cap (%s of %s).", c.Label(), t, c.CapLabel(), u.UsedLabel(), u.LimitLabel()),
+			Link:       nulls.NewString(fmt.Sprintf("/users/%s/contracts/%d", c.UserID, c.ID)),
+			DedupeKey:  nulls.NewString(fmt.Sprintf("cap:%d:%s:%s:%d:%d", c.ID, c.CapKind.String, period, c.CapAmount.Int, t)),
+		}
+		if _, err := Notify(tx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckCapAlertsFor runs CheckCapAlerts once for each contract and cap
+// period the tasks were logged in, as after an import.
+func CheckCapAlertsFor(tx *pop.Connection, tasks Tasks) error {
+	contracts := map[int]*Contract{}
+	checked := map[string]bool{}
+	for _, t := range tasks {
+		c, ok := contracts[t.ContractID]
+		if !ok {
+			c = &Contract{}
+			if err := tx.Find(c, t.ContractID); err != nil {
+				return err
+			}
+			contracts[t.ContractID] = c
+		}
+		if !c.HasCap() {
+			continue
+		}
+		start, _ := c.capPeriod(t.StartTime)
+		key := fmt.Sprintf("%d:%s", c.ID, start.Format("2006-01-02"))
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+		if err := c.CheckCapAlerts(tx, t.StartTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
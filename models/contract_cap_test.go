@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+func (ms *ModelSuite) Test_Contract_Cap() {
+	now := time.Now()
+
+	user := &User{Email: "cap@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+
+	usage, err := contract.LoadCapUsage(DB, now)
+	ms.NoError(err)
+	ms.Nil(usage)
+
+	verrs, err = contract.SetCap(DB, CapHours, 0, CapMonth)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	verrs, err = contract.SetCap(DB, CapHours, 600, CapMonth)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal("10h per month", contract.CapLabel())
+
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 480, StartTime: now, EndTime: now, ContractID: contract.ID}))
+	// Last month's time does not count towards this month's cap.
+	lastMonth := time.Date(now.Year(), now.Month(), 1, 12, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 600, StartTime: lastMonth, EndTime: lastMonth, ContractID: contract.ID}))
+
+	usage, err = contract.LoadCapUsage(DB, now)
+	ms.NoError(err)
+	ms.Equal(480, usage.Used)
+	ms.Equal(80, usage.Percent())
+	ms.False(usage.Over())
+
+	ms.NoError(contract.CheckCapAlerts(DB, now))
+	ms.NoError(contract.CheckCapAlerts(DB, now))
+	unread, err := UnreadNotifications(DB, user.ID)
+	ms.NoError(err)
+	ms.Equal(1, unread)
+
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 180, StartTime: now, EndTime: now, ContractID: contract.ID}))
+	ms.NoError(contract.CheckCapAlerts(DB, now))
+	list, err := LoadNotifications(DB, user.ID)
+	ms.NoError(err)
+	ms.Len(list, 2)
+
+	ms.NoError(MarkNotificationsRead(DB, user.ID, now))
+	unread, err = UnreadNotifications(DB, user.ID)
+	ms.NoError(err)
+	ms.Equal(0, unread)
+
+	verrs, err = contract.SetCap(DB, "", 0, "")
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.False(contract.HasCap())
+}
+
+func (ms *ModelSuite) Test_CapUsage() {
+	u := &CapUsage{Kind: CapMoney, Limit: 100000, Used: 125000}
+	ms.Equal(125, u.Percent())
+	ms.Equal(100, u.BarPercent())
+	ms.True(u.Over())
+	ms.Equal("$1,250.00", u.UsedLabel())
+
+	u = &CapUsage{Kind: CapHours, Limit: 2400, Used: 90}
+	ms.Equal(3, u.Percent())
+	ms.Equal("1.5h", u.UsedLabel())
+	ms.Equal("40h", u.LimitLabel())
+}
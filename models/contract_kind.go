@@ -0,0 +1,436 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+)
+
+// Contract kinds. Hourly contracts bill each task at its rate. Fixed-price
+// contracts bill their milestones once completed, and retainers bill a
+// monthly fee plus the contract rate for time past the included hours.
+const (
+	ContractHourly   = "hourly"
+	ContractFixed    = "fixed"
+	ContractRetainer = "retainer"
+)
+
+// ContractKinds lists the kinds of contract in the order they are offered.
+var ContractKinds = []string{ContractHourly, ContractFixed, ContractRetainer}
+
+// IsHourly reports whether the contract bills its tasks by the hour.
+func (c *Contract) IsHourly() bool {
+	return c.Kind == "" || c.Kind == ContractHourly
+}
+
+// IsFixed reports whether the contract is billed by milestone.
+func (c *Contract) IsFixed() bool {
+	return c.Kind == ContractFixed
+}
+
+// IsRetainer reports whether the contract is billed by the month.
+func (c *Contract) IsRetainer() bool {
+	return c.Kind == ContractRetainer
+}
+
+// KindLabel names the contract's kind for display.
+func (c *Contract) KindLabel() string {
+	switch c.Kind {
+	case ContractFixed:
+		return "Fixed price"
+	case ContractRetainer:
+		return "Retainer"
+	}
+	return "Hourly"
+}
+
+// TermsLabel describes how the contract is billed, e.g. "$1,500.00 per
+// month, 20h included, then $90/h".
+func (c *Contract) TermsLabel() string {
+	switch c.Kind {
+	case ContractFixed:
+		return "Fixed price, billed by milestone"
+	case ContractRetainer:
+		return fmt.Sprintf("%s per month, %s included, then $%d/h", FormatMoney(c.RetainerFee), formatCap(CapHours, c.IncludedMinutes), c.Rate)
+	}
+	return fmt.Sprintf("$%d/h", c.Rate)
+}
+
+// TaskRate is the rate new tasks on the contract are billed at. Time on
+// fixed-price and retainer contracts is covered by the milestones or the
+// monthly fee, so it carries no rate.
+func (c *Contract) TaskRate() int {
+	if c.IsHourly() {
+		return c.Rate
+	}
+	return 0
+}
+
+// RetainerFeeInput is the monthly fee as typed into the contract form.
+func (c *Contract) RetainerFeeInput() string {
+	if c.RetainerFee == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(c.RetainerFee)/100, 'f', 2, 64)
+}
+
+// IncludedHoursInput is the retainer's included time in decimal hours.
+func (c *Contract) IncludedHoursInput() string {
+	if c.IncludedMinutes == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(c.IncludedMinutes)/60, 'f', -1, 64)
+}
+
+// validateKind checks the billing terms for the contract's kind.
+func (c *Contract) validateKind(errs *validate.Errors) {
+	if c.Kind == "" {
+		c.Kind = ContractHourly
+	}
+	switch c.Kind {
+	case ContractHourly, ContractFixed:
+		c.RetainerFee, c.IncludedMinutes = 0, 0
+	case ContractRetainer:
+		if c.RetainerFee <= 0 {
+			errs.Add("retainer_fee", "Enter the monthly retainer fee.")
+		}
+		if c.IncludedMinutes < 0 {
+			errs.Add("included_minutes", "Included hours cannot be negative.")
+		}
+	default:
+		errs.Add("kind", "Pick hourly, fixed or retainer.")
+	}
+}
+
+// SetKind changes how the contract is billed. A change is dated at so that
+// time logged under the old terms is not billed again under the new ones.
+func (c *Contract) SetKind(kind string, at time.Time) {
+	was := c.Kind
+	if was == "" {
+		was = ContractHourly
+	}
+	if kind != was {
+		c.KindChangedAt = nulls.NewTime(at)
+	}
+	c.Kind = kind
+}
+
+// retainerSince is when the contract was changed to a retainer, or zero
+// when it was created as one. Time logged before then was billed by the
+// hour.
+func (c *Contract) retainerSince() time.Time {
+	if c.IsRetainer() && c.KindChangedAt.Valid {
+		return c.KindChangedAt.Time
+	}
+	return time.Time{}
+}
+
+// monthStart returns midnight on the first of the month containing t.
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// RetainerMonth is one month of a retainer: the fee and the time logged
+// against the included hours.
+type RetainerMonth struct {
+	Start    time.Time
+	Fee      int
+	Included int
+	Rate     int
+	Minutes  int
+}
+
+// Label names the month, e.g. "October 2021".
+func (m RetainerMonth) Label() string {
+	return m.Start.Format("January 2006")
+}
+
+// OverageMinutes is the time logged past the included hours.
+func (m RetainerMonth) OverageMinutes() int {
+	if m.Minutes <= m.Included {
+		return 0
+	}
+	return m.Minutes - m.Included
+}
+
+// Overage is the charge for the time past the included hours in cents.
+func (m RetainerMonth) Overage() int {
+	return Earnings(m.Rate, m.OverageMinutes())
+}
+
+// Amount is the month's fee and overage in cents.
+func (m RetainerMonth) Amount() int {
+	return m.Fee + m.Overage()
+}
+
+// Percent is the share of the included hours used, for the usage bar.
+func (m RetainerMonth) Percent() int {
+	if m.Included <= 0 {
+		return 0
+	}
+	return m.Minutes * 100 / m.Included
+}
+
+// BarPercent is Percent limited to 100 for the width of the usage bar.
+func (m RetainerMonth) BarPercent() int {
+	if p := m.Percent(); p < 100 {
+		return p
+	}
+	return 100
+}
+
+// lines bills the month as invoice lines.
+func (m RetainerMonth) lines() []InvoiceLine {
+	lines := []InvoiceLine{m.feeLine()}
+	if l, ok := m.overageLine(0); ok {
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// feeLine bills the month's fee.
+func (m RetainerMonth) feeLine() InvoiceLine {
+	return InvoiceLine{Description: "Retainer, " + m.Label(), Amount: m.Fee, Period: nulls.NewTime(m.Start)}
+}
+
+// overageLine bills the overage not covered by the billed minutes of the
+// month that are already on an invoice. It reports false when there is
+// nothing more to bill.
+func (m RetainerMonth) overageLine(billed int) (InvoiceLine, bool) {
+	done := RetainerMonth{Included: m.Included, Minutes: billed}.OverageMinutes()
+	extra := m.OverageMinutes() - done
+	if extra <= 0 {
+		return InvoiceLine{}, false
+	}
+	desc := fmt.Sprintf("Overage, %s: %s past %s at $%d/h", m.Label(), formatCap(CapHours, extra), formatCap(CapHours, m.Included), m.Rate)
+	if done > 0 {
+		desc = fmt.Sprintf("Overage, %s: %s more past %s at $%d/h", m.Label(), formatCap(CapHours, extra), formatCap(CapHours, m.Included), m.Rate)
+	}
+	return InvoiceLine{Description: desc, Amount: Earnings(m.Rate, extra), Period: nulls.NewTime(m.Start)}, true
+}
+
+// LoadRetainerMonths returns the retainer's months starting in [from, to),
+// limited to the contract's dates. Months before the contract was created
+// are skipped when it has no start date, and so are months before it
+// became a retainer. Only time logged since then counts.
+func (c *Contract) LoadRetainerMonths(tx *pop.Connection, from, to time.Time) ([]RetainerMonth, error) {
+	months := []RetainerMonth{}
+	if !c.IsRetainer() {
+		return months, nil
+	}
+	first := monthStart(c.CreatedAt.In(time.Local))
+	if c.StartsOn.Valid {
+		first = monthStart(c.StartsOn.Time.In(time.Local))
+	}
+	since := c.retainerSince()
+	if m := monthStart(since.In(time.Local)); m.After(first) {
+		first = m
+	}
+	for m := first; m.Before(to); m = m.AddDate(0, 1, 0) {
+		if c.EndsOn.Valid && m.After(c.EndsOn.Time) {
+			break
+		}
+		if m.Before(from) {
+			continue
+		}
+		months = append(months, RetainerMonth{Start: m, Fee: c.RetainerFee, Included: c.IncludedMinutes, Rate: c.Rate})
+	}
+	if len(months) == 0 {
+		return months, nil
+	}
+
+	tasks := Tasks{}
+	start := months[0].Start
+	if since.After(start) {
+		start = since
+	}
+	end := months[len(months)-1].Start.AddDate(0, 1, 0)
+	err := tx.Where("contract_id = ? AND start_time >= ? AND start_time < ?", c.ID, start, end).All(&tasks)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		start := monthStart(t.StartTime.In(time.Local))
+		for i := range months {
+			if months[i].Start.Equal(start) {
+				months[i].Minutes += t.Duration
+			}
+		}
+	}
+	return months, nil
+}
+
+// LoadRetainerMonth returns the retainer month containing at.
+func (c *Contract) LoadRetainerMonth(tx *pop.Connection, at time.Time) (*RetainerMonth, error) {
+	start := monthStart(at)
+	months, err := c.LoadRetainerMonths(tx, start, start.AddDate(0, 1, 0))
+	if err != nil || len(months) == 0 {
+		return nil, err
+	}
+	return &months[0], nil
+}
+
+// billedPeriods returns the retainer months already on an invoice that is
+// not void, keyed by "2006-01".
+func (c *Contract) billedPeriods(tx *pop.Connection) (map[string]bool, error) {
+	lines := InvoiceLines{}
+	err := tx.RawQuery(`SELECT invoice_lines.* FROM invoice_lines
+		JOIN invoices ON invoices.id = invoice_lines.invoice_id
+		WHERE invoices.contract_id = ? AND invoices.status != ? AND invoice_lines.period IS NOT NULL`, c.ID, InvoiceVoid).All(&lines)
+	if err != nil {
+		return nil, err
+	}
+	billed := map[string]bool{}
+	for _, l := range lines {
+		billed[l.Period.Time.Format("2006-01")] = true
+	}
+	return billed, nil
+}
+
+// retainerUsage is a retainer month's time that can be billed: the minutes
+// already on an invoice and the approved minutes that are not yet.
+type retainerUsage struct {
+	Invoiced int
+	Approved int
+}
+
+// loadRetainerUsage sums the billable time of the months starting before
+// until, keyed by "2006-01". Time that is not approved, or was logged
+// before the contract became a retainer, is left out.
+func (c *Contract) loadRetainerUsage(tx *pop.Connection, until time.Time) (map[string]retainerUsage, error) {
+	q := tx.Where("tasks.contract_id = ? AND tasks.start_time < ? AND (tasks.invoice_id IS NOT NULL OR "+approvedTasks+")", c.ID, until)
+	if since := c.retainerSince(); !since.IsZero() {
+		q = q.Where("tasks.start_time >= ?", since)
+	}
+	tasks := Tasks{}
+	err := q.All(&tasks)
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]retainerUsage{}
+	for _, t := range tasks {
+		key := t.StartTime.In(time.Local).Format("2006-01")
+		u := usage[key]
+		if t.InvoiceID.Valid {
+			u.Invoiced += t.Duration
+		} else {
+			u.Approved += t.Duration
+		}
+		usage[key] = u
+	}
+	return usage, nil
+}
+
+// pendingCharges lists what the contract owes apart from its tasks:
+// completed milestones that are not invoiced yet, and for a retainer the
+// fees of the unbilled months starting before until along with the overage
+// on their approved time. Time approved after its month was billed is
+// billed as further overage. It also returns the milestones so they can be
+// marked invoiced.
+func (c *Contract) pendingCharges(tx *pop.Connection, until time.Time) ([]InvoiceLine, Milestones, error) {
+	lines := []InvoiceLine{}
+	switch {
+	case c.IsFixed():
+		milestones := Milestones{}
+		err := tx.Where("contract_id = ? AND completed_on IS NOT NULL AND invoice_id IS NULL", c.ID).Order("completed_on asc, id asc").All(&milestones)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, m := range milestones {
+			lines = append(lines, InvoiceLine{Description: "Milestone: " + m.Name, Amount: m.Amount})
+		}
+		return lines, milestones, nil
+	case c.IsRetainer():
+		billed, err := c.billedPeriods(tx)
+		if err != nil {
+			return nil, nil, err
+		}
+		months, err := c.LoadRetainerMonths(tx, time.Time{}, until)
+		if err != nil {
+			return nil, nil, err
+		}
+		usage, err := c.loadRetainerUsage(tx, until)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, m := range months {
+			key := m.Start.Format("2006-01")
+			if !billed[key] {
+				lines = append(lines, m.feeLine())
+			}
+			u := usage[key]
+			m.Minutes = u.Invoiced + u.Approved
+			if l, ok := m.overageLine(u.Invoiced); ok {
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines, Milestones{}, nil
+}
+
+// earnedBetween is what the contract earned apart from its tasks in
+// [from, to): milestones completed in the range, or the retainer months
+// starting in it.
+func (c *Contract) earnedBetween(tx *pop.Connection, from, to time.Time) (int, error) {
+	total := 0
+	switch {
+	case c.IsFixed():
+		milestones := Milestones{}
+		err := tx.Where("contract_id = ? AND completed_on >= ? AND completed_on < ?", c.ID, from, to).All(&milestones)
+		if err != nil {
+			return 0, err
+		}
+		total = milestones.Total()
+	case c.IsRetainer():
+		months, err := c.LoadRetainerMonths(tx, from, to)
+		if err != nil {
+			return 0, err
+		}
+		for _, m := range months {
+			total += m.Amount()
+		}
+	}
+	return total, nil
+}
+
+// chargedContracts returns the user's fixed-price and retainer contracts
+// with their bosses.
+func chargedContracts(tx *pop.Connection, userID uuid.UUID) (Contracts, error) {
+	cs := Contracts{}
+	err := tx.Where("user_id = ? AND kind IN (?, ?)", userID, ContractFixed, ContractRetainer).Eager("Boss").All(&cs)
+	return cs, err
+}
+
+// addCharges adds each contract's charges, as worked out by charge, to the
+// per-boss totals.
+func addCharges(rows []BossSummary, cs Contracts, charge func(c *Contract) (int, error)) ([]BossSummary, error) {
+	for i := range cs {
+		c := &cs[i]
+		amount, err := charge(c)
+		if err != nil {
+			return nil, err
+		}
+		if amount == 0 {
+			continue
+		}
+		n := -1
+		for j := range rows {
+			if rows[j].BossID == c.BossID {
+				n = j
+			}
+		}
+		if n < 0 {
+			rows = append(rows, BossSummary{BossID: c.BossID, BossName: c.Boss.Name})
+			n = len(rows) - 1
+		}
+		rows[n].Charges += amount
+	}
+	sort.SliceStable(rows, func(a, b int) bool { return rows[a].BossName < rows[b].BossName })
+	return rows, nil
+}
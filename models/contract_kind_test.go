@@ -0,0 +1,207 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_RetainerMonth() {
+	m := RetainerMonth{Start: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), Fee: 150000, Included: 600, Rate: 90, Minutes: 540}
+	ms.Equal(0, m.OverageMinutes())
+	ms.Equal(150000, m.Amount())
+	ms.Equal(90, m.Percent())
+	ms.Len(m.lines(), 1)
+
+	m.Minutes = 690
+	ms.Equal(90, m.OverageMinutes())
+	ms.Equal(13500, m.Overage())
+	ms.Equal(163500, m.Amount())
+	ms.Equal(100, m.BarPercent())
+	lines := m.lines()
+	ms.Len(lines, 2)
+	ms.Equal("Retainer, October 2021", lines[0].Description)
+	ms.True(lines[1].Period.Valid)
+}
+
+func (ms *ModelSuite) Test_Contract_Kind_Validate() {
+	user := &User{Email: "kinds@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+
+	c := &Contract{BossID: boss.ID, UserID: user.ID, Kind: ContractRetainer, Rate: 90}
+	verrs, err = DB.ValidateAndCreate(c)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+
+	c.RetainerFee = 150000
+	verrs, err = DB.ValidateAndCreate(c)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal(0, c.TaskRate())
+
+	c.Kind = "daily"
+	verrs, err = DB.ValidateAndUpdate(c)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+}
+
+func (ms *ModelSuite) Test_CreateInvoice_Fixed() {
+	now := time.Now()
+
+	user := &User{Email: "fixed@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Kind: ContractFixed, Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+
+	// Time on a fixed-price contract is not billed by the hour.
+	task := &Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID}
+	verrs, err = DB.ValidateAndCreate(task)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal(0, task.Rate)
+
+	design := &Milestone{ContractID: contract.ID, Name: "Design", Amount: 50000}
+	ms.NoError(DB.Create(design))
+	build := &Milestone{ContractID: contract.ID, Name: "Build", Amount: 100000, DueOn: nulls.NewTime(now)}
+	ms.NoError(DB.Create(build))
+
+	_, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.Equal(ErrNothingToInvoice, err)
+
+	ms.NoError(design.SetComplete(DB, true, now))
+	d, err := LoadDashboard(DB, user.ID, now)
+	ms.NoError(err)
+	ms.Equal(50000, d.Unbilled.Amount())
+	ms.Equal(50000, d.Periods[1].Amount())
+
+	inv, verrs, err := CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	loaded, err := LoadInvoice(DB, user.ID, strconv.Itoa(inv.ID))
+	ms.NoError(err)
+	ms.Len(loaded.Lines, 1)
+	ms.Equal(50000, loaded.Subtotal())
+
+	ms.NoError(DB.Reload(design))
+	ms.True(design.IsInvoiced())
+	ms.Equal(ErrMilestoneInvoiced, design.SetComplete(DB, false, now))
+
+	// Voiding puts the milestone back up for billing.
+	ms.NoError(loaded.Void(DB))
+	ms.NoError(DB.Reload(design))
+	ms.False(design.IsInvoiced())
+}
+
+func (ms *ModelSuite) Test_CreateInvoice_Retainer() {
+	now := time.Now()
+	thisMonth := monthStart(now)
+	lastMonth := thisMonth.AddDate(0, -1, 0)
+
+	user := &User{Email: "retainer@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Kind: ContractRetainer, Rate: 90, RetainerFee: 150000, IncludedMinutes: 60, BossID: boss.ID, UserID: user.ID, StartsOn: nulls.NewTime(lastMonth)}
+	ms.NoError(DB.Create(contract))
+
+	day := lastMonth.AddDate(0, 0, 3)
+	ms.NoError(DB.Create(&Task{Duration: 100, StartTime: day, EndTime: day, ContractID: contract.ID}))
+
+	months, err := contract.LoadRetainerMonths(DB, lastMonth, thisMonth.AddDate(0, 1, 0))
+	ms.NoError(err)
+	ms.Len(months, 2)
+	ms.Equal(40, months[0].OverageMinutes())
+
+	// Only last month has ended, so only it is billed, and the overage
+	// waits for the time to be approved.
+	inv, verrs, err := CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Len(inv.Lines, 1)
+	ms.Equal(150000, inv.Subtotal())
+
+	_, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.Equal(ErrNothingToInvoice, err)
+
+	ms.approveWeek(contract, day)
+	inv, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.Len(inv.Lines, 1)
+	ms.Equal(6000, inv.Subtotal())
+
+	// Time approved after the month was billed is billed as more overage.
+	ms.NoError(DB.Create(&Task{Duration: 30, StartTime: day, EndTime: day, ContractID: contract.ID}))
+	ms.approveWeek(contract, day)
+	inv, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.Len(inv.Lines, 1)
+	ms.Contains(inv.Lines[0].Description, "more past")
+	ms.Equal(4500, inv.Subtotal())
+
+	_, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.Equal(ErrNothingToInvoice, err)
+}
+
+func (ms *ModelSuite) Test_CreateInvoice_Hourly_To_Retainer() {
+	now := time.Now()
+	thisMonth := monthStart(now)
+	lastMonth := thisMonth.AddDate(0, -1, 0)
+
+	user := &User{Email: "switch@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID, CreatedAt: thisMonth.AddDate(0, -3, 0)}
+	ms.NoError(DB.Create(contract))
+
+	// Two hours logged by the hour, one of them in the month of the change.
+	early := thisMonth.AddDate(0, -2, 3)
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 60, StartTime: early, EndTime: early, ContractID: contract.ID}))
+	ms.approveWeek(contract, early)
+	before := lastMonth.AddDate(0, 0, 1)
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 60, StartTime: before, EndTime: before, ContractID: contract.ID}))
+	ms.approveWeek(contract, before)
+
+	contract.SetKind(ContractRetainer, lastMonth.AddDate(0, 0, 9))
+	contract.Rate, contract.RetainerFee, contract.IncludedMinutes = 90, 150000, 60
+	verrs, err = DB.ValidateAndUpdate(contract)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	after := lastMonth.AddDate(0, 0, 16)
+	ms.NoError(DB.Create(&Task{Duration: 100, StartTime: after, EndTime: after, ContractID: contract.ID}))
+	ms.approveWeek(contract, after)
+
+	// Months start with the change, and only time since counts as overage.
+	months, err := contract.LoadRetainerMonths(DB, time.Time{}, thisMonth)
+	ms.NoError(err)
+	ms.Len(months, 1)
+	ms.Equal(100, months[0].Minutes)
+
+	// Last month's fee and 40 minutes of overage, plus the hourly time at
+	// its own rate.
+	inv, verrs, err := CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Len(inv.Lines, 2)
+	loaded, err := LoadInvoice(DB, user.ID, strconv.Itoa(inv.ID))
+	ms.NoError(err)
+	ms.Len(loaded.Tasks, 3)
+	ms.Equal(150000+6000+12000, loaded.Subtotal())
+
+	_, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.Equal(ErrNothingToInvoice, err)
+}
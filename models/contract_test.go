@@ -1,5 +1,96 @@
 package models
 
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
 func (ms *ModelSuite) Test_Contract() {
 	ms.Fail("This test needs to be implemented!")
 }
+
+func (ms *ModelSuite) Test_Contract_Covers() {
+	day := func(d int) time.Time { return time.Date(2021, 10, d, 0, 0, 0, 0, time.UTC) }
+	c := &Contract{}
+	ms.True(c.Covers(day(1)))
+	ms.Equal("", c.WindowLabel())
+
+	c.StartsOn = nulls.NewTime(day(4))
+	c.EndsOn = nulls.NewTime(day(8))
+	ms.False(c.Covers(day(3)))
+	ms.True(c.Covers(day(4).Add(9 * time.Hour)))
+	ms.True(c.Covers(day(8).Add(23 * time.Hour)))
+	ms.False(c.Covers(day(9)))
+	ms.Equal("Oct 4, 2021 to Oct 8, 2021", c.WindowLabel())
+}
+
+func (ms *ModelSuite) Test_Contract_Window_And_Archive() {
+	now := time.Now()
+
+	user := &User{Email: "window@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID, StartsOn: nulls.NewTime(now.AddDate(0, 0, -7)), EndsOn: nulls.NewTime(now.AddDate(0, 0, -14))}
+	verrs, err = DB.ValidateAndCreate(contract)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+
+	contract.EndsOn = nulls.NewTime(now.AddDate(0, 0, 7))
+	verrs, err = DB.ValidateAndCreate(contract)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	before := now.AddDate(0, 0, -10)
+	verrs, err = DB.ValidateAndCreate(&Task{Rate: 60, Duration: 30, StartTime: before, EndTime: before, ContractID: contract.ID})
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	verrs, err = DB.ValidateAndCreate(&Task{Rate: 60, Duration: 30, StartTime: now, EndTime: now, ContractID: contract.ID})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	ms.NoError(contract.SetArchived(DB, true, now))
+	ms.True(contract.IsArchived())
+	ms.NoError(user.GetActiveContracts(DB))
+	ms.Len(user.Contracts, 0)
+	ms.NoError(user.GetContracts(DB))
+	ms.Len(user.Contracts, 1)
+
+	ms.NoError(contract.SetArchived(DB, false, now))
+	ms.NoError(user.GetActiveContracts(DB))
+	ms.Len(user.Contracts, 1)
+}
+
+func (ms *ModelSuite) Test_Contract_Name() {
+	user := &User{Email: "named@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+
+	first := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID, Boss: boss}
+	verrs, err = DB.ValidateAndCreate(first)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal("ACME", first.Label())
+
+	verrs, err = DB.ValidateAndCreate(&Contract{Rate: 70, BossID: boss.ID, UserID: user.ID})
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+
+	second := &Contract{Rate: 70, BossID: boss.ID, UserID: user.ID, Name: " Website ", Boss: boss}
+	verrs, err = DB.ValidateAndCreate(second)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal("ACME - Website", second.Label())
+
+	verrs, err = DB.ValidateAndUpdate(second)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+}
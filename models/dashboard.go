@@ -0,0 +1,248 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// TrendWeeks is the number of weeks shown in the dashboard trend.
+const TrendWeeks = 12
+
+// BossSummary holds aggregated time and earnings for one boss.
+type BossSummary struct {
+	BossID      int    `json:"boss_id" db:"boss_id"`
+	BossName    string `json:"boss_name" db:"boss_name"`
+	Minutes     int    `json:"minutes" db:"minutes"`
+	RateMinutes int    `json:"-" db:"rate_minutes"`
+	// Charges are milestone and retainer earnings in cents, and for the
+	// unbilled summary, billable expenses too.
+	Charges int `json:"charges" db:"-"`
+}
+
+// Amount is the earnings for the summary in cents.
+func (b BossSummary) Amount() int {
+	return rateMinutesToCents(b.RateMinutes) + b.Charges
+}
+
+// PeriodSummary holds totals for a date range, broken down by boss.
+type PeriodSummary struct {
+	Label  string        `json:"label"`
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	Bosses []BossSummary `json:"bosses"`
+}
+
+// Minutes totals the time logged across all bosses.
+func (p PeriodSummary) Minutes() int {
+	total := 0
+	for _, b := range p.Bosses {
+		total += b.Minutes
+	}
+	return total
+}
+
+// Amount totals the earnings across all bosses in cents.
+func (p PeriodSummary) Amount() int {
+	total := 0
+	for _, b := range p.Bosses {
+		total += b.Amount()
+	}
+	return total
+}
+
+// WeekTotal holds totals for a single week starting on Monday.
+type WeekTotal struct {
+	Start       time.Time `json:"start"`
+	Minutes     int       `json:"minutes"`
+	RateMinutes int       `json:"-"`
+}
+
+// Amount is the earnings for the week in cents.
+func (w WeekTotal) Amount() int {
+	return rateMinutesToCents(w.RateMinutes)
+}
+
+// Dashboard collects the summaries shown on the home page.
+type Dashboard struct {
+	Periods  []PeriodSummary `json:"periods"`
+	Trend    []WeekTotal     `json:"trend"`
+	Unbilled PeriodSummary   `json:"unbilled"`
+}
+
+// TrendMax returns the largest weekly minutes total, used to scale the chart.
+func (d *Dashboard) TrendMax() int {
+	max := 0
+	for _, w := range d.Trend {
+		if w.Minutes > max {
+			max = w.Minutes
+		}
+	}
+	return max
+}
+
+// Earnings converts minutes at an hourly rate to cents.
+func Earnings(rate, minutes int) int {
+	return rateMinutesToCents(rate * minutes)
+}
+
+func rateMinutesToCents(rm int) int {
+	return int(math.Round(float64(rm) * 100 / 60))
+}
+
+// StartOfWeek returns midnight on the Monday of the week containing t.
+func StartOfWeek(t time.Time) time.Time {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// LoadDashboard builds the dashboard for a user relative to now.
+func LoadDashboard(tx *pop.Connection, userID uuid.UUID, now time.Time) (*Dashboard, error) {
+	d := &Dashboard{}
+
+	week := StartOfWeek(now)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	year := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	periods := []PeriodSummary{
+		{Label: "This week", From: week, To: week.AddDate(0, 0, 7)},
+		{Label: "This month", From: month, To: month.AddDate(0, 1, 0)},
+		{Label: "This year", From: year, To: year.AddDate(1, 0, 0)},
+	}
+	charged, err := chargedContracts(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range periods {
+		bosses, err := bossTotals(tx, userID, p.From, p.To)
+		if err != nil {
+			return nil, err
+		}
+		from, to := p.From, p.To
+		bosses, err = addCharges(bosses, charged, func(c *Contract) (int, error) {
+			return c.earnedBetween(tx, from, to)
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.Bosses = bosses
+		d.Periods = append(d.Periods, p)
+	}
+
+	trend, err := weeklyTotals(tx, userID, week, TrendWeeks)
+	if err != nil {
+		return nil, err
+	}
+	d.Trend = trend
+
+	unbilled, err := unbilledTotals(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	unbilled, err = addCharges(unbilled, charged, func(c *Contract) (int, error) {
+		lines, _, err := c.pendingCharges(tx, now)
+		total := 0
+		for _, l := range lines {
+			total += l.Amount
+		}
+		return total, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	expensed, err := expensedContracts(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	unbilled, err = addCharges(unbilled, expensed, func(c *Contract) (int, error) {
+		es, err := c.unbilledExpenses(tx)
+		return es.Totals().Unbilled, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.Unbilled = PeriodSummary{Label: "Unbilled", Bosses: unbilled}
+
+	return d, nil
+}
+
+const bossTotalsSQL = `SELECT bosses.id AS boss_id, bosses.name AS boss_name,
+	COALESCE(SUM(tasks.duration), 0) AS minutes,
+	COALESCE(SUM(tasks.rate * tasks.duration), 0) AS rate_minutes
+	FROM tasks
+	JOIN contracts ON contracts.id = tasks.contract_id
+	JOIN bosses ON bosses.id = contracts.boss_id
+	WHERE contracts.user_id = ? %s
+	GROUP BY bosses.id, bosses.name
+	ORDER BY bosses.name`
+
+// bossTotals sums tasks per boss with a start time in [from, to).
+func bossTotals(tx *pop.Connection, userID uuid.UUID, from, to time.Time) ([]BossSummary, error) {
+	rows := []BossSummary{}
+	q := tx.RawQuery(fmt.Sprintf(bossTotalsSQL, "AND tasks.start_time >= ? AND tasks.start_time < ?"), userID, from, to)
+	if err := q.All(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// unbilledTotals sums the tasks per boss that are not on an invoice yet.
+func unbilledTotals(tx *pop.Connection, userID uuid.UUID) ([]BossSummary, error) {
+	rows := []BossSummary{}
+	q := tx.RawQuery(fmt.Sprintf(bossTotalsSQL, "AND tasks.invoice_id IS NULL"), userID)
+	if err := q.All(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+type dayTotal struct {
+	Day         time.Time `db:"day"`
+	Minutes     int       `db:"minutes"`
+	RateMinutes int       `db:"rate_minutes"`
+}
+
+// weeklyTotals sums tasks per day and folds them into weeks ending with the
+// week starting at current.
+func weeklyTotals(tx *pop.Connection, userID uuid.UUID, current time.Time, weeks int) ([]WeekTotal, error) {
+	from := current.AddDate(0, 0, -7*(weeks-1))
+	to := current.AddDate(0, 0, 7)
+
+	days := []dayTotal{}
+	q := tx.RawQuery(`SELECT DATE(tasks.start_time) AS day,
+		COALESCE(SUM(tasks.duration), 0) AS minutes,
+		COALESCE(SUM(tasks.rate * tasks.duration), 0) AS rate_minutes
+		FROM tasks
+		JOIN contracts ON contracts.id = tasks.contract_id
+		WHERE contracts.user_id = ? AND tasks.start_time >= ? AND tasks.start_time < ?
+		GROUP BY DATE(tasks.start_time)`, userID, from, to)
+	if err := q.All(&days); err != nil {
+		return nil, err
+	}
+
+	return foldWeeks(days, from, weeks), nil
+}
+
+// foldWeeks buckets daily totals into consecutive weeks starting at from.
+func foldWeeks(days []dayTotal, from time.Time, weeks int) []WeekTotal {
+	trend := make([]WeekTotal, weeks)
+	for i := range trend {
+		trend[i].Start = from.AddDate(0, 0, 7*i)
+	}
+	for _, d := range days {
+		day := time.Date(d.Day.Year(), d.Day.Month(), d.Day.Day(), 0, 0, 0, 0, from.Location())
+		// Round to whole days so DST shifts do not move a day across weeks.
+		i := int(math.Round(day.Sub(from).Hours()/24)) / 7
+		if i < 0 || i >= weeks {
+			continue
+		}
+		trend[i].Minutes += d.Minutes
+		trend[i].RateMinutes += d.RateMinutes
+	}
+	return trend
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+)
+
+func (ms *ModelSuite) Test_StartOfWeek() {
+	// Wednesday.
+	now := time.Date(2021, 7, 21, 15, 30, 0, 0, time.UTC)
+	ms.Equal(time.Date(2021, 7, 19, 0, 0, 0, 0, time.UTC), StartOfWeek(now))
+
+	// Sunday belongs to the week that started the previous Monday.
+	sunday := time.Date(2021, 7, 25, 9, 0, 0, 0, time.UTC)
+	ms.Equal(time.Date(2021, 7, 19, 0, 0, 0, 0, time.UTC), StartOfWeek(sunday))
+}
+
+func (ms *ModelSuite) Test_Earnings() {
+	ms.Equal(5000, Earnings(50, 60))
+	ms.Equal(2500, Earnings(100, 15))
+	ms.Equal(333, Earnings(10, 20))
+}
+
+func (ms *ModelSuite) Test_FoldWeeks() {
+	from := time.Date(2021, 7, 5, 0, 0, 0, 0, time.UTC)
+	days := []dayTotal{
+		{Day: time.Date(2021, 7, 5, 0, 0, 0, 0, time.UTC), Minutes: 30, RateMinutes: 300},
+		{Day: time.Date(2021, 7, 11, 0, 0, 0, 0, time.UTC), Minutes: 60, RateMinutes: 600},
+		{Day: time.Date(2021, 7, 12, 0, 0, 0, 0, time.UTC), Minutes: 15, RateMinutes: 150},
+		// Outside the window.
+		{Day: time.Date(2021, 7, 26, 0, 0, 0, 0, time.UTC), Minutes: 90, RateMinutes: 900},
+	}
+
+	trend := foldWeeks(days, from, 3)
+	ms.Len(trend, 3)
+	ms.Equal(90, trend[0].Minutes)
+	ms.Equal(15, trend[1].Minutes)
+	ms.Equal(0, trend[2].Minutes)
+	ms.Equal(time.Date(2021, 7, 19, 0, 0, 0, 0, time.UTC), trend[2].Start)
+}
+
+func (ms *ModelSuite) Test_LoadDashboard() {
+	now := time.Now()
+
+	user := &User{Email: "dash@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+
+	task := &Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID}
+	ms.NoError(DB.Create(task))
+
+	d, err := LoadDashboard(DB, user.ID, now)
+	ms.NoError(err)
+	ms.Len(d.Periods, 3)
+	ms.Equal(90, d.Periods[0].Minutes())
+	ms.Equal(9000, d.Periods[0].Amount())
+	ms.Equal("ACME", d.Periods[0].Bosses[0].BossName)
+	ms.Equal(90, d.Trend[TrendWeeks-1].Minutes)
+	ms.Equal(9000, d.Unbilled.Amount())
+}
+
+func (ms *ModelSuite) Test_LoadDashboard_Expenses() {
+	now := time.Now()
+
+	user := &User{Email: "dash@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+
+	// Expenses count toward unbilled even before any time is logged.
+	travel := &Expense{ContractID: contract.ID, Kind: ExpenseOther, SpentOn: now, Category: "Travel", Currency: BillingCurrency, Amount: 2500, BaseAmount: 2500, Billable: true, Markup: 1000}
+	ms.NoError(DB.Create(travel))
+	meals := &Expense{ContractID: contract.ID, Kind: ExpenseOther, SpentOn: now, Category: "Meals", Currency: BillingCurrency, Amount: 1200, BaseAmount: 1200}
+	ms.NoError(DB.Create(meals))
+
+	d, err := LoadDashboard(DB, user.ID, now)
+	ms.NoError(err)
+	ms.Len(d.Unbilled.Bosses, 1)
+	ms.Equal("ACME", d.Unbilled.Bosses[0].BossName)
+	ms.Equal(2750, d.Unbilled.Amount())
+	ms.Equal(0, d.Periods[0].Amount())
+}
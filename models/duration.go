@@ -0,0 +1,67 @@
+package models
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var hoursMinutesPattern = regexp.MustCompile(`^(?:(\d+(?:\.\d+)?)\s*h)?\s*(?:(\d+)\s*m)?$`)
+
+// ParseDuration reads a duration in minutes from the formats commonly found
+// in timesheets: "90" (minutes), "1:30", "1:30:00", "1.5h", "1h 30m" and "45m".
+func ParseDuration(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, errors.New("duration is empty")
+	}
+
+	if strings.Contains(s, ":") {
+		parts := strings.Split(s, ":")
+		if len(parts) > 3 {
+			return 0, errors.Errorf("invalid duration %q", s)
+		}
+		nums := make([]int, 3)
+		for i, p := range parts {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 0 {
+				return 0, errors.Errorf("invalid duration %q", s)
+			}
+			nums[i] = n
+		}
+		return nums[0]*60 + nums[1] + int(math.Round(float64(nums[2])/60)), nil
+	}
+
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+
+	m := hoursMinutesPattern.FindStringSubmatch(s)
+	if m == nil || (m[1] == "" && m[2] == "") {
+		return 0, errors.Errorf("invalid duration %q", s)
+	}
+	minutes := 0.0
+	if m[1] != "" {
+		h, _ := strconv.ParseFloat(m[1], 64)
+		minutes += h * 60
+	}
+	if m[2] != "" {
+		n, _ := strconv.Atoi(m[2])
+		minutes += float64(n)
+	}
+	return int(math.Round(minutes)), nil
+}
+
+// DurationFromHours converts decimal hours to whole minutes.
+func DurationFromHours(h float64) int {
+	return int(math.Round(h * 60))
+}
+
+// DurationBetween returns the whole minutes between start and end.
+func DurationBetween(start, end time.Time) int {
+	return int(math.Round(end.Sub(start).Minutes()))
+}
@@ -0,0 +1,24 @@
+package models
+
+func (ms *ModelSuite) Test_ParseDuration() {
+	tests := map[string]int{
+		"90":      90,
+		"1:30":    90,
+		"1:30:40": 91,
+		"1.5h":    90,
+		"1h 30m":  90,
+		"2h":      120,
+		"45m":     45,
+		" 15 ":    15,
+	}
+	for in, want := range tests {
+		got, err := ParseDuration(in)
+		ms.NoError(err, in)
+		ms.Equal(want, got, in)
+	}
+
+	for _, in := range []string{"", "abc", "1:xx", "h"} {
+		_, err := ParseDuration(in)
+		ms.Error(err, in)
+	}
+}
@@ -0,0 +1,332 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrExpenseInvoiced is returned when removing an expense that is already
+// on an invoice.
+var ErrExpenseInvoiced = errors.New("expense is already invoiced")
+
+// Expense kinds. Expenses are paid out of pocket and reimbursed; mileage
+// is the distance driven at the contract's mileage rate.
+const (
+	ExpenseOther   = "expense"
+	ExpenseMileage = "mileage"
+)
+
+// ExpenseCategories lists the categories an expense can be filed under.
+// Mileage is always filed under "Mileage".
+var ExpenseCategories = []string{"Travel", "Lodging", "Meals", "Materials", "Software", "Other"}
+
+// BillingCurrency is the currency invoices are issued in.
+const BillingCurrency = "USD"
+
+// ExpenseCurrencies lists the currencies an expense can be paid in.
+var ExpenseCurrencies = []string{BillingCurrency, "CAD", "EUR", "GBP", "AUD"}
+
+// MileageUnits lists the units a contract's mileage rate can be per.
+var MileageUnits = []string{"mi", "km"}
+
+// Expense is a cost on a contract to be passed on to the boss. Amount is in
+// cents of Currency; BaseAmount is what it came to in the BillingCurrency.
+// Billable expenses go on the next invoice with their markup, in hundredths
+// of a percent, added. Mileage keeps the distance, in hundredths, and the
+// rate and unit it was logged at.
+type Expense struct {
+	ID          int          `json:"id" db:"id"`
+	ContractID  int          `json:"-" db:"contract_id"`
+	Kind        string       `json:"kind" db:"kind"`
+	SpentOn     time.Time    `json:"spent_on" db:"spent_on"`
+	Category    string       `json:"category" db:"category"`
+	Description nulls.String `json:"description" db:"description"`
+	Currency    string       `json:"currency" db:"currency"`
+	Amount      int          `json:"amount" db:"amount"`
+	BaseAmount  int          `json:"base_amount" db:"base_amount"`
+	Distance    int          `json:"distance" db:"distance"`
+	MileageRate int          `json:"mileage_rate" db:"mileage_rate"`
+	MileageUnit string       `json:"mileage_unit" db:"mileage_unit"`
+	Billable    bool         `json:"billable" db:"billable"`
+	Markup      int          `json:"markup" db:"markup"`
+	InvoiceID   nulls.Int    `json:"-" db:"invoice_id"`
+	Receipt     *Attachment  `json:"-" db:"-"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (e Expense) String() string {
+	je, _ := json.Marshal(e)
+	return string(je)
+}
+
+// Expenses is not required by pop and may be deleted
+type Expenses []Expense
+
+// NewMileage logs distance, in hundredths, driven for the contract at its
+// current mileage rate.
+func NewMileage(c *Contract, day time.Time, distance int) *Expense {
+	amount := int(math.Round(float64(distance) * float64(c.MileageRate) / 100))
+	return &Expense{
+		ContractID:  c.ID,
+		Kind:        ExpenseMileage,
+		SpentOn:     day,
+		Category:    "Mileage",
+		Currency:    BillingCurrency,
+		Amount:      amount,
+		BaseAmount:  amount,
+		Distance:    distance,
+		MileageRate: c.MileageRate,
+		MileageUnit: c.mileageUnit(),
+	}
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (e *Expense) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	if e.Kind == "" {
+		e.Kind = ExpenseOther
+	}
+	if e.Currency == BillingCurrency {
+		e.BaseAmount = e.Amount
+	}
+	errs := validate.Validate(
+		&validators.IntIsPresent{Field: e.ContractID, Name: "ContractID"},
+		&validators.TimeIsPresent{Field: e.SpentOn, Name: "SpentOn", Message: "Enter the date."},
+		&validators.StringInclusion{Field: e.Currency, Name: "Currency", List: ExpenseCurrencies},
+	)
+	switch e.Kind {
+	case ExpenseMileage:
+		if e.Distance <= 0 {
+			errs.Add("distance", "Enter the distance driven.")
+		} else if e.MileageRate <= 0 {
+			errs.Add("mileage_rate", "Set the contract's mileage rate first.")
+		}
+	case ExpenseOther:
+		(&validators.StringInclusion{Field: e.Category, Name: "Category", List: ExpenseCategories, Message: "Pick a category."}).IsValid(errs)
+		if e.Amount <= 0 {
+			errs.Add("amount", "Enter the amount spent.")
+		} else if e.BaseAmount <= 0 {
+			errs.Add("base_amount", fmt.Sprintf("Enter what the expense came to in %s.", BillingCurrency))
+		}
+	default:
+		errs.Add("kind", "Pick an expense or mileage.")
+	}
+	if len(e.Description.String) > 200 {
+		errs.Add("description", "Descriptions can be up to 200 characters.")
+	}
+	if e.Markup < 0 {
+		errs.Add("markup", "Markup cannot be negative.")
+	}
+	return errs, nil
+}
+
+// IsMileage reports whether the expense is distance driven.
+func (e *Expense) IsMileage() bool {
+	return e.Kind == ExpenseMileage
+}
+
+// IsInvoiced reports whether the expense is on an invoice.
+func (e *Expense) IsInvoiced() bool {
+	return e.InvoiceID.Valid
+}
+
+// HasReceipt reports whether a receipt is attached. Receipts are loaded by
+// LoadExpenses.
+func (e *Expense) HasReceipt() bool {
+	return e.Receipt != nil
+}
+
+// MarkupAmount is the markup on the expense in cents.
+func (e *Expense) MarkupAmount() int {
+	return int(math.Round(float64(e.BaseAmount) * float64(e.Markup) / 10000))
+}
+
+// Charge is what the expense is billed at in cents: its cost in the
+// billing currency plus markup. Expenses that are not billable charge
+// nothing.
+func (e *Expense) Charge() int {
+	if !e.Billable {
+		return 0
+	}
+	return e.BaseAmount + e.MarkupAmount()
+}
+
+// AmountLabel shows what was spent in its own currency, e.g. "42.00 EUR".
+// Mileage shows the distance and rate, e.g. "12.5 mi at $0.58/mi".
+func (e *Expense) AmountLabel() string {
+	if e.IsMileage() {
+		return fmt.Sprintf("%s %s at %s/%s", FormatDistance(e.Distance), e.MileageUnit, FormatMoney(e.MileageRate), e.MileageUnit)
+	}
+	if e.Currency == BillingCurrency {
+		return FormatMoney(e.Amount)
+	}
+	return strings.TrimPrefix(FormatMoney(e.Amount), "$") + " " + e.Currency
+}
+
+// LineDescription describes the expense on an invoice.
+func (e *Expense) LineDescription() string {
+	desc := e.Category + ", " + e.SpentOn.Format("Jan 2")
+	if e.IsMileage() {
+		desc += ": " + FormatDistance(e.Distance) + " " + e.MileageUnit
+	}
+	if e.Description.Valid && e.Description.String != "" {
+		desc += ": " + e.Description.String
+	}
+	notes := []string{}
+	if !e.IsMileage() && e.Currency != BillingCurrency {
+		notes = append(notes, e.AmountLabel())
+	}
+	if e.Markup > 0 {
+		notes = append(notes, "incl. "+FormatPercent(e.Markup)+" markup")
+	}
+	if len(notes) > 0 {
+		desc += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return desc
+}
+
+// FormatDistance formats a distance in hundredths, e.g. 1250 as "12.5".
+func FormatDistance(d int) string {
+	return strconv.FormatFloat(float64(d)/100, 'f', -1, 64)
+}
+
+// ParseDistance reads a distance such as "12.5" as hundredths.
+func ParseDistance(s string) (int, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) {
+		return 0, errors.Errorf("invalid distance %q", s)
+	}
+	return int(math.Round(f * 100)), nil
+}
+
+// ExpenseTotals sums a contract's expenses and mileage. Billable is the
+// total charge with markup; Unbilled is the part not on an invoice yet.
+type ExpenseTotals struct {
+	Expenses int
+	Mileage  int
+	Billable int
+	Unbilled int
+}
+
+// Totals sums the expenses in the billing currency.
+func (es Expenses) Totals() ExpenseTotals {
+	t := ExpenseTotals{}
+	for i := range es {
+		e := &es[i]
+		if e.IsMileage() {
+			t.Mileage += e.BaseAmount
+		} else {
+			t.Expenses += e.BaseAmount
+		}
+		t.Billable += e.Charge()
+		if !e.IsInvoiced() {
+			t.Unbilled += e.Charge()
+		}
+	}
+	return t
+}
+
+// LoadExpenses returns the contract's expenses, newest first, with their
+// receipts.
+func LoadExpenses(tx *pop.Connection, contractID int) (Expenses, error) {
+	es := Expenses{}
+	err := tx.Where("contract_id = ?", contractID).Order("spent_on desc, id desc").All(&es)
+	if err != nil || len(es) == 0 {
+		return es, err
+	}
+	receipts := Attachments{}
+	err = tx.Where("expense_id IN (SELECT id FROM expenses WHERE contract_id = ?)", contractID).Order("id asc").All(&receipts)
+	if err != nil {
+		return es, err
+	}
+	for i := range es {
+		for j := range receipts {
+			if receipts[j].ExpenseID.Int == es[i].ID {
+				es[i].Receipt = &receipts[j]
+			}
+		}
+	}
+	return es, nil
+}
+
+// unbilledExpenses returns the contract's billable expenses that are not on
+// an invoice, oldest first.
+func (c *Contract) unbilledExpenses(tx *pop.Connection) (Expenses, error) {
+	es := Expenses{}
+	err := tx.Where("contract_id = ? AND billable = ? AND invoice_id IS NULL", c.ID, true).Order("spent_on asc, id asc").All(&es)
+	return es, err
+}
+
+// expensedContracts returns the user's contracts with unbilled, billable
+// expenses, with their bosses.
+func expensedContracts(tx *pop.Connection, userID uuid.UUID) (Contracts, error) {
+	cs := Contracts{}
+	err := tx.Where("user_id = ? AND id IN (SELECT contract_id FROM expenses WHERE billable = ? AND invoice_id IS NULL)", userID, true).Eager("Boss").All(&cs)
+	return cs, err
+}
+
+// expenseLines turns expenses into invoice lines.
+func expenseLines(es Expenses) []InvoiceLine {
+	lines := []InvoiceLine{}
+	for i := range es {
+		lines = append(lines, InvoiceLine{Description: es[i].LineDescription(), Amount: es[i].Charge()})
+	}
+	return lines
+}
+
+// validateMileage checks the contract's mileage rate, in cents per unit.
+func (c *Contract) validateMileage(errs *validate.Errors) {
+	if c.MileageUnit == "" {
+		c.MileageUnit = MileageUnits[0]
+	}
+	if c.MileageRate < 0 {
+		errs.Add("mileage_rate", "The mileage rate cannot be negative.")
+	}
+	(&validators.StringInclusion{Field: c.MileageUnit, Name: "MileageUnit", List: MileageUnits, Message: "Pick miles or kilometers."}).IsValid(errs)
+}
+
+// mileageUnit is the unit of the contract's mileage rate, miles unless
+// set.
+func (c *Contract) mileageUnit() string {
+	if c.MileageUnit == "" {
+		return MileageUnits[0]
+	}
+	return c.MileageUnit
+}
+
+// MileageLabel describes the contract's mileage rate, e.g. "$0.58/mi".
+func (c *Contract) MileageLabel() string {
+	return FormatMoney(c.MileageRate) + "/" + c.mileageUnit()
+}
+
+// FindExpense finds one of the contract's expenses.
+func FindExpense(tx *pop.Connection, contractID int, id string) (*Expense, error) {
+	e := &Expense{}
+	err := tx.Where("contract_id = ?", contractID).Find(e, id)
+	return e, err
+}
+
+// Remove deletes an expense that is not invoiced along with its receipts.
+// The receipts are returned so their files can be deleted too.
+func (e *Expense) Remove(tx *pop.Connection) (Attachments, error) {
+	if e.IsInvoiced() {
+		return nil, ErrExpenseInvoiced
+	}
+	receipts := Attachments{}
+	if err := tx.Where("expense_id = ?", e.ID).All(&receipts); err != nil {
+		return nil, err
+	}
+	return receipts, tx.Destroy(e)
+}
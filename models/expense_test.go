@@ -0,0 +1,104 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+func (ms *ModelSuite) Test_Expense_Charge() {
+	e := &Expense{Kind: ExpenseOther, Category: "Travel", Currency: "EUR", Amount: 4200, BaseAmount: 4560, Billable: true, Markup: 1000, SpentOn: time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC)}
+	ms.Equal(456, e.MarkupAmount())
+	ms.Equal(5016, e.Charge())
+	ms.Equal("42.00 EUR", e.AmountLabel())
+	ms.Equal("Travel, Dec 20 (42.00 EUR, incl. 10% markup)", e.LineDescription())
+
+	e.Billable = false
+	ms.Equal(0, e.Charge())
+
+	c := &Contract{ID: 1, MileageRate: 58}
+	m := NewMileage(c, e.SpentOn, 1250)
+	ms.Equal(725, m.BaseAmount)
+	ms.Equal("12.5 mi at $0.58/mi", m.AmountLabel())
+	ms.Equal("$0.58/mi", c.MileageLabel())
+
+	d, err := ParseDistance(" 12.5 ")
+	ms.NoError(err)
+	ms.Equal(1250, d)
+	_, err = ParseDistance("far")
+	ms.Error(err)
+	ms.Equal("12.5", FormatDistance(1250))
+}
+
+func (ms *ModelSuite) Test_Expense_Validate() {
+	e := &Expense{ContractID: 1, SpentOn: time.Now(), Category: "Travel", Currency: "EUR", Amount: 4200}
+	verrs, err := e.Validate(DB)
+	ms.NoError(err)
+	ms.NotEmpty(verrs.Get("base_amount"))
+
+	// Expenses in the billing currency need no conversion.
+	e.Currency = BillingCurrency
+	verrs, err = e.Validate(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal(4200, e.BaseAmount)
+
+	m := NewMileage(&Contract{ID: 1}, time.Now(), 1250)
+	verrs, err = m.Validate(DB)
+	ms.NoError(err)
+	ms.NotEmpty(verrs.Get("mileage_rate"))
+}
+
+func (ms *ModelSuite) Test_CreateInvoice_Expenses() {
+	now := time.Now()
+
+	user := &User{Email: "expenses@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID, MileageRate: 58, MileageUnit: "mi"}
+	ms.NoError(DB.Create(contract))
+
+	travel := &Expense{ContractID: contract.ID, SpentOn: now, Category: "Travel", Currency: BillingCurrency, Amount: 10000, Billable: true, Markup: 1000}
+	verrs, err = DB.ValidateAndCreate(travel)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	meals := &Expense{ContractID: contract.ID, SpentOn: now, Category: "Meals", Currency: BillingCurrency, Amount: 1500}
+	verrs, err = DB.ValidateAndCreate(meals)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	drive := NewMileage(contract, now, 1250)
+	drive.Billable = true
+	verrs, err = DB.ValidateAndCreate(drive)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	expenses, err := LoadExpenses(DB, contract.ID)
+	ms.NoError(err)
+	totals := expenses.Totals()
+	ms.Equal(11500, totals.Expenses)
+	ms.Equal(725, totals.Mileage)
+	ms.Equal(11725, totals.Unbilled)
+
+	// Only billable expenses are invoiced, with their markup.
+	inv, verrs, err := CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	loaded, err := LoadInvoice(DB, user.ID, strconv.Itoa(inv.ID))
+	ms.NoError(err)
+	ms.Len(loaded.Lines, 2)
+	ms.Equal(11725, loaded.Subtotal())
+
+	ms.NoError(DB.Reload(travel))
+	ms.True(travel.IsInvoiced())
+	_, err = travel.Remove(DB)
+	ms.Equal(ErrExpenseInvoiced, err)
+	_, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.Equal(ErrNothingToInvoice, err)
+
+	// Voiding puts the expenses back up for billing.
+	ms.NoError(loaded.Void(DB))
+	ms.NoError(DB.Reload(travel))
+	ms.False(travel.IsInvoiced())
+}
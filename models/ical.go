@@ -0,0 +1,269 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const icalTimeFormat = "20060102T150405Z"
+
+// icalEscape escapes a TEXT value per RFC 5545.
+func icalEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// icalFold splits a content line into 75 octet chunks joined by CRLF and a
+// space, without breaking UTF-8 sequences.
+func icalFold(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts as an octet.
+		limit = 74
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+// CalendarWriter writes an iCalendar stream one event at a time.
+type CalendarWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewCalendarWriter starts a VCALENDAR named name.
+func NewCalendarWriter(w io.Writer, name string) *CalendarWriter {
+	cw := &CalendarWriter{w: bufio.NewWriter(w)}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//buftester//Timelogger//EN")
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("X-WR-CALNAME:" + icalEscape(name))
+	return cw
+}
+
+func (cw *CalendarWriter) line(s string) {
+	if cw.err != nil {
+		return
+	}
+	_, cw.err = cw.w.WriteString(icalFold(s) + "\r\n")
+}
+
+// WriteTask adds a task as a VEVENT. The summary is the boss name followed by
+// the description. Tasks without a usable end time span their duration.
+func (cw *CalendarWriter) WriteTask(t TaskRow) error {
+	end := t.EndTime
+	if !end.After(t.StartTime) {
+		end = t.StartTime.Add(time.Duration(t.Duration) * time.Minute)
+	}
+	summary := t.BossName
+	if t.Description != "" {
+		summary += ": " + t.Description
+	}
+
+	cw.line("BEGIN:VEVENT")
+	cw.line(fmt.Sprintf("UID:task-%d@buftester", t.ID))
+	cw.line("DTSTAMP:" + t.UpdatedAt.UTC().Format(icalTimeFormat))
+	cw.line("DTSTART:" + t.StartTime.UTC().Format(icalTimeFormat))
+	cw.line("DTEND:" + end.UTC().Format(icalTimeFormat))
+	cw.line("SUMMARY:" + icalEscape(summary))
+	if t.Description != "" {
+		cw.line("DESCRIPTION:" + icalEscape(t.Description))
+	}
+	cw.line("END:VEVENT")
+	return cw.err
+}
+
+// Close ends the calendar and flushes the output.
+func (cw *CalendarWriter) Close() error {
+	cw.line("END:VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+// CalendarEvent is a VEVENT read from an iCalendar file.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+}
+
+// Duration is the length of the event in minutes.
+func (e CalendarEvent) Duration() int {
+	return DurationBetween(e.Start, e.End)
+}
+
+// ExternalID identifies the event occurrence for de-duplication.
+func (e CalendarEvent) ExternalID() string {
+	return "ics:" + e.UID + ":" + e.Start.UTC().Format(icalTimeFormat)
+}
+
+// icalUnescape reverses icalEscape.
+func icalUnescape(s string) string {
+	r := strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+	return r.Replace(s)
+}
+
+// icalProperty is a content line split into name, parameters and value.
+type icalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+func parseICalLine(line string) (icalProperty, bool) {
+	p := icalProperty{Params: map[string]string{}}
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return p, false
+	}
+	head := strings.Split(line[:i], ";")
+	p.Name = strings.ToUpper(head[0])
+	p.Value = line[i+1:]
+	for _, param := range head[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			p.Params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return p, true
+}
+
+// parseICalTime reads DATE and DATE-TIME values, honouring TZID.
+func parseICalTime(p icalProperty) (time.Time, error) {
+	loc := time.Local
+	if tz := p.Params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	v := p.Value
+	switch {
+	case strings.HasSuffix(v, "Z"):
+		t, err := time.Parse(icalTimeFormat, v)
+		return t.Local(), err
+	case len(v) == len("20060102"):
+		return time.ParseInLocation("20060102", v, loc)
+	default:
+		return time.ParseInLocation("20060102T150405", v, loc)
+	}
+}
+
+var icalDurationPattern = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICalDuration reads a DURATION value such as PT1H30M.
+func parseICalDuration(v string) (time.Duration, error) {
+	m := icalDurationPattern.FindStringSubmatch(strings.TrimPrefix(v, "+"))
+	if m == nil {
+		return 0, errors.Errorf("invalid duration %q", v)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, u := range units {
+		if m[i+1] != "" {
+			n, _ := strconv.Atoi(m[i+1])
+			d += time.Duration(n) * u
+		}
+	}
+	return d, nil
+}
+
+// ParseCalendar reads the events of an iCalendar file. Recurring events are
+// returned once, at their first occurrence.
+func ParseCalendar(r io.Reader) ([]CalendarEvent, error) {
+	// Unfold continuation lines first.
+	lines := []string{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	events := []CalendarEvent{}
+	var ev *CalendarEvent
+	var duration time.Duration
+	depth := 0
+	for n, l := range lines {
+		p, ok := parseICalLine(l)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.Name == "BEGIN" && strings.EqualFold(p.Value, "VEVENT"):
+			ev = &CalendarEvent{}
+			duration = 0
+			depth = 0
+		case p.Name == "BEGIN" && ev != nil:
+			// Skip nested components such as VALARM.
+			depth++
+		case p.Name == "END" && ev != nil && depth > 0:
+			depth--
+		case p.Name == "END" && strings.EqualFold(p.Value, "VEVENT") && ev != nil:
+			if ev.Start.IsZero() {
+				return nil, errors.Errorf("line %d: event %q has no start", n+1, ev.Summary)
+			}
+			if ev.End.IsZero() {
+				ev.End = ev.Start.Add(duration)
+			}
+			events = append(events, *ev)
+			ev = nil
+		case ev == nil || depth > 0:
+			continue
+		case p.Name == "UID":
+			ev.UID = p.Value
+		case p.Name == "SUMMARY":
+			ev.Summary = icalUnescape(p.Value)
+		case p.Name == "DESCRIPTION":
+			ev.Description = icalUnescape(p.Value)
+		case p.Name == "DTSTART":
+			t, err := parseICalTime(p)
+			if err != nil {
+				return nil, errors.Errorf("line %d: cannot read start %q", n+1, p.Value)
+			}
+			ev.Start = t
+		case p.Name == "DTEND":
+			t, err := parseICalTime(p)
+			if err != nil {
+				return nil, errors.Errorf("line %d: cannot read end %q", n+1, p.Value)
+			}
+			ev.End = t
+		case p.Name == "DURATION":
+			d, err := parseICalDuration(p.Value)
+			if err != nil {
+				return nil, errors.Errorf("line %d: %v", n+1, err)
+			}
+			duration = d
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+func (ms *ModelSuite) Test_ICalFold() {
+	short := "SUMMARY:short"
+	ms.Equal(short, icalFold(short))
+
+	long := "SUMMARY:" + strings.Repeat("é", 60)
+	for _, l := range strings.Split(icalFold(long), "\r\n") {
+		ms.True(len(l) <= 75, l)
+	}
+	ms.Equal(long, strings.ReplaceAll(icalFold(long), "\r\n ", ""))
+}
+
+func (ms *ModelSuite) Test_CalendarWriter() {
+	start := time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)
+	b := &bytes.Buffer{}
+	cw := NewCalendarWriter(b, "Jo tasks")
+	ms.NoError(cw.WriteTask(TaskRow{ID: 7, BossName: "ACME", Description: "Call, notes", StartTime: start, Duration: 45}))
+	ms.NoError(cw.Close())
+
+	out := b.String()
+	ms.True(strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"))
+	ms.Contains(out, "UID:task-7@buftester\r\n")
+	ms.Contains(out, "DTSTART:20210301T090000Z\r\n")
+	ms.Contains(out, "DTEND:20210301T094500Z\r\n")
+	ms.Contains(out, "SUMMARY:ACME: Call\\, notes\r\n")
+	ms.True(strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+}
+
+func (ms *ModelSuite) Test_ParseCalendar() {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:abc@example.com\r\n" +
+		"DTSTART:20210301T090000Z\r\n" +
+		"DTEND:20210301T103000Z\r\n" +
+		"SUMMARY:ACME standup\\, weekly\r\n" +
+		"DESCRIPTION:Long notes that are folded \r\n" +
+		" across two lines\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"DESCRIPTION:Reminder\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:def@example.com\r\n" +
+		"DTSTART;TZID=UTC:20210302T140000\r\n" +
+		"DURATION:PT45M\r\n" +
+		"SUMMARY:Review\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := ParseCalendar(strings.NewReader(ics))
+	ms.NoError(err)
+	ms.Len(events, 2)
+
+	ms.Equal("abc@example.com", events[0].UID)
+	ms.Equal("ACME standup, weekly", events[0].Summary)
+	ms.Equal("Long notes that are folded across two lines", events[0].Description)
+	ms.Equal(90, events[0].Duration())
+	ms.Equal("ics:abc@example.com:20210301T090000Z", events[0].ExternalID())
+
+	ms.Equal(45, events[1].Duration())
+	ms.Equal("ics:def@example.com:20210302T140000Z", events[1].ExternalID())
+
+	_, err = ParseCalendar(strings.NewReader("BEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\n"))
+	ms.Error(err)
+}
@@ -0,0 +1,295 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+)
+
+// ImportFields lists the Task fields a CSV column can be mapped to.
+var ImportFields = []string{"Date", "Start", "End", "Duration", "Description", "Rate", "Boss"}
+
+// ImportMapping maps Task fields to zero-based CSV column indexes. Fields
+// that are not in the map are not imported.
+type ImportMapping map[string]int
+
+// Column returns the raw value of field in record, or "" if unmapped.
+func (m ImportMapping) Column(record []string, field string) string {
+	i, ok := m[field]
+	if !ok || i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// Index returns the column for field, or -1 if it is unmapped.
+func (m ImportMapping) Index(field string) int {
+	if i, ok := m[field]; ok {
+		return i
+	}
+	return -1
+}
+
+// GuessImportMapping matches CSV headers against the import field names.
+func GuessImportMapping(headers []string) ImportMapping {
+	aliases := map[string][]string{
+		"Date":        {"date", "day", "start date"},
+		"Start":       {"start", "start time", "from"},
+		"End":         {"end", "end time", "to", "stop"},
+		"Duration":    {"duration", "minutes"},
+		"Description": {"description", "notes", "note", "task"},
+		"Rate":        {"rate", "hourly rate"},
+		"Boss":        {"boss", "client", "customer", "employer"},
+	}
+	m := ImportMapping{}
+	for i, h := range headers {
+		h = strings.ToLower(strings.TrimSpace(h))
+		for field, names := range aliases {
+			if _, taken := m[field]; taken {
+				continue
+			}
+			for _, n := range names {
+				if h == n {
+					m[field] = i
+				}
+			}
+		}
+	}
+	return m
+}
+
+// ReadCSV reads all records from r, returning the header row separately.
+func ReadCSV(r io.Reader) ([]string, [][]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if len(records) == 0 {
+		return nil, nil, errors.New("file is empty")
+	}
+	return records[0], records[1:], nil
+}
+
+// ImportRow is a single parsed CSV line ready to be saved as a Task.
+type ImportRow struct {
+	Line     int
+	BossName string
+	Task     Task
+	Errors   *validate.Errors
+}
+
+// ImportRows is the result of a dry run.
+type ImportRows []ImportRow
+
+// HasErrors reports if any row failed to parse or validate.
+func (rows ImportRows) HasErrors() bool {
+	for _, r := range rows {
+		if r.Errors.HasAny() {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCount returns the number of rows with errors.
+func (rows ImportRows) ErrorCount() int {
+	n := 0
+	for _, r := range rows {
+		if r.Errors.HasAny() {
+			n++
+		}
+	}
+	return n
+}
+
+var importDateLayouts = []string{
+	"2006-01-02",
+	"01/02/2006",
+	"1/2/2006",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+var importTimeLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM"}
+
+// parseImportTime reads a date or date-time value. A bare time of day is
+// combined with day, which may be zero if no date column is mapped.
+func parseImportTime(s string, day time.Time) (time.Time, error) {
+	for _, l := range importDateLayouts {
+		if t, err := time.ParseInLocation(l, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if !day.IsZero() {
+		for _, l := range importTimeLayouts {
+			if t, err := time.ParseInLocation(l, s, time.Local); err == nil {
+				return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), nil
+			}
+		}
+	}
+	return time.Time{}, errors.Errorf("cannot read %q as a date or time", s)
+}
+
+// ImportTarget decides which contract an import row belongs to. Either every
+// row goes to Contract, or rows are matched by boss name to one of the
+// user's Contracts.
+type ImportTarget struct {
+	Contract  *Contract
+	Contracts []Contract
+}
+
+// resolve finds the contract for a boss name. The name may also be a
+// contract label such as "ACME - Website" when there are several contracts
+// with one boss.
+func (t ImportTarget) resolve(bossName string) (*Contract, error) {
+	if t.Contract != nil {
+		return t.Contract, nil
+	}
+	if bossName == "" {
+		return nil, errors.New("No boss given and no contract selected.")
+	}
+	var found *Contract
+	n := 0
+	for i := range t.Contracts {
+		c := &t.Contracts[i]
+		if c.Boss == nil {
+			continue
+		}
+		if c.Name != "" && strings.EqualFold(c.Label(), bossName) {
+			return c, nil
+		}
+		if strings.EqualFold(c.Boss.Name, bossName) {
+			found = c
+			n++
+		}
+	}
+	if n > 1 {
+		return nil, errors.Errorf("Several contracts found for boss %q; select a contract.", bossName)
+	}
+	if found == nil {
+		return nil, errors.Errorf("No contract found for boss %q.", bossName)
+	}
+	return found, nil
+}
+
+// BuildImport parses records with the mapping and validates the resulting
+// tasks. Nothing is written to the database.
+func BuildImport(tx *pop.Connection, records [][]string, m ImportMapping, target ImportTarget) (ImportRows, error) {
+	rows := ImportRows{}
+	for i, rec := range records {
+		row := ImportRow{
+			// Header is line 1.
+			Line:     i + 2,
+			BossName: m.Column(rec, "Boss"),
+			Errors:   validate.NewErrors(),
+		}
+		parseImportRecord(&row, rec, m)
+
+		contract, err := target.resolve(row.BossName)
+		if err != nil {
+			row.Errors.Add("contract", err.Error())
+		} else {
+			row.Task.ContractID = contract.ID
+			row.Task.Contract = contract
+			if _, ok := m["Rate"]; !ok || !contract.IsHourly() {
+				row.Task.Rate = contract.TaskRate()
+			}
+			if !row.Task.StartTime.IsZero() && !contract.Covers(row.Task.StartTime) {
+				row.Errors.Add("date", "The date is outside the contract's dates ("+contract.WindowLabel()+").")
+			}
+		}
+
+		verrs, err := row.Task.Validate(tx)
+		if err != nil {
+			return nil, err
+		}
+		row.Errors.Append(verrs)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseImportRecord fills in row.Task from a CSV record, recording any
+// problems on row.Errors.
+func parseImportRecord(row *ImportRow, rec []string, m ImportMapping) {
+	t := &row.Task
+	t.Description = m.Column(rec, "Description")
+
+	var day time.Time
+	if v := m.Column(rec, "Date"); v != "" {
+		d, err := parseImportTime(v, time.Time{})
+		if err != nil {
+			row.Errors.Add("date", err.Error())
+		}
+		day = d
+	}
+	t.StartTime = day
+	if v := m.Column(rec, "Start"); v != "" {
+		s, err := parseImportTime(v, day)
+		if err != nil {
+			row.Errors.Add("start", err.Error())
+		}
+		t.StartTime = s
+	}
+	if v := m.Column(rec, "End"); v != "" {
+		e, err := parseImportTime(v, t.StartTime)
+		if err != nil {
+			row.Errors.Add("end", err.Error())
+		}
+		t.EndTime = e
+	}
+	if t.StartTime.IsZero() {
+		row.Errors.Add("date", "A date or start time is required.")
+	}
+
+	if v := m.Column(rec, "Duration"); v != "" {
+		d, err := ParseDuration(v)
+		if err != nil {
+			row.Errors.Add("duration", err.Error())
+		}
+		t.Duration = d
+	} else if !t.StartTime.IsZero() && !t.EndTime.IsZero() {
+		t.Duration = DurationBetween(t.StartTime, t.EndTime)
+	}
+	if t.EndTime.IsZero() && !t.StartTime.IsZero() {
+		t.EndTime = t.StartTime.Add(time.Duration(t.Duration) * time.Minute)
+	}
+
+	if v := m.Column(rec, "Rate"); v != "" {
+		// Rates are whole dollars, so "47.00" is read but "47.50" is not.
+		r, err := strconv.ParseFloat(strings.TrimPrefix(v, "$"), 64)
+		if err != nil {
+			row.Errors.Add("rate", fmt.Sprintf("cannot read %q as a rate", v))
+		} else if r != math.Trunc(r) {
+			row.Errors.Add("rate", fmt.Sprintf("rate %q must be a whole number of dollars", v))
+		}
+		t.Rate = int(r)
+	}
+}
+
+// SaveImport creates a task for every row. Callers must check HasErrors
+// first and run this inside a transaction so the batch is all or nothing.
+func SaveImport(tx *pop.Connection, rows ImportRows) error {
+	saved := Tasks{}
+	for i := range rows {
+		t := rows[i].Task
+		t.Contract = nil
+		if err := tx.Create(&t); err != nil {
+			return errors.Wrapf(err, "line %d", rows[i].Line)
+		}
+		saved = append(saved, t)
+	}
+	return CheckCapAlertsFor(tx, saved)
+}
@@ -0,0 +1,457 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// External import sources.
+const (
+	SourceToggl   = "toggl"
+	SourceHarvest = "harvest"
+)
+
+// ExternalEntry is a time entry read from another time tracker's export.
+type ExternalEntry struct {
+	ExternalID  string
+	Client      string
+	Project     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Duration    int
+	Rate        int
+}
+
+// BossName is the boss an entry is filed under: the client, or the project
+// when the entry has no client.
+func (e ExternalEntry) BossName() string {
+	if e.Client != "" {
+		return e.Client
+	}
+	if e.Project != "" {
+		return e.Project
+	}
+	return "Unassigned"
+}
+
+// TaskDescription keeps the project name when it is not already the boss.
+func (e ExternalEntry) TaskDescription() string {
+	if e.Project == "" || e.Project == e.BossName() {
+		return e.Description
+	}
+	if e.Description == "" {
+		return e.Project
+	}
+	return e.Project + ": " + e.Description
+}
+
+// ParseExternal reads a Toggl or Harvest export. JSON and CSV files are
+// told apart by their first character.
+func ParseExternal(source string, r io.Reader) ([]ExternalEntry, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	isJSON := len(bytes.TrimSpace(b)) > 0 && strings.ContainsRune("[{", rune(bytes.TrimSpace(b)[0]))
+
+	switch {
+	case source == SourceToggl && isJSON:
+		return ParseTogglJSON(bytes.NewReader(b))
+	case source == SourceToggl:
+		return ParseTogglCSV(bytes.NewReader(b))
+	case source == SourceHarvest && isJSON:
+		return ParseHarvestJSON(bytes.NewReader(b))
+	case source == SourceHarvest:
+		return ParseHarvestCSV(bytes.NewReader(b))
+	}
+	return nil, errors.Errorf("unknown import source %q", source)
+}
+
+// csvColumns indexes a header row by lower-cased column name.
+type csvColumns map[string]int
+
+func newCSVColumns(headers []string) csvColumns {
+	cols := csvColumns{}
+	for i, h := range headers {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	return cols
+}
+
+// get returns the first of names present in record.
+func (cols csvColumns) get(record []string, names ...string) string {
+	for _, n := range names {
+		if i, ok := cols[n]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+	}
+	return ""
+}
+
+// hashID builds a stable identifier for exports that have no entry IDs.
+func hashID(source string, fields ...string) string {
+	h := sha1.New()
+	for _, f := range fields {
+		io.WriteString(h, f)
+		h.Write([]byte{0})
+	}
+	return source + ":" + hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// rateFromAmount derives an hourly rate from a billed amount.
+func rateFromAmount(amount string, minutes int) int {
+	a, err := strconv.ParseFloat(strings.TrimPrefix(amount, "$"), 64)
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return int(a*60/float64(minutes) + 0.5)
+}
+
+// ParseTogglCSV reads a Toggl "detailed report" CSV export.
+func ParseTogglCSV(r io.Reader) ([]ExternalEntry, error) {
+	headers, records, err := ReadCSV(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	cols := newCSVColumns(headers)
+
+	entries := []ExternalEntry{}
+	for i, rec := range records {
+		e := ExternalEntry{
+			Client:      cols.get(rec, "client"),
+			Project:     cols.get(rec, "project"),
+			Description: cols.get(rec, "description"),
+		}
+		startDate, startTime := cols.get(rec, "start date"), cols.get(rec, "start time")
+		e.Start, err = time.ParseInLocation("2006-01-02 15:04:05", startDate+" "+startTime, time.Local)
+		if err != nil {
+			return nil, errors.Errorf("line %d: cannot read start %q", i+2, startDate+" "+startTime)
+		}
+		endDate, endTime := cols.get(rec, "end date"), cols.get(rec, "end time")
+		e.End, err = time.ParseInLocation("2006-01-02 15:04:05", endDate+" "+endTime, time.Local)
+		if err != nil {
+			return nil, errors.Errorf("line %d: cannot read end %q", i+2, endDate+" "+endTime)
+		}
+		e.Duration, err = ParseDuration(cols.get(rec, "duration"))
+		if err != nil {
+			e.Duration = DurationBetween(e.Start, e.End)
+		}
+		for _, name := range headers {
+			if strings.HasPrefix(strings.ToLower(name), "amount") {
+				e.Rate = rateFromAmount(cols.get(rec, strings.ToLower(strings.TrimSpace(name))), e.Duration)
+			}
+		}
+		e.ExternalID = hashID(SourceToggl, cols.get(rec, "email"), e.Client, e.Project, e.Description, startDate, startTime, endDate, endTime)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+type togglEntry struct {
+	ID          int64     `json:"id"`
+	Description string    `json:"description"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Dur         int64     `json:"dur"`
+	Client      string    `json:"client"`
+	Project     string    `json:"project"`
+	Billable    float64   `json:"billable"`
+}
+
+// ParseTogglJSON reads a Toggl detailed report in JSON, either the full
+// report object with a "data" list or the bare list of entries.
+func ParseTogglJSON(r io.Reader) ([]ExternalEntry, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	raw := []togglEntry{}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		err = json.Unmarshal(b, &raw)
+	} else {
+		report := struct {
+			Data []togglEntry `json:"data"`
+		}{}
+		err = json.Unmarshal(b, &report)
+		raw = report.Data
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read Toggl JSON")
+	}
+
+	entries := []ExternalEntry{}
+	for _, t := range raw {
+		e := ExternalEntry{
+			ExternalID:  fmt.Sprintf("%s:%d", SourceToggl, t.ID),
+			Client:      t.Client,
+			Project:     t.Project,
+			Description: t.Description,
+			Start:       t.Start.Local(),
+			End:         t.End.Local(),
+			// Toggl reports durations in milliseconds.
+			Duration: int((t.Dur + 30000) / 60000),
+		}
+		if t.Billable > 0 && e.Duration > 0 {
+			e.Rate = int(t.Billable*60/float64(e.Duration) + 0.5)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// harvestStart combines a spent date with an optional "3:04pm" start time.
+func harvestStart(day time.Time, clock string) time.Time {
+	clock = strings.ToLower(strings.ReplaceAll(clock, " ", ""))
+	for _, l := range []string{"3:04pm", "15:04"} {
+		if t, err := time.Parse(l, clock); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+		}
+	}
+	return day
+}
+
+// ParseHarvestCSV reads a Harvest "detailed time" CSV export.
+func ParseHarvestCSV(r io.Reader) ([]ExternalEntry, error) {
+	headers, records, err := ReadCSV(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	cols := newCSVColumns(headers)
+
+	entries := []ExternalEntry{}
+	seen := map[string]int{}
+	for i, rec := range records {
+		e := ExternalEntry{
+			Client:      cols.get(rec, "client"),
+			Project:     cols.get(rec, "project"),
+			Description: cols.get(rec, "notes"),
+		}
+		date := cols.get(rec, "date")
+		day, err := parseImportTime(date, time.Time{})
+		if err != nil {
+			return nil, errors.Errorf("line %d: cannot read date %q", i+2, date)
+		}
+		hours, err := strconv.ParseFloat(cols.get(rec, "hours"), 64)
+		if err != nil {
+			return nil, errors.Errorf("line %d: cannot read hours %q", i+2, cols.get(rec, "hours"))
+		}
+		e.Duration = DurationFromHours(hours)
+		e.Start = day
+		e.End = day.Add(time.Duration(e.Duration) * time.Minute)
+		if rate, err := strconv.ParseFloat(cols.get(rec, "billable rate"), 64); err == nil {
+			e.Rate = int(rate + 0.5)
+		}
+		e.ExternalID = hashID(SourceHarvest, cols.get(rec, "first name"), cols.get(rec, "last name"), date, e.Client, e.Project, cols.get(rec, "task"), e.Description, cols.get(rec, "hours"))
+		// The export has no entry ids, so identical entries on the same day
+		// are told apart by how many came before them in the file.
+		seen[e.ExternalID]++
+		if n := seen[e.ExternalID]; n > 1 {
+			e.ExternalID = fmt.Sprintf("%s:%d", e.ExternalID, n)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+type harvestEntry struct {
+	ID           int64   `json:"id"`
+	SpentDate    string  `json:"spent_date"`
+	Hours        float64 `json:"hours"`
+	Notes        string  `json:"notes"`
+	StartedTime  string  `json:"started_time"`
+	BillableRate float64 `json:"billable_rate"`
+	Client       struct {
+		Name string `json:"name"`
+	} `json:"client"`
+	Project struct {
+		Name string `json:"name"`
+	} `json:"project"`
+}
+
+// ParseHarvestJSON reads Harvest v2 time entries, either the API response
+// object with a "time_entries" list or the bare list.
+func ParseHarvestJSON(r io.Reader) ([]ExternalEntry, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	raw := []harvestEntry{}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		err = json.Unmarshal(b, &raw)
+	} else {
+		res := struct {
+			TimeEntries []harvestEntry `json:"time_entries"`
+		}{}
+		err = json.Unmarshal(b, &res)
+		raw = res.TimeEntries
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read Harvest JSON")
+	}
+
+	entries := []ExternalEntry{}
+	for _, h := range raw {
+		day, err := time.ParseInLocation("2006-01-02", h.SpentDate, time.Local)
+		if err != nil {
+			return nil, errors.Errorf("entry %d: cannot read date %q", h.ID, h.SpentDate)
+		}
+		e := ExternalEntry{
+			ExternalID:  fmt.Sprintf("%s:%d", SourceHarvest, h.ID),
+			Client:      h.Client.Name,
+			Project:     h.Project.Name,
+			Description: h.Notes,
+			Duration:    DurationFromHours(h.Hours),
+			Rate:        int(h.BillableRate + 0.5),
+		}
+		e.Start = harvestStart(day, h.StartedTime)
+		e.End = e.Start.Add(time.Duration(e.Duration) * time.Minute)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ExternalImportResult counts what an import changed. Failed describes
+// the entries that could not be saved.
+type ExternalImportResult struct {
+	Created          int
+	Skipped          int
+	BossesCreated    int
+	ContractsCreated int
+	Failed           []string
+}
+
+func (r ExternalImportResult) String() string {
+	s := fmt.Sprintf("%d tasks imported, %d already present, %d bosses and %d contracts created.",
+		r.Created, r.Skipped, r.BossesCreated, r.ContractsCreated)
+	if len(r.Failed) > 0 {
+		s += fmt.Sprintf(" %d could not be imported.", len(r.Failed))
+	}
+	return s
+}
+
+// fail records an entry that could not be saved and why.
+func (r *ExternalImportResult) fail(e ExternalEntry, reason string) {
+	r.Failed = append(r.Failed, fmt.Sprintf("%s, %s: %s", e.BossName(), e.Start.Format("Jan 2, 2006"), reason))
+}
+
+// ImportExternal saves entries as tasks for user, creating any missing
+// bosses and contracts. Each entry goes on the first of the boss's
+// contracts that is not archived and covers its date. Entries already
+// imported are skipped, so running the same import twice changes nothing.
+func ImportExternal(tx *pop.Connection, user *User, entries []ExternalEntry) (*ExternalImportResult, error) {
+	res := &ExternalImportResult{}
+	contracts := map[string]Contracts{}
+	created := Tasks{}
+
+	for _, e := range entries {
+		exists, err := tx.Where("external_id = ? AND contract_id IN (SELECT id FROM contracts WHERE user_id = ?)", e.ExternalID, user.ID).Exists(&Task{})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if exists {
+			res.Skipped++
+			continue
+		}
+
+		key := strings.ToLower(e.BossName())
+		cs, ok := contracts[key]
+		if !ok {
+			cs, err = bossContracts(tx, user, e, res)
+			if err != nil {
+				return nil, err
+			}
+			contracts[key] = cs
+		}
+		contract := activeContractOn(cs, e.Start)
+		if contract == nil {
+			res.fail(e, "No active contract covers this date.")
+			continue
+		}
+
+		rate := e.Rate
+		if rate == 0 || !contract.IsHourly() {
+			rate = contract.TaskRate()
+		}
+		task := &Task{
+			Rate:        rate,
+			Description: e.TaskDescription(),
+			StartTime:   e.Start,
+			EndTime:     e.End,
+			Duration:    e.Duration,
+			ContractID:  contract.ID,
+			ExternalID:  nulls.NewString(e.ExternalID),
+		}
+		verrs, err := tx.ValidateAndCreate(task)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if verrs.HasAny() {
+			res.fail(e, strings.ReplaceAll(verrs.Error(), "\n", " "))
+			continue
+		}
+		res.Created++
+		created = append(created, *task)
+	}
+	if err := CheckCapAlertsFor(tx, created); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+// bossContracts returns the user's contracts with the entry's boss, the
+// unnamed one first and then the oldest. The boss and a contract are
+// created when the user has none yet.
+func bossContracts(tx *pop.Connection, user *User, e ExternalEntry, res *ExternalImportResult) (Contracts, error) {
+	boss := &Boss{}
+	q := tx.Scope(BossesVisibleTo(user.ID)).Where("LOWER(name) = ?", strings.ToLower(e.BossName()))
+	err := q.First(boss)
+	if err != nil {
+		if errors.Cause(err) != sql.ErrNoRows {
+			return nil, errors.WithStack(err)
+		}
+		boss = &Boss{Name: e.BossName(), UserID: nulls.NewUUID(user.ID)}
+		if err := tx.Create(boss); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		res.BossesCreated++
+	}
+
+	cs := Contracts{}
+	err = tx.Where("user_id = ? AND boss_id = ?", user.ID, boss.ID).Order("name asc, id asc").All(&cs)
+	if err != nil || len(cs) > 0 {
+		return cs, errors.WithStack(err)
+	}
+	contract := Contract{UserID: user.ID, BossID: boss.ID, Rate: e.Rate}
+	if err := tx.Create(&contract); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	res.ContractsCreated++
+	return Contracts{contract}, nil
+}
+
+// activeContractOn picks the first of the contracts that is not archived
+// and covers t, or nil.
+func activeContractOn(cs Contracts, t time.Time) *Contract {
+	for i := range cs {
+		if !cs[i].IsArchived() && cs[i].Covers(t) {
+			return &cs[i]
+		}
+	}
+	return nil
+}
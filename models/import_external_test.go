@@ -0,0 +1,189 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+const togglCSV = `User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags,Amount (USD)
+Jo,jo@example.com,ACME,Website,,Fix header,Yes,2021-03-01,09:00:00,2021-03-01,10:30:00,01:30:00,,75.00
+Jo,jo@example.com,,Internal,,Planning,No,2021-03-02,14:00:00,2021-03-02,14:45:00,00:45:00,,
+`
+
+const togglJSON = `{"total_count": 1, "data": [
+  {"id": 1234, "description": "Fix header", "start": "2021-03-01T09:00:00+00:00", "end": "2021-03-01T10:30:00+00:00",
+   "dur": 5400000, "client": "ACME", "project": "Website", "billable": 75.0}
+]}`
+
+const harvestCSV = `Date,Client,Project,Project Code,Task,Notes,Hours,Hours Rounded,Billable?,Invoiced?,Approved?,First Name,Last Name,Roles,Employee?,Billable Rate,Billable Amount,Cost Rate,Cost Amount,Currency,External Reference URL
+2021-03-01,ACME,ACME,,Design,Wireframes,1.5,1.5,Yes,No,No,Jo,Doe,,Yes,60.0,90.0,0,0,US Dollar - USD,
+`
+
+const harvestJSON = `{"time_entries": [
+  {"id": 636709355, "spent_date": "2021-03-01", "hours": 2.0, "notes": "Review", "started_time": "3:00pm",
+   "billable_rate": 100.0, "client": {"id": 1, "name": "ACME"}, "project": {"id": 2, "name": "Website"}}
+]}`
+
+func (ms *ModelSuite) Test_ParseTogglCSV() {
+	entries, err := ParseExternal(SourceToggl, strings.NewReader(togglCSV))
+	ms.NoError(err)
+	ms.Len(entries, 2)
+
+	e := entries[0]
+	ms.Equal("ACME", e.BossName())
+	ms.Equal("Website: Fix header", e.TaskDescription())
+	ms.Equal(90, e.Duration)
+	ms.Equal(50, e.Rate)
+	ms.Equal(time.Date(2021, 3, 1, 9, 0, 0, 0, time.Local), e.Start)
+	ms.True(strings.HasPrefix(e.ExternalID, "toggl:"))
+
+	// No client: the project becomes the boss.
+	ms.Equal("Internal", entries[1].BossName())
+	ms.Equal("Planning", entries[1].TaskDescription())
+
+	again, err := ParseTogglCSV(strings.NewReader(togglCSV))
+	ms.NoError(err)
+	ms.Equal(e.ExternalID, again[0].ExternalID)
+}
+
+func (ms *ModelSuite) Test_ParseTogglJSON() {
+	entries, err := ParseExternal(SourceToggl, strings.NewReader(togglJSON))
+	ms.NoError(err)
+	ms.Len(entries, 1)
+	ms.Equal("toggl:1234", entries[0].ExternalID)
+	ms.Equal(90, entries[0].Duration)
+	ms.Equal(50, entries[0].Rate)
+}
+
+func (ms *ModelSuite) Test_ParseHarvestCSV() {
+	entries, err := ParseExternal(SourceHarvest, strings.NewReader(harvestCSV))
+	ms.NoError(err)
+	ms.Len(entries, 1)
+	ms.Equal("ACME", entries[0].BossName())
+	ms.Equal("Wireframes", entries[0].TaskDescription())
+	ms.Equal(90, entries[0].Duration)
+	ms.Equal(60, entries[0].Rate)
+}
+
+func (ms *ModelSuite) Test_ParseHarvestJSON() {
+	entries, err := ParseExternal(SourceHarvest, strings.NewReader(harvestJSON))
+	ms.NoError(err)
+	ms.Len(entries, 1)
+	ms.Equal("harvest:636709355", entries[0].ExternalID)
+	ms.Equal(120, entries[0].Duration)
+	ms.Equal(time.Date(2021, 3, 1, 15, 0, 0, 0, time.Local), entries[0].Start)
+}
+
+func (ms *ModelSuite) Test_ImportExternal_Twice() {
+	user := &User{Email: "toggl@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	entries, err := ParseTogglCSV(strings.NewReader(togglCSV))
+	ms.NoError(err)
+
+	res, err := ImportExternal(DB, user, entries)
+	ms.NoError(err)
+	ms.Equal(2, res.Created)
+	ms.Equal(2, res.BossesCreated)
+	ms.Equal(2, res.ContractsCreated)
+
+	res, err = ImportExternal(DB, user, entries)
+	ms.NoError(err)
+	ms.Equal(0, res.Created)
+	ms.Equal(2, res.Skipped)
+	ms.Equal(0, res.BossesCreated)
+}
+
+func (ms *ModelSuite) Test_ParseHarvestCSV_Identical_Entries() {
+	row := "2021-03-01,ACME,ACME,,Design,Standup,0.25,0.25,Yes,No,No,Jo,Doe,,Yes,60.0,15.0,0,0,US Dollar - USD,\n"
+	header := strings.SplitN(harvestCSV, "\n", 2)[0] + "\n"
+	entries, err := ParseHarvestCSV(strings.NewReader(header + row + row))
+	ms.NoError(err)
+	ms.Len(entries, 2)
+	ms.NotEqual(entries[0].ExternalID, entries[1].ExternalID)
+
+	again, err := ParseHarvestCSV(strings.NewReader(header + row + row))
+	ms.NoError(err)
+	ms.Equal(entries[1].ExternalID, again[1].ExternalID)
+}
+
+func (ms *ModelSuite) Test_ImportExternal_Invalid_Entry() {
+	user := &User{Email: "harvest@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.Local)
+	entries := []ExternalEntry{
+		{ExternalID: "harvest:1", Client: "ACME", Description: "Review", Start: day, End: day, Duration: 60},
+		{ExternalID: "harvest:2", Client: "ACME", Description: "Nothing", Start: day, End: day, Duration: 0},
+	}
+	res, err := ImportExternal(DB, user, entries)
+	ms.NoError(err)
+	ms.Equal(1, res.Created)
+	ms.Len(res.Failed, 1)
+	ms.Contains(res.Failed[0], "ACME, Mar 1, 2021")
+}
+
+func (ms *ModelSuite) Test_ImportExternal_Active_Contracts() {
+	user := &User{Email: "window@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	boss := &Boss{Name: "ACME", UserID: nulls.NewUUID(user.ID)}
+	ms.NoError(DB.Create(boss))
+	archived := &Contract{UserID: user.ID, BossID: boss.ID, Rate: 40, ArchivedAt: nulls.NewTime(time.Now())}
+	ms.NoError(DB.Create(archived))
+	march := &Contract{UserID: user.ID, BossID: boss.ID, Name: "March", Rate: 60,
+		StartsOn: nulls.NewTime(time.Date(2021, 3, 1, 0, 0, 0, 0, time.Local)),
+		EndsOn:   nulls.NewTime(time.Date(2021, 3, 31, 0, 0, 0, 0, time.Local))}
+	ms.NoError(DB.Create(march))
+
+	inMarch := time.Date(2021, 3, 10, 9, 0, 0, 0, time.Local)
+	inApril := time.Date(2021, 4, 10, 9, 0, 0, 0, time.Local)
+	entries := []ExternalEntry{
+		{ExternalID: "toggl:1", Client: "ACME", Start: inMarch, End: inMarch.Add(time.Hour), Duration: 60},
+		{ExternalID: "toggl:2", Client: "ACME", Start: inApril, End: inApril.Add(time.Hour), Duration: 60},
+	}
+	res, err := ImportExternal(DB, user, entries)
+	ms.NoError(err)
+	ms.Equal(1, res.Created)
+	ms.Equal(0, res.ContractsCreated)
+	ms.Len(res.Failed, 1)
+
+	count, err := DB.Where("contract_id = ?", march.ID).Count(&Task{})
+	ms.NoError(err)
+	ms.Equal(1, count)
+}
+
+func (ms *ModelSuite) Test_ImportExternal_Cap_Alerts() {
+	user := &User{Email: "capped@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	boss := &Boss{Name: "ACME", UserID: nulls.NewUUID(user.ID)}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{UserID: user.ID, BossID: boss.ID, Rate: 60}
+	ms.NoError(DB.Create(contract))
+	verrs, err := contract.SetCap(DB, CapHours, 600, CapMonth)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	inMarch := time.Date(2021, 3, 10, 9, 0, 0, 0, time.Local)
+	inApril := time.Date(2021, 4, 10, 9, 0, 0, 0, time.Local)
+	entries := []ExternalEntry{
+		{ExternalID: "toggl:1", Client: "ACME", Start: inMarch, End: inMarch, Duration: 480},
+		{ExternalID: "toggl:2", Client: "ACME", Start: inApril, End: inApril, Duration: 360},
+		{ExternalID: "toggl:3", Client: "ACME", Start: inApril, End: inApril, Duration: 300},
+	}
+	res, err := ImportExternal(DB, user, entries)
+	ms.NoError(err)
+	ms.Equal(3, res.Created)
+
+	// March reached 80%; April went past both thresholds.
+	list, err := LoadNotifications(DB, user.ID)
+	ms.NoError(err)
+	ms.Len(list, 3)
+}
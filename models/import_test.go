@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+func (ms *ModelSuite) Test_GuessImportMapping() {
+	m := GuessImportMapping([]string{"Date", "Client", "Notes", "Minutes"})
+	ms.Equal(0, m.Index("Date"))
+	ms.Equal(1, m.Index("Boss"))
+	ms.Equal(2, m.Index("Description"))
+	ms.Equal(3, m.Index("Duration"))
+	ms.Equal(-1, m.Index("Rate"))
+}
+
+func (ms *ModelSuite) Test_BuildImport() {
+	data := "date,start,end,boss,description\n" +
+		"2021-03-01,09:00,10:30,acme,Planning\n" +
+		"2021-03-02,,,Unknown,Nothing\n"
+	headers, records, err := ReadCSV(strings.NewReader(data))
+	ms.NoError(err)
+
+	contracts := []Contract{{ID: 7, Rate: 50, Boss: &Boss{Name: "ACME"}}}
+	rows, err := BuildImport(DB, records, GuessImportMapping(headers), ImportTarget{Contracts: contracts})
+	ms.NoError(err)
+	ms.Len(rows, 2)
+
+	ok := rows[0]
+	ms.False(ok.Errors.HasAny())
+	ms.Equal(7, ok.Task.ContractID)
+	ms.Equal(50, ok.Task.Rate)
+	ms.Equal(90, ok.Task.Duration)
+	ms.Equal(time.Date(2021, 3, 1, 9, 0, 0, 0, time.Local), ok.Task.StartTime)
+
+	bad := rows[1]
+	ms.Equal(3, bad.Line)
+	ms.NotEmpty(bad.Errors.Get("contract"))
+	ms.NotEmpty(bad.Errors.Get("duration"))
+	ms.True(rows.HasErrors())
+	ms.Equal(1, rows.ErrorCount())
+}
+
+func (ms *ModelSuite) Test_ImportTarget_Resolve_Named() {
+	acme := &Boss{Name: "ACME"}
+	target := ImportTarget{Contracts: []Contract{{ID: 1, Boss: acme}, {ID: 2, Name: "Website", Boss: acme}}}
+
+	_, err := target.resolve("acme")
+	ms.Error(err)
+	c, err := target.resolve("ACME - website")
+	ms.NoError(err)
+	ms.Equal(2, c.ID)
+
+	target.Contracts = target.Contracts[1:]
+	c, err = target.resolve("ACME")
+	ms.NoError(err)
+	ms.Equal(2, c.ID)
+}
+
+func (ms *ModelSuite) Test_BuildImport_Whole_Dollar_Rates() {
+	data := "date,duration,boss,rate\n" +
+		"2021-03-01,60,acme,$47.00\n" +
+		"2021-03-02,60,acme,47.50\n"
+	headers, records, err := ReadCSV(strings.NewReader(data))
+	ms.NoError(err)
+
+	contracts := []Contract{{ID: 7, Rate: 50, Boss: &Boss{Name: "ACME"}}}
+	rows, err := BuildImport(DB, records, GuessImportMapping(headers), ImportTarget{Contracts: contracts})
+	ms.NoError(err)
+	ms.Len(rows, 2)
+
+	ms.False(rows[0].Errors.HasAny())
+	ms.Equal(47, rows[0].Task.Rate)
+	ms.NotEmpty(rows[1].Errors.Get("rate"))
+}
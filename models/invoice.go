@@ -0,0 +1,306 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrNothingToInvoice is returned when a contract has no approved, unbilled
+// tasks and nothing else to bill.
+var ErrNothingToInvoice = errors.New("there is nothing approved and unbilled on this contract")
+
+// Invoice bills a contract's tasks. Tasks point at the invoice they are on.
+type Invoice struct {
+	ID           int          `json:"id" db:"id"`
+	Number       string       `json:"number" db:"number"`
+	Status       string       `json:"status" db:"status"`
+	UserID       uuid.UUID    `json:"-" db:"user_id"`
+	User         *User        `json:"user,omitempty" belongs_to:"user"`
+	ContractID   int          `json:"-" db:"contract_id"`
+	Contract     *Contract    `json:"contract,omitempty" belongs_to:"contract"`
+	IssuedOn     time.Time    `json:"issued_on" db:"issued_on"`
+	DueOn        time.Time    `json:"due_on" db:"due_on"`
+	PaymentTerms int          `json:"payment_terms" db:"payment_terms"`
+	TaxRounding  string       `json:"tax_rounding" db:"tax_rounding"`
+	Taxes        InvoiceTaxes `json:"taxes,omitempty" has_many:"invoice_taxes" order_by:"id asc"`
+	Lines        InvoiceLines `json:"lines,omitempty" has_many:"invoice_lines" order_by:"id asc"`
+	Notes        nulls.String `json:"notes" db:"notes"`
+	Tasks        Tasks        `json:"tasks,omitempty" has_many:"tasks"`
+	Payments     Payments     `json:"payments,omitempty" has_many:"payments" order_by:"paid_on asc"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (i Invoice) String() string {
+	ji, _ := json.Marshal(i)
+	return string(ji)
+}
+
+// Invoices is not required by pop and may be deleted
+type Invoices []Invoice
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (i *Invoice) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	errs := validate.Validate(
+		&validators.StringIsPresent{Field: i.Number, Name: "Number"},
+		&validators.IntIsPresent{Field: i.ContractID, Name: "ContractID"},
+		&validators.TimeIsPresent{Field: i.IssuedOn, Name: "IssuedOn"},
+		&validators.StringInclusion{Field: i.Status, Name: "Status", List: InvoiceStatuses},
+	)
+	if i.PaymentTerms < 0 {
+		errs.Add("payment_terms", "Payment terms cannot be negative.")
+	}
+	return errs, nil
+}
+
+// Subtotal is the sum of the task and line amounts in cents.
+func (i *Invoice) Subtotal() int {
+	total := 0
+	for _, a := range i.amounts() {
+		total += a
+	}
+	return total
+}
+
+// amounts lists what each task and line on the invoice charges.
+func (i *Invoice) amounts() []int {
+	amounts := make([]int, 0, len(i.Tasks)+len(i.Lines))
+	for _, t := range i.Tasks {
+		amounts = append(amounts, Earnings(t.Rate, t.Duration))
+	}
+	for _, l := range i.Lines {
+		amounts = append(amounts, l.Amount)
+	}
+	return amounts
+}
+
+// TaxLines works out each of the invoice's taxes.
+func (i *Invoice) TaxLines() []TaxLine {
+	return computeTaxes(i.amounts(), i.Taxes, i.TaxRounding)
+}
+
+// Tax is all tax on the invoice in cents, included or added.
+func (i *Invoice) Tax() int {
+	total := 0
+	for _, l := range i.TaxLines() {
+		total += l.Amount
+	}
+	return total
+}
+
+// IncludedTax is the part of the subtotal that is inclusive tax.
+func (i *Invoice) IncludedTax() int {
+	total := 0
+	for _, l := range i.TaxLines() {
+		if l.Inclusive {
+			total += l.Amount
+		}
+	}
+	return total
+}
+
+// Net is the subtotal without any tax.
+func (i *Invoice) Net() int {
+	return i.Subtotal() - i.IncludedTax()
+}
+
+// Total is the amount due in cents: the subtotal plus exclusive taxes.
+func (i *Invoice) Total() int {
+	return i.Subtotal() + i.Tax() - i.IncludedTax()
+}
+
+// Terms describes the payment terms, e.g. "Net 30".
+func (i *Invoice) Terms() string {
+	if i.PaymentTerms == 0 {
+		return "Due on receipt"
+	}
+	return fmt.Sprintf("Net %d", i.PaymentTerms)
+}
+
+// FormatMoney formats cents as dollars, e.g. "$1,234.50".
+func FormatMoney(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	dollars := fmt.Sprintf("%d", cents/100)
+	for i := len(dollars) - 3; i > 0; i -= 3 {
+		dollars = dollars[:i] + "," + dollars[i:]
+	}
+	return fmt.Sprintf("%s$%s.%02d", sign, dollars, cents%100)
+}
+
+// FormatPercent formats hundredths of a percent, e.g. 825 as "8.25%".
+func FormatPercent(bp int) string {
+	s := fmt.Sprintf("%d.%02d", bp/100, bp%100)
+	for s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	return s + "%"
+}
+
+// ParsePercent reads a percentage such as "8.25" as hundredths of a percent.
+func ParsePercent(s string) (int, error) {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || f < 0 {
+		return 0, errors.Errorf("invalid percentage %q", s)
+	}
+	return int(math.Round(f * 100)), nil
+}
+
+// nextInvoiceNumber numbers a user's invoices INV-0001, INV-0002, ... from
+// a counter on the user, so a number is never handed out twice. Bumping the
+// counter locks the user's row until the transaction ends, which keeps
+// invoices created at the same time apart.
+func nextInvoiceNumber(tx *pop.Connection, userID uuid.UUID) (string, error) {
+	err := tx.RawQuery("UPDATE users SET invoice_sequence = invoice_sequence + 1 WHERE id = ?", userID).Exec()
+	if err != nil {
+		return "", err
+	}
+	seq := struct {
+		N int `db:"invoice_sequence"`
+	}{}
+	if err := tx.RawQuery("SELECT invoice_sequence FROM users WHERE id = ?", userID).First(&seq); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("INV-%04d", seq.N), nil
+}
+
+// CreateInvoice bills every approved, unbilled task on the contract and its
+// unbilled, billable expenses. A fixed-price contract also bills its
+// completed milestones, and a retainer the months that ended before
+// issued. The invoice is dated on issued and falls due after the payment
+// terms. The contract's tax rates and the user's rounding mode are copied
+// onto the invoice.
+func CreateInvoice(tx *pop.Connection, contract *Contract, issued time.Time, terms int) (*Invoice, *validate.Errors, error) {
+	billable := "tasks.contract_id = ? AND tasks.invoice_id IS NULL AND " + approvedTasks
+	args := []interface{}{contract.ID}
+	if contract.IsRetainer() {
+		// Retainer time is billed with its month once the month has ended.
+		// Time logged before the contract became a retainer is billed by
+		// the hour straight away.
+		until := monthStart(issued)
+		if since := contract.retainerSince(); since.After(until) {
+			until = since
+		}
+		billable += " AND tasks.start_time < ?"
+		args = append(args, until)
+	}
+	unbilled, err := tx.Where(billable, args...).Count(&Task{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !contract.IsHourly() {
+		// Only hourly time is charged by the task: what was logged before
+		// the contract changed kind and still carries its rate.
+		unbilled, err = tx.Where(billable+" AND tasks.rate > 0", args...).Count(&Task{})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	lines, milestones, err := contract.pendingCharges(tx, monthStart(issued))
+	if err != nil {
+		return nil, nil, err
+	}
+	expenses, err := contract.unbilledExpenses(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines = append(lines, expenseLines(expenses)...)
+	if unbilled == 0 && len(lines) == 0 {
+		return nil, nil, ErrNothingToInvoice
+	}
+
+	number, err := nextInvoiceNumber(tx, contract.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	user := &User{}
+	if err := tx.Find(user, contract.UserID); err != nil {
+		return nil, nil, err
+	}
+	rates, err := contract.GetTaxRates(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	day := time.Date(issued.Year(), issued.Month(), issued.Day(), 0, 0, 0, 0, time.UTC)
+	inv := &Invoice{
+		Number:       number,
+		Status:       InvoiceDraft,
+		UserID:       contract.UserID,
+		ContractID:   contract.ID,
+		IssuedOn:     day,
+		DueOn:        day.AddDate(0, 0, terms),
+		PaymentTerms: terms,
+		TaxRounding:  user.TaxRounding,
+	}
+	verrs, err := tx.ValidateAndCreate(inv)
+	if err != nil || verrs.HasAny() {
+		return inv, verrs, err
+	}
+	for _, r := range rates {
+		t := InvoiceTax{InvoiceID: inv.ID, Name: r.Name, Rate: r.Rate, Inclusive: r.Inclusive}
+		if err := tx.Create(&t); err != nil {
+			return inv, verrs, err
+		}
+		inv.Taxes = append(inv.Taxes, t)
+	}
+	for _, l := range lines {
+		l.InvoiceID = inv.ID
+		if err := tx.Create(&l); err != nil {
+			return inv, verrs, err
+		}
+		inv.Lines = append(inv.Lines, l)
+	}
+	for _, m := range milestones {
+		if err := tx.RawQuery("UPDATE milestones SET invoice_id = ? WHERE id = ?", inv.ID, m.ID).Exec(); err != nil {
+			return inv, verrs, err
+		}
+	}
+	for _, e := range expenses {
+		if err := tx.RawQuery("UPDATE expenses SET invoice_id = ? WHERE id = ?", inv.ID, e.ID).Exec(); err != nil {
+			return inv, verrs, err
+		}
+	}
+
+	err = tx.RawQuery("UPDATE tasks SET invoice_id = ? WHERE "+billable, append([]interface{}{inv.ID}, args...)...).Exec()
+	return inv, verrs, err
+}
+
+// LoadInvoice finds one of the user's invoices with its tasks, contract and
+// boss.
+func LoadInvoice(tx *pop.Connection, userID uuid.UUID, id string) (*Invoice, error) {
+	inv := &Invoice{}
+	err := tx.Where("user_id = ?", userID).Eager("User", "Contract.Boss.Contacts", "Tasks", "Payments", "Taxes", "Lines").Find(inv, id)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(inv.Tasks, func(a, b int) bool {
+		return inv.Tasks[a].StartTime.Before(inv.Tasks[b].StartTime)
+	})
+	return inv, nil
+}
+
+// LoadInvoices returns the user's invoices, newest first.
+func LoadInvoices(tx *pop.Connection, userID uuid.UUID) (Invoices, error) {
+	invoices := Invoices{}
+	err := tx.Where("user_id = ?", userID).Eager("Contract.Boss", "Tasks", "Payments", "Taxes", "Lines").Order("issued_on desc, id desc").All(&invoices)
+	return invoices, err
+}
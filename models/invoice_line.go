@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+// InvoiceLine is a charge on an invoice that is not a task: a completed
+// milestone or a retainer month. Period is the retainer month it bills.
+type InvoiceLine struct {
+	ID          int        `json:"id" db:"id"`
+	InvoiceID   int        `json:"-" db:"invoice_id"`
+	Description string     `json:"description" db:"description"`
+	Amount      int        `json:"amount" db:"amount"`
+	Period      nulls.Time `json:"period" db:"period"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (l InvoiceLine) String() string {
+	jl, _ := json.Marshal(l)
+	return string(jl)
+}
+
+// InvoiceLines is not required by pop and may be deleted
+type InvoiceLines []InvoiceLine
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"buftester/markdown"
+	"buftester/pdf"
+)
+
+// Invoice page layout, in points.
+const (
+	invoiceMargin   = 50.0
+	invoiceLeading  = 14.0
+	invoiceFontSize = 10.0
+	invoiceColHours = 400.0
+	invoiceColRate  = 480.0
+)
+
+// invoiceDate is how dates are printed on invoices.
+const invoiceDate = "January 2, 2006"
+
+// invoiceLayout tracks the write position while the invoice flows down the
+// pages.
+type invoiceLayout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+func (l *invoiceLayout) right() float64 {
+	return l.page.Width() - invoiceMargin
+}
+
+// ensure starts a new page when fewer than h points are left on the current
+// one, repeating the table header if asked to.
+func (l *invoiceLayout) ensure(h float64, header bool) {
+	if l.y+h <= l.page.Height()-invoiceMargin {
+		return
+	}
+	l.page = l.doc.AddPage()
+	l.y = invoiceMargin
+	if header {
+		l.tableHeader()
+	}
+}
+
+func (l *invoiceLayout) tableHeader() {
+	p := l.page
+	p.FillRect(invoiceMargin, l.y, l.right()-invoiceMargin, invoiceLeading+6, 0.9)
+	base := l.y + invoiceLeading
+	p.Text(invoiceMargin+4, base, pdf.HelveticaBold, invoiceFontSize, "Date")
+	p.Text(invoiceMargin+70, base, pdf.HelveticaBold, invoiceFontSize, "Description")
+	p.TextRight(invoiceColHours, base, pdf.HelveticaBold, invoiceFontSize, "Hours")
+	p.TextRight(invoiceColRate, base, pdf.HelveticaBold, invoiceFontSize, "Rate")
+	p.TextRight(l.right()-4, base, pdf.HelveticaBold, invoiceFontSize, "Amount")
+	l.y += invoiceLeading + 12
+}
+
+// block writes a titled group of lines at x and returns the y below it.
+func (l *invoiceLayout) block(x, y float64, title string, lines []string) float64 {
+	l.page.Text(x, y, pdf.HelveticaBold, invoiceFontSize, title)
+	for _, s := range lines {
+		if s == "" {
+			continue
+		}
+		y += invoiceLeading
+		l.page.Text(x, y, pdf.Helvetica, invoiceFontSize, s)
+	}
+	return y + invoiceLeading
+}
+
+// PDF lays the invoice out as a document. The invoice must be loaded with
+// its user, contract, boss, boss contacts, tasks and lines.
+func (i *Invoice) PDF() *pdf.Document {
+	doc := pdf.New(pdf.Letter)
+	doc.SetTitle("Invoice " + i.Number)
+	l := &invoiceLayout{doc: doc, page: doc.AddPage(), y: invoiceMargin + 20}
+	p := l.page
+
+	p.Text(invoiceMargin, l.y, pdf.HelveticaBold, 24, "INVOICE")
+	if i.Status == InvoiceVoid || i.Status == InvoicePaid {
+		p.Text(invoiceMargin+130, l.y, pdf.HelveticaBold, 24, strings.ToUpper(i.StatusLabel()))
+	}
+	p.TextRight(l.right(), l.y-10, pdf.HelveticaBold, 12, i.Number)
+	p.TextRight(l.right(), l.y+6, pdf.Helvetica, invoiceFontSize, "Issued "+i.IssuedOn.Format(invoiceDate))
+	p.TextRight(l.right(), l.y+6+invoiceLeading, pdf.Helvetica, invoiceFontSize, "Due "+i.DueOn.Format(invoiceDate))
+	l.y += 50
+
+	from := []string{}
+	if i.User != nil {
+		from = append(from, i.User.FullName(), i.User.Email)
+	}
+	to := []string{}
+	if i.Contract != nil && i.Contract.Boss != nil {
+		to = i.Contract.Boss.BillingLines()
+	}
+	y1 := l.block(invoiceMargin, l.y, "From", from)
+	y2 := l.block(l.page.Width()/2, l.y, "Bill To", to)
+	l.y = y1
+	if y2 > l.y {
+		l.y = y2
+	}
+	l.y += 10
+
+	l.tableHeader()
+	descWidth := invoiceColHours - 60 - (invoiceMargin + 70)
+	for _, t := range i.Tasks {
+		lines := pdf.Wrap(pdf.Helvetica, invoiceFontSize, markdown.Text(t.Description), descWidth)
+		l.ensure(float64(len(lines))*invoiceLeading, true)
+		p = l.page
+		p.Text(invoiceMargin+4, l.y, pdf.Helvetica, invoiceFontSize, t.StartTime.Format("Jan 2, 2006"))
+		p.TextRight(invoiceColHours, l.y, pdf.Helvetica, invoiceFontSize, fmt.Sprintf("%.2f", float64(t.Duration)/60))
+		p.TextRight(invoiceColRate, l.y, pdf.Helvetica, invoiceFontSize, FormatMoney(t.Rate*100))
+		p.TextRight(l.right()-4, l.y, pdf.Helvetica, invoiceFontSize, FormatMoney(Earnings(t.Rate, t.Duration)))
+		for _, s := range lines {
+			p.Text(invoiceMargin+70, l.y, pdf.Helvetica, invoiceFontSize, s)
+			l.y += invoiceLeading
+		}
+		l.y += 2
+	}
+	for _, line := range i.Lines {
+		lines := pdf.Wrap(pdf.Helvetica, invoiceFontSize, line.Description, invoiceColRate-(invoiceMargin+70))
+		l.ensure(float64(len(lines))*invoiceLeading, true)
+		p = l.page
+		if line.Period.Valid {
+			p.Text(invoiceMargin+4, l.y, pdf.Helvetica, invoiceFontSize, line.Period.Time.Format("Jan 2006"))
+		}
+		p.TextRight(l.right()-4, l.y, pdf.Helvetica, invoiceFontSize, FormatMoney(line.Amount))
+		for _, s := range lines {
+			p.Text(invoiceMargin+70, l.y, pdf.Helvetica, invoiceFontSize, s)
+			l.y += invoiceLeading
+		}
+		l.y += 2
+	}
+
+	taxes := i.TaxLines()
+	l.ensure(float64(6+len(taxes))*invoiceLeading, false)
+	p = l.page
+	p.Line(invoiceColHours-60, l.y-6, l.right(), l.y-6, 0.5)
+	l.y += 8
+	totals := [][2]string{{"Subtotal", FormatMoney(i.Subtotal())}}
+	for _, t := range taxes {
+		totals = append(totals, [2]string{t.Label(), FormatMoney(t.Amount)})
+	}
+	for _, row := range totals {
+		p.TextRight(invoiceColRate, l.y, pdf.Helvetica, invoiceFontSize, row[0])
+		p.TextRight(l.right()-4, l.y, pdf.Helvetica, invoiceFontSize, row[1])
+		l.y += invoiceLeading
+	}
+	p.TextRight(invoiceColRate, l.y+4, pdf.HelveticaBold, 12, "Total")
+	p.TextRight(l.right()-4, l.y+4, pdf.HelveticaBold, 12, FormatMoney(i.Total()))
+	if len(i.Payments) > 0 {
+		l.y += invoiceLeading + 4
+		p.TextRight(invoiceColRate, l.y, pdf.Helvetica, invoiceFontSize, "Paid")
+		p.TextRight(l.right()-4, l.y, pdf.Helvetica, invoiceFontSize, FormatMoney(-i.Paid()))
+		l.y += invoiceLeading
+		p.TextRight(invoiceColRate, l.y, pdf.HelveticaBold, invoiceFontSize, "Balance due")
+		p.TextRight(l.right()-4, l.y, pdf.HelveticaBold, invoiceFontSize, FormatMoney(i.Balance()))
+	}
+	l.y += 3 * invoiceLeading
+
+	terms := fmt.Sprintf("Payment terms: %s. Please pay by %s.", i.Terms(), i.DueOn.Format(invoiceDate))
+	notes := []string{}
+	if i.Notes.Valid && i.Notes.String != "" {
+		notes = pdf.Wrap(pdf.Helvetica, invoiceFontSize, i.Notes.String, l.right()-invoiceMargin)
+	}
+	l.ensure(float64(len(notes)+1)*invoiceLeading, false)
+	l.page.Text(invoiceMargin, l.y, pdf.Helvetica, invoiceFontSize, terms)
+	for _, s := range notes {
+		l.y += invoiceLeading
+		l.page.Text(invoiceMargin, l.y, pdf.Helvetica, invoiceFontSize, s)
+	}
+
+	return doc
+}
+
+// WritePDF renders the invoice as a PDF to w.
+func (i *Invoice) WritePDF(w io.Writer) error {
+	_, err := i.PDF().WriteTo(w)
+	return err
+}
+
+// PDFName is the file name used for downloads and attachments.
+func (i *Invoice) PDFName() string {
+	return i.Number + ".pdf"
+}
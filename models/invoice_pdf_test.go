@@ -0,0 +1,77 @@
+package models
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// sampleInvoice is a fully loaded invoice that needs no database.
+func sampleInvoice() *Invoice {
+	issued := time.Date(2021, 8, 2, 0, 0, 0, 0, time.UTC)
+	tasks := Tasks{}
+	for d := 0; d < 60; d++ {
+		tasks = append(tasks, Task{
+			Rate:        80,
+			Duration:    30 + d,
+			StartTime:   issued.AddDate(0, 0, -60+d),
+			Description: strings.Repeat("Code review and deployment (phase two). ", d%3+1),
+		})
+	}
+	return &Invoice{
+		Number: "INV-0007",
+		User:   &User{FirstName: "Jo", LastName: "Smith", Email: "jo@example.com"},
+		Contract: &Contract{Boss: &Boss{
+			Name:     "ACME Café",
+			Address:  nulls.NewString("12 Rue de la Paix\n75002 Paris\nFrance"),
+			TaxID:    nulls.NewString("FR 12 345678901"),
+			Contacts: BossContacts{{Name: "Ana Lopez", Email: nulls.NewString("billing@acme.example")}},
+		}},
+		IssuedOn:     issued,
+		DueOn:        issued.AddDate(0, 0, 30),
+		PaymentTerms: 30,
+		TaxRounding:  TaxRoundLine,
+		Taxes: InvoiceTaxes{
+			{Name: "VAT", Rate: 2000, Inclusive: true},
+			{Name: "City tax", Rate: 825},
+		},
+		Notes: nulls.NewString("Thank you for your business."),
+		Tasks: tasks,
+	}
+}
+
+func Test_Invoice_PDF_Golden(t *testing.T) {
+	var b bytes.Buffer
+	if err := sampleInvoice().WritePDF(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "invoice.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("output does not match %s, run with -update if the change is intended", golden)
+	}
+}
+
+func Test_Invoice_PDF_Pages(t *testing.T) {
+	doc := sampleInvoice().PDF()
+	if doc.Pages() < 2 {
+		t.Errorf("expected the tasks to flow onto a second page, got %d", doc.Pages())
+	}
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+)
+
+// Invoice statuses. Drafts are sent, then paid through one or more payments.
+// Drafts and unpaid invoices can be voided instead.
+const (
+	InvoiceDraft         = "draft"
+	InvoiceSent          = "sent"
+	InvoicePartiallyPaid = "partially_paid"
+	InvoicePaid          = "paid"
+	InvoiceVoid          = "void"
+)
+
+// InvoiceStatuses lists every status in lifecycle order.
+var InvoiceStatuses = []string{InvoiceDraft, InvoiceSent, InvoicePartiallyPaid, InvoicePaid, InvoiceVoid}
+
+// invoiceTransitions holds the statuses each status may move to.
+var invoiceTransitions = map[string][]string{
+	InvoiceDraft:         {InvoiceSent, InvoiceVoid},
+	InvoiceSent:          {InvoicePartiallyPaid, InvoicePaid, InvoiceVoid},
+	InvoicePartiallyPaid: {InvoicePaid},
+}
+
+// ErrInvalidTransition is returned when an invoice cannot move to a status.
+var ErrInvalidTransition = errors.New("invoice cannot change to that status")
+
+// CanTransition reports whether the invoice may move to status.
+func (i *Invoice) CanTransition(status string) bool {
+	for _, s := range invoiceTransitions[i.Status] {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Invoice) transition(tx *pop.Connection, status string) error {
+	if !i.CanTransition(status) {
+		return ErrInvalidTransition
+	}
+	i.Status = status
+	i.UpdatedAt = time.Now()
+	return tx.UpdateColumns(i, "status", "updated_at")
+}
+
+// StatusLabel is the status for display, e.g. "Partially paid".
+func (i *Invoice) StatusLabel() string {
+	switch i.Status {
+	case InvoiceSent:
+		return "Sent"
+	case InvoicePartiallyPaid:
+		return "Partially paid"
+	case InvoicePaid:
+		return "Paid"
+	case InvoiceVoid:
+		return "Void"
+	}
+	return "Draft"
+}
+
+// IsOpen reports whether the invoice has been sent and is awaiting payment.
+func (i *Invoice) IsOpen() bool {
+	return i.Status == InvoiceSent || i.Status == InvoicePartiallyPaid
+}
+
+// Paid is the amount received so far in cents.
+func (i *Invoice) Paid() int {
+	return i.Payments.Total()
+}
+
+// Balance is the amount still owed in cents.
+func (i *Invoice) Balance() int {
+	if i.Status == InvoiceVoid {
+		return 0
+	}
+	return i.Total() - i.Paid()
+}
+
+// DaysOverdue is the number of whole days since the due date, or 0 when the
+// invoice is not open or not yet due.
+func (i *Invoice) DaysOverdue(now time.Time) int {
+	if !i.IsOpen() {
+		return 0
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	due := time.Date(i.DueOn.Year(), i.DueOn.Month(), i.DueOn.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(today.Sub(due).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// IsOverdue reports whether an open invoice is past its due date.
+func (i *Invoice) IsOverdue(now time.Time) bool {
+	return i.DaysOverdue(now) > 0
+}
+
+// MarkSent moves a draft to sent. Sending again is not an error.
+func (i *Invoice) MarkSent(tx *pop.Connection) error {
+	if i.Status != InvoiceDraft {
+		return nil
+	}
+	return i.transition(tx, InvoiceSent)
+}
+
+// Void cancels an invoice that has no payments. Its tasks, milestones,
+// expenses and retainer months become unbilled again so they can go on a
+// new invoice.
+func (i *Invoice) Void(tx *pop.Connection) error {
+	if len(i.Payments) > 0 {
+		return ErrInvalidTransition
+	}
+	if err := i.transition(tx, InvoiceVoid); err != nil {
+		return err
+	}
+	i.Tasks = Tasks{}
+	if err := tx.RawQuery("UPDATE milestones SET invoice_id = NULL WHERE invoice_id = ?", i.ID).Exec(); err != nil {
+		return err
+	}
+	if err := tx.RawQuery("UPDATE expenses SET invoice_id = NULL WHERE invoice_id = ?", i.ID).Exec(); err != nil {
+		return err
+	}
+	return tx.RawQuery("UPDATE tasks SET invoice_id = NULL WHERE invoice_id = ?", i.ID).Exec()
+}
+
+// RecordPayment adds a payment to a sent invoice and moves it to partially
+// paid or paid. Payments larger than the balance are rejected.
+func (i *Invoice) RecordPayment(tx *pop.Connection, p *Payment) (*validate.Errors, error) {
+	if !i.IsOpen() {
+		return nil, ErrInvalidTransition
+	}
+	p.InvoiceID = i.ID
+	verrs, err := p.Validate(tx)
+	if err != nil {
+		return verrs, err
+	}
+	if p.Amount > i.Balance() {
+		verrs.Add("amount", "Amount is more than the balance due.")
+	}
+	if verrs.HasAny() {
+		return verrs, nil
+	}
+
+	verrs, err = tx.ValidateAndCreate(p)
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+	i.Payments = append(i.Payments, *p)
+
+	status := InvoicePartiallyPaid
+	if i.Balance() == 0 {
+		status = InvoicePaid
+	}
+	if status == i.Status {
+		return verrs, nil
+	}
+	return verrs, i.transition(tx, status)
+}
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+func (ms *ModelSuite) Test_CreateInvoice() {
+	now := time.Now()
+
+	user := &User{Email: "invoice@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID}))
+	ms.NoError(DB.Create(&Task{Rate: 80, Duration: 30, StartTime: now, EndTime: now, ContractID: contract.ID}))
+
+	rate := &TaxRate{UserID: user.ID, Name: "Sales tax", Rate: 1000}
+	ms.NoError(DB.Create(rate))
+	ms.NoError(contract.SetTaxRates(DB, []int{rate.ID}))
+
+	// Only approved tasks are billed.
+	_, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.Equal(ErrNothingToInvoice, err)
+	ms.approveWeek(contract, now)
+
+	inv, verrs, err := CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal("INV-0001", inv.Number)
+	ms.Equal(TaxRoundInvoice, inv.TaxRounding)
+	ms.Len(inv.Taxes, 1)
+	ms.Equal(inv.IssuedOn.AddDate(0, 0, 30), inv.DueOn)
+
+	loaded, err := LoadInvoice(DB, user.ID, strconv.Itoa(inv.ID))
+	ms.NoError(err)
+	ms.Len(loaded.Tasks, 2)
+	ms.Equal(13000, loaded.Subtotal())
+	ms.Equal(1300, loaded.Tax())
+	ms.Equal(14300, loaded.Total())
+
+	d, err := LoadDashboard(DB, user.ID, now)
+	ms.NoError(err)
+	ms.Equal(0, d.Unbilled.Amount())
+
+	_, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.Equal(ErrNothingToInvoice, err)
+
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 60, StartTime: now, EndTime: now, ContractID: contract.ID}))
+	ms.approveWeek(contract, now)
+	inv, _, err = CreateInvoice(DB, contract, now, 0)
+	ms.NoError(err)
+	ms.Equal("INV-0002", inv.Number)
+	ms.Equal("Due on receipt", inv.Terms())
+}
+
+// approveWeek submits and approves the contract's tasks in now's week so
+// they can be invoiced.
+func (ms *ModelSuite) approveWeek(contract *Contract, now time.Time) {
+	ts, err := SubmitTimesheet(DB, contract, now, now)
+	ms.NoError(err)
+	ms.NoError(ts.Review(DB, true, "ACME", "", now))
+}
+
+func (ms *ModelSuite) Test_FormatMoney() {
+	ms.Equal("$0.00", FormatMoney(0))
+	ms.Equal("$12.05", FormatMoney(1205))
+	ms.Equal("$1,234,567.89", FormatMoney(123456789))
+	ms.Equal("-$100.00", FormatMoney(-10000))
+}
+
+func (ms *ModelSuite) Test_Percent() {
+	ms.Equal("8.25%", FormatPercent(825))
+	ms.Equal("10%", FormatPercent(1000))
+	ms.Equal("0.5%", FormatPercent(50))
+
+	bp, err := ParsePercent("8.25")
+	ms.NoError(err)
+	ms.Equal(825, bp)
+	bp, err = ParsePercent(" 20% ")
+	ms.NoError(err)
+	ms.Equal(2000, bp)
+	_, err = ParsePercent("-1")
+	ms.Error(err)
+	_, err = ParsePercent("abc")
+	ms.Error(err)
+}
+
+func (ms *ModelSuite) Test_Invoice_Lifecycle() {
+	now := time.Now()
+
+	user := &User{Email: "lifecycle@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 100, StartTime: now, EndTime: now, ContractID: contract.ID}))
+	ms.approveWeek(contract, now)
+
+	inv, _, err := CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	inv, err = LoadInvoice(DB, user.ID, strconv.Itoa(inv.ID))
+	ms.NoError(err)
+	ms.Equal(InvoiceDraft, inv.Status)
+	ms.Equal(10000, inv.Balance())
+
+	// Drafts cannot be paid.
+	_, err = inv.RecordPayment(DB, &Payment{PaidOn: now, Amount: 100, Method: "Cash"})
+	ms.Equal(ErrInvalidTransition, err)
+
+	ms.NoError(inv.MarkSent(DB))
+	ms.Equal(InvoiceSent, inv.Status)
+
+	verrs, err = inv.RecordPayment(DB, &Payment{PaidOn: now, Amount: 20000, Method: "Cash"})
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+
+	verrs, err = inv.RecordPayment(DB, &Payment{PaidOn: now, Amount: 4000, Method: "Check"})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal(InvoicePartiallyPaid, inv.Status)
+	ms.Equal(6000, inv.Balance())
+	ms.Equal(ErrInvalidTransition, inv.Void(DB))
+
+	verrs, err = inv.RecordPayment(DB, &Payment{PaidOn: now, Amount: 6000, Method: "Card"})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal(InvoicePaid, inv.Status)
+
+	inv, err = LoadInvoice(DB, user.ID, strconv.Itoa(inv.ID))
+	ms.NoError(err)
+	ms.Equal(InvoicePaid, inv.Status)
+	ms.Len(inv.Payments, 2)
+	ms.Equal(0, inv.Balance())
+}
+
+func (ms *ModelSuite) Test_Invoice_Void() {
+	now := time.Now()
+
+	user := &User{Email: "void@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 60, StartTime: now, EndTime: now, ContractID: contract.ID}))
+	ms.approveWeek(contract, now)
+
+	inv, _, err := CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.NoError(inv.Void(DB))
+	ms.Equal(InvoiceVoid, inv.Status)
+	ms.Equal(ErrInvalidTransition, inv.MarkSent(DB))
+
+	// The task is unbilled again.
+	inv, _, err = CreateInvoice(DB, contract, now, 30)
+	ms.NoError(err)
+	ms.Equal("INV-0002", inv.Number)
+}
+
+func (ms *ModelSuite) Test_Invoice_DaysOverdue() {
+	now := time.Date(2021, 9, 15, 10, 0, 0, 0, time.UTC)
+	inv := &Invoice{Status: InvoiceSent, DueOn: time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)}
+	ms.Equal(14, inv.DaysOverdue(now))
+	ms.True(inv.IsOverdue(now))
+
+	inv.DueOn = time.Date(2021, 9, 15, 0, 0, 0, 0, time.UTC)
+	ms.False(inv.IsOverdue(now))
+
+	inv.DueOn = time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	inv.Status = InvoicePaid
+	ms.False(inv.IsOverdue(now))
+}
+
+func (ms *ModelSuite) Test_ParseMoney() {
+	cents, err := ParseMoney("$1,234.5")
+	ms.NoError(err)
+	ms.Equal(123450, cents)
+	_, err = ParseMoney("twelve")
+	ms.Error(err)
+}
+
+func (ms *ModelSuite) Test_NextInvoiceNumber_Not_Reused() {
+	user := &User{Email: "numbers@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	n, err := nextInvoiceNumber(DB, user.ID)
+	ms.NoError(err)
+	ms.Equal("INV-0001", n)
+
+	// A number stays used even when nothing is left to count it.
+	n, err = nextInvoiceNumber(DB, user.ID)
+	ms.NoError(err)
+	ms.Equal("INV-0002", n)
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/pkg/errors"
+)
+
+// ErrMilestoneInvoiced is returned when changing a milestone that is
+// already on an invoice.
+var ErrMilestoneInvoiced = errors.New("milestone is already invoiced")
+
+// Milestone is a deliverable on a fixed-price contract. It is billed once
+// completed. Amount is in cents.
+type Milestone struct {
+	ID          int        `json:"id" db:"id"`
+	ContractID  int        `json:"-" db:"contract_id"`
+	Name        string     `json:"name" db:"name"`
+	Amount      int        `json:"amount" db:"amount" form:"-"`
+	DueOn       nulls.Time `json:"due_on" db:"due_on" form:"-"`
+	CompletedOn nulls.Time `json:"completed_on" db:"completed_on" form:"-"`
+	InvoiceID   nulls.Int  `json:"-" db:"invoice_id" form:"-"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (m Milestone) String() string {
+	jm, _ := json.Marshal(m)
+	return string(jm)
+}
+
+// Milestones is not required by pop and may be deleted
+type Milestones []Milestone
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (m *Milestone) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: m.Name, Name: "Name"},
+		&validators.IntIsGreaterThan{Field: m.Amount, Name: "Amount", Compared: 0, Message: "Enter the milestone's price."},
+	), nil
+}
+
+// IsComplete reports whether the milestone has been delivered.
+func (m *Milestone) IsComplete() bool {
+	return m.CompletedOn.Valid
+}
+
+// IsInvoiced reports whether the milestone is on an invoice.
+func (m *Milestone) IsInvoiced() bool {
+	return m.InvoiceID.Valid
+}
+
+// SetComplete marks the milestone completed on day, or open again.
+// Invoiced milestones cannot change.
+func (m *Milestone) SetComplete(tx *pop.Connection, done bool, day time.Time) error {
+	if m.IsInvoiced() {
+		return ErrMilestoneInvoiced
+	}
+	m.CompletedOn = nulls.Time{}
+	if done {
+		m.CompletedOn = nulls.NewTime(time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()))
+	}
+	return tx.UpdateColumns(m, "completed_on", "updated_at")
+}
+
+// Total sums the milestones' amounts in cents.
+func (ms Milestones) Total() int {
+	total := 0
+	for _, m := range ms {
+		total += m.Amount
+	}
+	return total
+}
+
+// CompletedTotal sums the completed milestones in cents.
+func (ms Milestones) CompletedTotal() int {
+	total := 0
+	for _, m := range ms {
+		if m.IsComplete() {
+			total += m.Amount
+		}
+	}
+	return total
+}
+
+// LoadMilestones returns the contract's milestones by due date; those
+// without one come last.
+func LoadMilestones(tx *pop.Connection, contractID int) (Milestones, error) {
+	ms := Milestones{}
+	err := tx.Where("contract_id = ?", contractID).Order("due_on IS NULL, due_on asc, id asc").All(&ms)
+	return ms, err
+}
+
+// FindMilestone finds one of the contract's milestones.
+func FindMilestone(tx *pop.Connection, contractID int, id string) (*Milestone, error) {
+	m := &Milestone{}
+	err := tx.Where("contract_id = ?", contractID).Find(m, id)
+	return m, err
+}
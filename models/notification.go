@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// Notification is a message for a user shown in the app.
+type Notification struct {
+	ID         int          `json:"id" db:"id"`
+	UserID     uuid.UUID    `json:"-" db:"user_id"`
+	ContractID nulls.Int    `json:"contract_id" db:"contract_id"`
+	Message    string       `json:"message" db:"message"`
+	Link       nulls.String `json:"link" db:"link"`
+	DedupeKey  nulls.String `json:"-" db:"dedupe_key"`
+	ReadAt     nulls.Time   `json:"read_at" db:"read_at"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (n Notification) String() string {
+	jn, _ := json.Marshal(n)
+	return string(jn)
+}
+
+// Notifications is not required by pop and may be deleted
+type Notifications []Notification
+
+// Notify adds a notification for the user unless one with the same
+// dedupe key was already sent, in which case it returns false.
+func Notify(tx *pop.Connection, n *Notification) (bool, error) {
+	if n.DedupeKey.Valid {
+		exists, err := tx.Where("user_id = ? AND dedupe_key = ?", n.UserID, n.DedupeKey.String).Exists(&Notification{})
+		if err != nil || exists {
+			return false, err
+		}
+	}
+	return true, tx.Create(n)
+}
+
+// LoadNotifications returns the user's latest notifications, newest first.
+func LoadNotifications(tx *pop.Connection, userID uuid.UUID) (Notifications, error) {
+	list := Notifications{}
+	err := tx.Where("user_id = ?", userID).Order("created_at desc, id desc").Limit(50).All(&list)
+	return list, err
+}
+
+// UnreadNotifications counts the user's unread notifications.
+func UnreadNotifications(tx *pop.Connection, userID uuid.UUID) (int, error) {
+	return tx.Where("user_id = ? AND read_at IS NULL", userID).Count(&Notification{})
+}
+
+// MarkNotificationsRead marks all the user's notifications read.
+func MarkNotificationsRead(tx *pop.Connection, userID uuid.UUID, now time.Time) error {
+	return tx.RawQuery("UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL", now, userID).Exec()
+}
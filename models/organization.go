@@ -0,0 +1,213 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Organization roles. Owners manage members and roles, managers see the
+// work of every member and members only see their own.
+const (
+	RoleOwner   = "owner"
+	RoleManager = "manager"
+	RoleMember  = "member"
+)
+
+// OrganizationRoles lists the roles a member can have.
+var OrganizationRoles = []string{RoleOwner, RoleManager, RoleMember}
+
+// ErrLastOwner is returned when a change would leave an organization
+// without an owner.
+var ErrLastOwner = errors.New("an organization needs at least one owner")
+
+// Organization is a team of users working for the same bosses.
+type Organization struct {
+	ID        int                 `json:"id" db:"id"`
+	Name      string              `json:"name" db:"name"`
+	Members   OrganizationMembers `json:"members,omitempty" has_many:"organization_members"`
+	CreatedAt time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (o Organization) String() string {
+	jo, _ := json.Marshal(o)
+	return string(jo)
+}
+
+// Organizations is not required by pop and may be deleted
+type Organizations []Organization
+
+// SelectLabel provides label for select-list.
+func (o Organization) SelectLabel() string {
+	return o.Name
+}
+
+// SelectValue provides value for select-list.
+func (o Organization) SelectValue() interface{} {
+	return o.ID
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (o *Organization) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: o.Name, Name: "Name"},
+	), nil
+}
+
+// OrganizationMember gives a user a role in an organization.
+type OrganizationMember struct {
+	ID             int       `json:"id" db:"id"`
+	OrganizationID int       `json:"organization_id" db:"organization_id"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	User           *User     `json:"user,omitempty" belongs_to:"users"`
+	Role           string    `json:"role" db:"role"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// OrganizationMembers is not required by pop and may be deleted
+type OrganizationMembers []OrganizationMember
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (m *OrganizationMember) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringInclusion{Field: m.Role, Name: "Role", List: OrganizationRoles},
+	), nil
+}
+
+// IsOwner reports whether the member can manage the organization.
+func (m *OrganizationMember) IsOwner() bool {
+	return m.Role == RoleOwner
+}
+
+// IsManager reports whether the member sees the work of everyone in the
+// organization. Owners are managers too.
+func (m *OrganizationMember) IsManager() bool {
+	return m.Role == RoleOwner || m.Role == RoleManager
+}
+
+// CreateOrganization starts an organization with the user as its owner.
+func CreateOrganization(tx *pop.Connection, name string, owner uuid.UUID) (*Organization, *validate.Errors, error) {
+	org := &Organization{Name: strings.TrimSpace(name)}
+	verrs, err := tx.ValidateAndCreate(org)
+	if err != nil || verrs.HasAny() {
+		return org, verrs, err
+	}
+	err = tx.Create(&OrganizationMember{OrganizationID: org.ID, UserID: owner, Role: RoleOwner})
+	return org, verrs, err
+}
+
+// LoadOrganizations returns the organizations the user belongs to.
+func LoadOrganizations(tx *pop.Connection, userID uuid.UUID) (Organizations, error) {
+	orgs := Organizations{}
+	err := tx.Where("id IN (SELECT organization_id FROM organization_members WHERE user_id = ?)", userID).
+		Order("name asc").All(&orgs)
+	return orgs, err
+}
+
+// FindMembership loads the user's membership of an organization with the
+// organization's members, or sql.ErrNoRows when the user is not a member.
+func FindMembership(tx *pop.Connection, orgID interface{}, userID uuid.UUID) (*Organization, *OrganizationMember, error) {
+	org := &Organization{}
+	if err := tx.Eager("Members.User").Find(org, orgID); err != nil {
+		return nil, nil, err
+	}
+	for i := range org.Members {
+		if org.Members[i].UserID == userID {
+			return org, &org.Members[i], nil
+		}
+	}
+	return nil, nil, sql.ErrNoRows
+}
+
+// AddMember adds the user with the given email, or changes their role when
+// they already belong to the organization.
+func (o *Organization) AddMember(tx *pop.Connection, email, role string) (*validate.Errors, error) {
+	user := &User{}
+	err := tx.Where("email = ?", strings.ToLower(strings.TrimSpace(email))).First(user)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return nil, ErrNoSuchUser
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	member := &OrganizationMember{}
+	err = tx.Where("organization_id = ? AND user_id = ?", o.ID, user.ID).First(member)
+	if errors.Cause(err) == sql.ErrNoRows {
+		member = &OrganizationMember{OrganizationID: o.ID, UserID: user.ID, Role: role}
+		return tx.ValidateAndCreate(member)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return o.SetRole(tx, user.ID, role)
+}
+
+// SetRole changes a member's role, keeping at least one owner.
+func (o *Organization) SetRole(tx *pop.Connection, userID uuid.UUID, role string) (*validate.Errors, error) {
+	member := &OrganizationMember{}
+	if err := tx.Where("organization_id = ? AND user_id = ?", o.ID, userID).First(member); err != nil {
+		return nil, err
+	}
+	if member.IsOwner() && role != RoleOwner {
+		if err := o.keepOwner(tx, userID); err != nil {
+			return nil, err
+		}
+	}
+	member.Role = role
+	return tx.ValidateAndUpdate(member)
+}
+
+// RemoveMember takes the user out of the organization. Their contracts stay
+// with the organization's bosses.
+func (o *Organization) RemoveMember(tx *pop.Connection, userID uuid.UUID) error {
+	if err := o.keepOwner(tx, userID); err != nil {
+		return err
+	}
+	return tx.RawQuery("DELETE FROM organization_members WHERE organization_id = ? AND user_id = ?", o.ID, userID).Exec()
+}
+
+// keepOwner fails when userID is the only owner left.
+func (o *Organization) keepOwner(tx *pop.Connection, userID uuid.UUID) error {
+	others, err := tx.Where("organization_id = ? AND role = ? AND user_id <> ?", o.ID, RoleOwner, userID).
+		Count(&OrganizationMember{})
+	if err != nil {
+		return err
+	}
+	owner, err := tx.Where("organization_id = ? AND role = ? AND user_id = ?", o.ID, RoleOwner, userID).
+		Exists(&OrganizationMember{})
+	if err != nil {
+		return err
+	}
+	if owner && others == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// SetOrganization moves the boss, and the contracts its organization's
+// members have with it, into an organization. Contracts of users the boss
+// is only shared with stay out of it. An invalid id takes them out of any
+// organization.
+func (b *Boss) SetOrganization(tx *pop.Connection, orgID nulls.Int) error {
+	b.OrganizationID = orgID
+	if err := tx.UpdateColumns(b, "organization_id"); err != nil {
+		return err
+	}
+	if err := tx.RawQuery("UPDATE contracts SET organization_id = NULL WHERE boss_id = ?", b.ID).Exec(); err != nil {
+		return err
+	}
+	return tx.RawQuery(`UPDATE contracts SET organization_id = ? WHERE boss_id = ?
+		AND user_id IN (SELECT user_id FROM organization_members WHERE organization_id = ?)`, orgID, b.ID, orgID).Exec()
+}
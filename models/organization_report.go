@@ -0,0 +1,118 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// MemberTotals is the time one member logged for the organization's
+// bosses, broken down by boss.
+type MemberTotals struct {
+	UserID uuid.UUID
+	Name   string
+	Bosses []BossSummary
+}
+
+// Minutes totals the member's time.
+func (m MemberTotals) Minutes() int {
+	total := 0
+	for _, b := range m.Bosses {
+		total += b.Minutes
+	}
+	return total
+}
+
+// Amount totals the member's earnings in cents.
+func (m MemberTotals) Amount() int {
+	total := 0
+	for _, b := range m.Bosses {
+		total += b.Amount()
+	}
+	return total
+}
+
+// OrganizationReport totals the work on an organization's contracts between
+// From and To, To being exclusive.
+type OrganizationReport struct {
+	From    time.Time
+	To      time.Time
+	Members []MemberTotals
+}
+
+// Minutes totals the time across members.
+func (r *OrganizationReport) Minutes() int {
+	total := 0
+	for _, m := range r.Members {
+		total += m.Minutes()
+	}
+	return total
+}
+
+// Amount totals the earnings across members in cents.
+func (r *OrganizationReport) Amount() int {
+	total := 0
+	for _, m := range r.Members {
+		total += m.Amount()
+	}
+	return total
+}
+
+type memberBossTotal struct {
+	UserID      uuid.UUID `db:"user_id"`
+	FirstName   string    `db:"first_name"`
+	LastName    string    `db:"last_name"`
+	Email       string    `db:"email"`
+	BossID      int       `db:"boss_id"`
+	BossName    string    `db:"boss_name"`
+	Minutes     int       `db:"minutes"`
+	RateMinutes int       `db:"rate_minutes"`
+}
+
+// LoadOrganizationReport sums the tasks matching the filter per member and
+// boss. The filter must name the organization and the period; a user or
+// project in it narrows the report further.
+func LoadOrganizationReport(tx *pop.Connection, f TaskFilter) (*OrganizationReport, error) {
+	where, args := f.where()
+	stmt := `SELECT users.id AS user_id, users.first_name, users.last_name, users.email,
+		bosses.id AS boss_id, bosses.name AS boss_name,
+		COALESCE(SUM(tasks.duration), 0) AS minutes,
+		COALESCE(SUM(tasks.rate * tasks.duration), 0) AS rate_minutes
+		FROM tasks
+		JOIN contracts ON contracts.id = tasks.contract_id
+		JOIN bosses ON bosses.id = contracts.boss_id
+		JOIN users ON users.id = contracts.user_id
+		WHERE ` + where + `
+		GROUP BY users.id, users.first_name, users.last_name, users.email, bosses.id, bosses.name
+		ORDER BY users.last_name, users.first_name, users.id, bosses.name`
+
+	rows := []memberBossTotal{}
+	if err := tx.RawQuery(stmt, args...).All(&rows); err != nil {
+		return nil, err
+	}
+	return buildOrganizationReport(rows, f.From, f.To), nil
+}
+
+// buildOrganizationReport groups consecutive rows of the same user.
+func buildOrganizationReport(rows []memberBossTotal, from, to time.Time) *OrganizationReport {
+	rep := &OrganizationReport{From: from, To: to, Members: []MemberTotals{}}
+	for _, row := range rows {
+		n := len(rep.Members)
+		if n == 0 || rep.Members[n-1].UserID != row.UserID {
+			name := row.Email
+			if row.FirstName != "" || row.LastName != "" {
+				name = (&User{FirstName: row.FirstName, LastName: row.LastName}).FullName()
+			}
+			rep.Members = append(rep.Members, MemberTotals{UserID: row.UserID, Name: name})
+			n++
+		}
+		rep.Members[n-1].Bosses = append(rep.Members[n-1].Bosses, BossSummary{
+			BossID:      row.BossID,
+			BossName:    row.BossName,
+			Minutes:     row.Minutes,
+			RateMinutes: row.RateMinutes,
+		})
+	}
+	return rep
+}
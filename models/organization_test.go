@@ -0,0 +1,159 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_Organization_Members() {
+	owner := &User{Email: "boss@agency.example", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := owner.Create(DB)
+	ms.NoError(err)
+	dev := &User{Email: "dev@agency.example", Password: "secret", PasswordConfirmation: "secret"}
+	_, err = dev.Create(DB)
+	ms.NoError(err)
+
+	org, verrs, err := CreateOrganization(DB, "Agency", owner.ID)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	_, err = org.AddMember(DB, "nobody@agency.example", RoleMember)
+	ms.Equal(ErrNoSuchUser, err)
+	verrs, err = org.AddMember(DB, "dev@agency.example", "intern")
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	verrs, err = org.AddMember(DB, "DEV@agency.example", RoleMember)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	_, member, err := FindMembership(DB, org.ID, dev.ID)
+	ms.NoError(err)
+	ms.False(member.IsManager())
+
+	// The only owner cannot step down or leave.
+	_, err = org.SetRole(DB, owner.ID, RoleManager)
+	ms.Equal(ErrLastOwner, err)
+	ms.Equal(ErrLastOwner, org.RemoveMember(DB, owner.ID))
+
+	_, err = org.SetRole(DB, dev.ID, RoleOwner)
+	ms.NoError(err)
+	_, err = org.SetRole(DB, owner.ID, RoleManager)
+	ms.NoError(err)
+
+	ms.NoError(org.RemoveMember(DB, owner.ID))
+	_, _, err = FindMembership(DB, org.ID, owner.ID)
+	ms.Error(err)
+}
+
+func (ms *ModelSuite) Test_Organization_Bosses() {
+	owner := &User{Email: "lead@agency.example", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := owner.Create(DB)
+	ms.NoError(err)
+	dev := &User{Email: "coder@agency.example", Password: "secret", PasswordConfirmation: "secret"}
+	_, err = dev.Create(DB)
+	ms.NoError(err)
+
+	org, _, err := CreateOrganization(DB, "Agency", owner.ID)
+	ms.NoError(err)
+	_, err = org.AddMember(DB, dev.Email, RoleMember)
+	ms.NoError(err)
+
+	boss := &Boss{Name: "Umbrella", UserID: nulls.NewUUID(owner.ID)}
+	ms.NoError(DB.Create(boss))
+	_, err = FindBoss(DB, dev.ID, boss.ID)
+	ms.Error(err)
+
+	first := &Contract{Rate: 50, BossID: boss.ID, UserID: owner.ID}
+	ms.NoError(DB.Create(first))
+	ms.False(first.OrganizationID.Valid)
+
+	ms.NoError(boss.SetOrganization(DB, nulls.NewInt(org.ID)))
+	_, err = FindBoss(DB, dev.ID, boss.ID)
+	ms.NoError(err)
+
+	// New contracts join the boss's organization.
+	second := &Contract{Rate: 40, BossID: boss.ID, UserID: dev.ID}
+	ms.NoError(DB.Create(second))
+	ms.Equal(nulls.NewInt(org.ID), second.OrganizationID)
+
+	ms.NoError(DB.Reload(first))
+	ms.Equal(nulls.NewInt(org.ID), first.OrganizationID)
+
+	start := time.Date(2021, 9, 6, 9, 0, 0, 0, time.UTC)
+	ms.NoError(DB.Create(&Task{Rate: 50, Duration: 60, StartTime: start, EndTime: start, ContractID: first.ID}))
+	ms.NoError(DB.Create(&Task{Rate: 40, Duration: 30, StartTime: start, EndTime: start, ContractID: second.ID}))
+
+	from, to := start.AddDate(0, 0, -1), start.AddDate(0, 0, 1)
+	all, err := LoadOrganizationReport(DB, TaskFilter{OrganizationID: org.ID, From: from, To: to})
+	ms.NoError(err)
+	ms.Len(all.Members, 2)
+	ms.Equal(90, all.Minutes())
+
+	own, err := LoadOrganizationReport(DB, TaskFilter{OrganizationID: org.ID, UserID: dev.ID, From: from, To: to})
+	ms.NoError(err)
+	ms.Len(own.Members, 1)
+	ms.Equal(2000, own.Amount())
+}
+
+func (ms *ModelSuite) Test_BuildOrganizationReport() {
+	a := uuid.Must(uuid.NewV4())
+	b := uuid.Must(uuid.NewV4())
+	rows := []memberBossTotal{
+		{UserID: a, FirstName: "Ann", LastName: "Lee", BossName: "ACME", Minutes: 60, RateMinutes: 60 * 50},
+		{UserID: a, FirstName: "Ann", LastName: "Lee", BossName: "Globex", Minutes: 30, RateMinutes: 30 * 50},
+		{UserID: b, Email: "bo@example.com", BossName: "ACME", Minutes: 15, RateMinutes: 15 * 80},
+	}
+	rep := buildOrganizationReport(rows, time.Time{}, time.Time{})
+	ms.Len(rep.Members, 2)
+	ms.Equal("Ann Lee", rep.Members[0].Name)
+	ms.Len(rep.Members[0].Bosses, 2)
+	ms.Equal(7500, rep.Members[0].Amount())
+	ms.Equal("bo@example.com", rep.Members[1].Name)
+	ms.Equal(105, rep.Minutes())
+	ms.Equal(9500, rep.Amount())
+}
+
+func (ms *ModelSuite) Test_Organization_Bosses_Shared_User() {
+	owner := &User{Email: "lead@agency.example", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := owner.Create(DB)
+	ms.NoError(err)
+	outsider := &User{Email: "freelancer@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err = outsider.Create(DB)
+	ms.NoError(err)
+
+	org, _, err := CreateOrganization(DB, "Agency", owner.ID)
+	ms.NoError(err)
+
+	boss := &Boss{Name: "Umbrella", UserID: nulls.NewUUID(owner.ID)}
+	ms.NoError(DB.Create(boss))
+	_, err = boss.ShareWith(DB, outsider.Email)
+	ms.NoError(err)
+
+	own := &Contract{Rate: 50, BossID: boss.ID, UserID: owner.ID}
+	ms.NoError(DB.Create(own))
+	shared := &Contract{Rate: 40, BossID: boss.ID, UserID: outsider.ID}
+	ms.NoError(DB.Create(shared))
+
+	// Only the members' contracts follow the boss into the organization.
+	ms.NoError(boss.SetOrganization(DB, nulls.NewInt(org.ID)))
+	ms.NoError(DB.Reload(own))
+	ms.Equal(nulls.NewInt(org.ID), own.OrganizationID)
+	ms.NoError(DB.Reload(shared))
+	ms.False(shared.OrganizationID.Valid)
+
+	later := &Contract{Rate: 40, BossID: boss.ID, UserID: outsider.ID}
+	ms.NoError(DB.Create(later))
+	ms.False(later.OrganizationID.Valid)
+
+	start := time.Date(2021, 9, 6, 9, 0, 0, 0, time.UTC)
+	ms.NoError(DB.Create(&Task{Rate: 50, Duration: 60, StartTime: start, EndTime: start, ContractID: own.ID}))
+	ms.NoError(DB.Create(&Task{Rate: 40, Duration: 30, StartTime: start, EndTime: start, ContractID: shared.ID}))
+	ms.NoError(DB.Create(&Task{Rate: 40, Duration: 15, StartTime: start, EndTime: start, ContractID: later.ID}))
+
+	rep, err := LoadOrganizationReport(DB, TaskFilter{OrganizationID: org.ID, From: start.AddDate(0, 0, -1), To: start.AddDate(0, 0, 1)})
+	ms.NoError(err)
+	ms.Len(rep.Members, 1)
+	ms.Equal(60, rep.Minutes())
+}
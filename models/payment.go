@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/pkg/errors"
+)
+
+// PaymentMethods are the ways a payment can be recorded as received.
+var PaymentMethods = []string{"Bank transfer", "Check", "Card", "Cash", "Other"}
+
+// Payment is money received against an invoice. Amount is in cents.
+type Payment struct {
+	ID        int       `json:"id" db:"id"`
+	InvoiceID int       `json:"-" db:"invoice_id"`
+	PaidOn    time.Time `json:"paid_on" db:"paid_on"`
+	Amount    int       `json:"amount" db:"amount"`
+	Method    string    `json:"method" db:"method"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (p Payment) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// Payments is not required by pop and may be deleted
+type Payments []Payment
+
+// Total sums the payments in cents.
+func (p Payments) Total() int {
+	total := 0
+	for _, pay := range p {
+		total += pay.Amount
+	}
+	return total
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (p *Payment) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	errs := validate.Validate(
+		&validators.IntIsPresent{Field: p.InvoiceID, Name: "InvoiceID"},
+		&validators.TimeIsPresent{Field: p.PaidOn, Name: "PaidOn"},
+		&validators.StringInclusion{Field: p.Method, Name: "Method", List: PaymentMethods},
+	)
+	if p.Amount <= 0 {
+		errs.Add("amount", "Amount must be greater than zero.")
+	}
+	return errs, nil
+}
+
+// ParseMoney reads a dollar amount such as "1,234.50" as cents.
+func ParseMoney(s string) (int, error) {
+	v := strings.NewReplacer("$", "", ",", "").Replace(strings.TrimSpace(s))
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errors.Errorf("invalid amount %q", s)
+	}
+	return int(math.Round(f * 100)), nil
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// PortalExpiries are the choices, in days, offered when sharing a contract's
+// portal. Zero keeps the link working until it is revoked.
+var PortalExpiries = []int{0, 7, 30, 90}
+
+// ResetPortalToken issues a new secret for the contract's client portal,
+// replacing any previous one. The link stops working after expires when it
+// is set.
+func (c *Contract) ResetPortalToken(tx *pop.Connection, expires nulls.Time) error {
+	token, err := NewToken()
+	if err != nil {
+		return err
+	}
+	c.PortalToken = nulls.NewString(token)
+	c.PortalExpiresAt = expires
+	return tx.UpdateColumns(c, "portal_token", "portal_expires_at")
+}
+
+// RevokePortalToken disables the client portal.
+func (c *Contract) RevokePortalToken(tx *pop.Connection) error {
+	c.PortalToken = nulls.String{}
+	c.PortalExpiresAt = nulls.Time{}
+	return tx.UpdateColumns(c, "portal_token", "portal_expires_at")
+}
+
+// PortalActive reports whether the portal link works at now.
+func (c *Contract) PortalActive(now time.Time) bool {
+	if !c.PortalToken.Valid {
+		return false
+	}
+	return !c.PortalExpiresAt.Valid || now.Before(c.PortalExpiresAt.Time)
+}
+
+// FindContractByPortalToken loads the contract a portal link points to,
+// provided the link has not expired.
+func FindContractByPortalToken(tx *pop.Connection, token string, now time.Time) (*Contract, error) {
+	c := &Contract{}
+	err := tx.Where("portal_token = ?", token).
+		Where("(portal_expires_at IS NULL OR portal_expires_at > ?)", now).
+		Eager("Boss", "User").First(c)
+	return c, err
+}
+
+// portalMonth is how months are passed in portal links.
+const portalMonth = "2006-01"
+
+// PeriodTotal sums the time and earnings logged in a month.
+type PeriodTotal struct {
+	Month   time.Time
+	Minutes int
+	Amount  int
+}
+
+// Portal is what a client sees of a contract: a month of the worklog, the
+// monthly totals and the issued invoices.
+type Portal struct {
+	Contract *Contract
+	Month    time.Time
+	Tasks    Tasks
+	Totals   []PeriodTotal
+	Invoices Invoices
+}
+
+// LoadPortal builds the portal for the contract, showing the tasks of the
+// month containing month.
+func LoadPortal(tx *pop.Connection, contract *Contract, month time.Time) (*Portal, error) {
+	p := &Portal{
+		Contract: contract,
+		Month:    time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location()),
+	}
+
+	tasks := Tasks{}
+	if err := tx.Where("contract_id = ?", contract.ID).Order("start_time asc").All(&tasks); err != nil {
+		return nil, err
+	}
+	p.Totals = monthlyTotals(tasks, month.Location())
+	next := p.Month.AddDate(0, 1, 0)
+	for _, t := range tasks {
+		if !t.StartTime.Before(p.Month) && t.StartTime.Before(next) {
+			p.Tasks = append(p.Tasks, t)
+		}
+	}
+
+	// Drafts and void invoices were never issued to the client.
+	err := tx.Where("contract_id = ? AND status IN (?, ?, ?)", contract.ID, InvoiceSent, InvoicePartiallyPaid, InvoicePaid).
+		Eager("Tasks", "Payments", "Taxes", "Lines").Order("issued_on desc, id desc").All(&p.Invoices)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Minutes totals the time in the portal's month.
+func (p *Portal) Minutes() int {
+	total := 0
+	for _, t := range p.Tasks {
+		total += t.Duration
+	}
+	return total
+}
+
+// Amount totals the earnings in the portal's month in cents.
+func (p *Portal) Amount() int {
+	total := 0
+	for _, t := range p.Tasks {
+		total += Earnings(t.Rate, t.Duration)
+	}
+	return total
+}
+
+// PrevMonth is the month before the portal's, as a month param.
+func (p *Portal) PrevMonth() string {
+	return p.Month.AddDate(0, -1, 0).Format(portalMonth)
+}
+
+// NextMonth is the month after the portal's, as a month param.
+func (p *Portal) NextMonth() string {
+	return p.Month.AddDate(0, 1, 0).Format(portalMonth)
+}
+
+// Param is the total's month as a month param.
+func (pt PeriodTotal) Param() string {
+	return pt.Month.Format(portalMonth)
+}
+
+// monthlyTotals groups tasks by month in loc, newest first.
+func monthlyTotals(tasks Tasks, loc *time.Location) []PeriodTotal {
+	byMonth := map[time.Time]*PeriodTotal{}
+	for _, t := range tasks {
+		st := t.StartTime.In(loc)
+		m := time.Date(st.Year(), st.Month(), 1, 0, 0, 0, 0, loc)
+		pt, ok := byMonth[m]
+		if !ok {
+			pt = &PeriodTotal{Month: m}
+			byMonth[m] = pt
+		}
+		pt.Minutes += t.Duration
+		pt.Amount += Earnings(t.Rate, t.Duration)
+	}
+
+	totals := []PeriodTotal{}
+	for _, pt := range byMonth {
+		totals = append(totals, *pt)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Month.After(totals[j].Month)
+	})
+	return totals
+}
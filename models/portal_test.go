@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_Contract_Portal() {
+	now := time.Now()
+
+	user := &User{Email: "portal@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID}))
+	lastMonth := time.Date(now.Year(), now.Month(), 1, 12, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 30, StartTime: lastMonth, EndTime: lastMonth, ContractID: contract.ID}))
+	ms.False(contract.PortalActive(now))
+
+	ms.NoError(contract.ResetPortalToken(DB, nulls.Time{}))
+	ms.True(contract.PortalActive(now))
+	found, err := FindContractByPortalToken(DB, contract.PortalToken.String, now)
+	ms.NoError(err)
+	ms.Equal(contract.ID, found.ID)
+	ms.Equal(boss.Name, found.Boss.Name)
+
+	p, err := LoadPortal(DB, found, now)
+	ms.NoError(err)
+	ms.Len(p.Tasks, 1)
+	ms.Len(p.Totals, 2)
+	ms.Equal(9000, p.Amount())
+	ms.Len(p.Invoices, 0)
+
+	// Expired links stop working.
+	ms.NoError(contract.ResetPortalToken(DB, nulls.NewTime(now.Add(-time.Hour))))
+	ms.False(contract.PortalActive(now))
+	_, err = FindContractByPortalToken(DB, contract.PortalToken.String, now)
+	ms.Error(err)
+
+	ms.NoError(contract.ResetPortalToken(DB, nulls.NewTime(now.AddDate(0, 0, 7))))
+	token := contract.PortalToken.String
+	ms.NoError(contract.RevokePortalToken(DB))
+	_, err = FindContractByPortalToken(DB, token, now)
+	ms.Error(err)
+}
+
+func (ms *ModelSuite) Test_MonthlyTotals() {
+	sep := time.Date(2021, 9, 30, 23, 0, 0, 0, time.UTC)
+	tasks := Tasks{
+		{Rate: 60, Duration: 60, StartTime: sep},
+		{Rate: 60, Duration: 30, StartTime: sep.Add(2 * time.Hour)},
+		{Rate: 60, Duration: 30, StartTime: sep.AddDate(0, 0, -5)},
+	}
+
+	totals := monthlyTotals(tasks, time.UTC)
+	ms.Len(totals, 2)
+	ms.Equal(time.October, totals[0].Month.Month())
+	ms.Equal(30, totals[0].Minutes)
+	ms.Equal("2021-10", totals[0].Param())
+	ms.Equal(90, totals[1].Minutes)
+	ms.Equal(9000, totals[1].Amount)
+}
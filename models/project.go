@@ -0,0 +1,140 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Project is a workstream within a contract. Tasks may belong to one so
+// its time can be reported separately.
+type Project struct {
+	ID         int       `json:"id" db:"id"`
+	ContractID int       `json:"-" db:"contract_id"`
+	Contract   *Contract `json:"contract,omitempty" belongs_to:"contract"`
+	Name       string    `json:"name" db:"name"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (p Project) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// Projects is not required by pop and may be deleted
+type Projects []Project
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (p *Project) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	var err error
+	return validate.Validate(
+		&validators.StringIsPresent{Field: p.Name, Name: "Name"},
+		&validators.FuncValidator{
+			Field:   p.Name,
+			Name:    "Name",
+			Message: "%s is already used for another project on this contract",
+			Fn: func() bool {
+				q := tx.Where("contract_id = ? AND name = ?", p.ContractID, p.Name)
+				if p.ID != 0 {
+					q = q.Where("id != ?", p.ID)
+				}
+				var b bool
+				b, err = q.Exists(&Project{})
+				return !b
+			},
+		},
+	), err
+}
+
+// Label names the project with its contract, e.g. "ACME - Website /
+// Checkout". The contract and its boss must be loaded.
+func (p *Project) Label() string {
+	if p.Contract == nil {
+		return p.Name
+	}
+	return p.Contract.Label() + " / " + p.Name
+}
+
+// NameOf returns the name of the project with the given id, or "" when
+// the id is null or not in the list.
+func (ps Projects) NameOf(id nulls.Int) string {
+	if !id.Valid {
+		return ""
+	}
+	for _, p := range ps {
+		if p.ID == id.Int {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// FindProject finds one of the contract's projects.
+func FindProject(tx *pop.Connection, contractID int, id interface{}) (*Project, error) {
+	p := &Project{}
+	err := tx.Where("contract_id = ?", contractID).Find(p, id)
+	return p, err
+}
+
+// LoadProjects returns the contract's projects by name.
+func LoadProjects(tx *pop.Connection, contractID int) (Projects, error) {
+	ps := Projects{}
+	err := tx.Where("contract_id = ?", contractID).Order("name asc").All(&ps)
+	return ps, err
+}
+
+// LoadUserProjects returns the projects on all the user's contracts with
+// their contracts and bosses, for filters.
+func LoadUserProjects(tx *pop.Connection, userID uuid.UUID) (Projects, error) {
+	ps := Projects{}
+	err := tx.Where("projects.contract_id IN (SELECT id FROM contracts WHERE user_id = ?)", userID).
+		Eager("Contract.Boss").Order("projects.name asc").All(&ps)
+	return ps, err
+}
+
+// LoadOrganizationProjects returns the projects on the organization's
+// contracts with their contracts and bosses, for filters.
+func LoadOrganizationProjects(tx *pop.Connection, orgID int) (Projects, error) {
+	ps := Projects{}
+	err := tx.Where("projects.contract_id IN (SELECT id FROM contracts WHERE organization_id = ?)", orgID).
+		Eager("Contract.Boss").Order("projects.name asc").All(&ps)
+	return ps, err
+}
+
+// ProjectTotal is the time and earnings logged against one project of a
+// contract. Tasks without a project are totalled with a null ProjectID.
+type ProjectTotal struct {
+	ProjectID   nulls.Int `json:"project_id" db:"project_id"`
+	Name        string    `json:"name" db:"name"`
+	Minutes     int       `json:"minutes" db:"minutes"`
+	RateMinutes int       `json:"-" db:"rate_minutes"`
+}
+
+// Amount is the earnings for the project in cents.
+func (t ProjectTotal) Amount() int {
+	return rateMinutesToCents(t.RateMinutes)
+}
+
+// LoadProjectTotals sums the contract's tasks per project. Tasks without a
+// project come last.
+func LoadProjectTotals(tx *pop.Connection, contractID int) ([]ProjectTotal, error) {
+	rows := []ProjectTotal{}
+	err := tx.RawQuery(`SELECT tasks.project_id, COALESCE(projects.name, '') AS name,
+		COALESCE(SUM(tasks.duration), 0) AS minutes,
+		COALESCE(SUM(tasks.rate * tasks.duration), 0) AS rate_minutes
+		FROM tasks
+		LEFT JOIN projects ON projects.id = tasks.project_id
+		WHERE tasks.contract_id = ?
+		GROUP BY tasks.project_id, projects.name
+		ORDER BY projects.name IS NULL, projects.name`, contractID).All(&rows)
+	return rows, err
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_Projects() {
+	now := time.Now()
+
+	user := &User{Email: "projects@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+	other := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID, Name: "Other"}
+	ms.NoError(DB.Create(other))
+
+	checkout := &Project{ContractID: contract.ID, Name: " Checkout "}
+	verrs, err = DB.ValidateAndCreate(checkout)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal("Checkout", checkout.Name)
+
+	verrs, err = DB.ValidateAndCreate(&Project{ContractID: contract.ID, Name: "Checkout"})
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	verrs, err = DB.ValidateAndCreate(&Project{ContractID: other.ID, Name: "Checkout"})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	// A task cannot use another contract's project.
+	verrs, err = DB.ValidateAndCreate(&Task{Rate: 60, Duration: 30, StartTime: now, EndTime: now, ContractID: other.ID, ProjectID: nulls.NewInt(checkout.ID)})
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+
+	verrs, err = DB.ValidateAndCreate(&Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID, ProjectID: nulls.NewInt(checkout.ID)})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 30, StartTime: now, EndTime: now, ContractID: contract.ID}))
+
+	totals, err := LoadProjectTotals(DB, contract.ID)
+	ms.NoError(err)
+	ms.Len(totals, 2)
+	ms.Equal("Checkout", totals[0].Name)
+	ms.Equal(90, totals[0].Minutes)
+	ms.Equal(9000, totals[0].Amount())
+	ms.False(totals[1].ProjectID.Valid)
+	ms.Equal(30, totals[1].Minutes)
+
+	rows := []TaskRow{}
+	err = TaskFilter{UserID: user.ID, ProjectID: checkout.ID}.EachRow(DB, 10, func(r TaskRow) error {
+		rows = append(rows, r)
+		return nil
+	})
+	ms.NoError(err)
+	ms.Len(rows, 1)
+	ms.Equal("Checkout", rows[0].ProjectName)
+
+	ms.NoError(DB.Load(contract, "Projects"))
+	ms.Equal("Checkout", contract.Projects.NameOf(nulls.NewInt(checkout.ID)))
+	ms.Equal("", contract.Projects.NameOf(nulls.Int{}))
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// AgingBuckets label the receivables columns. Invoices are aged by the days
+// since they were issued.
+var AgingBuckets = []string{"0–30 days", "31–60 days", "61–90 days", "90+ days"}
+
+// agingBucket returns the AgingBuckets index for an invoice age in days.
+func agingBucket(days int) int {
+	switch {
+	case days <= 30:
+		return 0
+	case days <= 60:
+		return 1
+	case days <= 90:
+		return 2
+	}
+	return 3
+}
+
+// ReceivablesRow is the outstanding balance owed by one boss, in cents.
+type ReceivablesRow struct {
+	BossID   int
+	BossName string
+	Buckets  []int
+	Overdue  int
+	Invoices int
+}
+
+func newReceivablesRow(id int, name string) *ReceivablesRow {
+	return &ReceivablesRow{BossID: id, BossName: name, Buckets: make([]int, len(AgingBuckets))}
+}
+
+// Total is the balance across all buckets.
+func (r *ReceivablesRow) Total() int {
+	total := 0
+	for _, b := range r.Buckets {
+		total += b
+	}
+	return total
+}
+
+// Receivables is the aging report for a user's open invoices.
+type Receivables struct {
+	Rows   []*ReceivablesRow
+	Totals *ReceivablesRow
+}
+
+// LoadReceivables ages the balances of the user's sent and partially paid
+// invoices as of now, grouped by boss.
+func LoadReceivables(tx *pop.Connection, userID uuid.UUID, now time.Time) (*Receivables, error) {
+	invoices := Invoices{}
+	err := tx.Where("user_id = ? AND status IN (?, ?)", userID, InvoiceSent, InvoicePartiallyPaid).
+		Eager("Contract.Boss", "Tasks", "Payments", "Taxes", "Lines").All(&invoices)
+	if err != nil {
+		return nil, err
+	}
+	return buildReceivables(invoices, now), nil
+}
+
+func buildReceivables(invoices Invoices, now time.Time) *Receivables {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	byBoss := map[int]*ReceivablesRow{}
+	rec := &Receivables{Totals: newReceivablesRow(0, "Total")}
+
+	for i := range invoices {
+		inv := &invoices[i]
+		balance := inv.Balance()
+		if balance <= 0 {
+			continue
+		}
+		bossID, bossName := 0, ""
+		if inv.Contract != nil && inv.Contract.Boss != nil {
+			bossID, bossName = inv.Contract.Boss.ID, inv.Contract.Boss.Name
+		}
+		row, ok := byBoss[bossID]
+		if !ok {
+			row = newReceivablesRow(bossID, bossName)
+			byBoss[bossID] = row
+			rec.Rows = append(rec.Rows, row)
+		}
+
+		issued := time.Date(inv.IssuedOn.Year(), inv.IssuedOn.Month(), inv.IssuedOn.Day(), 0, 0, 0, 0, time.UTC)
+		b := agingBucket(int(today.Sub(issued).Hours() / 24))
+		for _, r := range []*ReceivablesRow{row, rec.Totals} {
+			r.Buckets[b] += balance
+			r.Invoices++
+			if inv.IsOverdue(now) {
+				r.Overdue += balance
+			}
+		}
+	}
+
+	sort.Slice(rec.Rows, func(a, b int) bool {
+		return rec.Rows[a].Total() > rec.Rows[b].Total()
+	})
+	return rec
+}
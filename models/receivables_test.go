@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+func (ms *ModelSuite) Test_BuildReceivables() {
+	now := time.Date(2021, 9, 30, 12, 0, 0, 0, time.UTC)
+	acme := &Contract{Boss: &Boss{ID: 1, Name: "ACME"}}
+	beta := &Contract{Boss: &Boss{ID: 2, Name: "Beta"}}
+	task := func(minutes int) Tasks {
+		return Tasks{{Rate: 60, Duration: minutes}}
+	}
+
+	invoices := Invoices{
+		{Status: InvoiceSent, Contract: acme, IssuedOn: now.AddDate(0, 0, -10), DueOn: now.AddDate(0, 0, 20), Tasks: task(60)},
+		{Status: InvoicePartiallyPaid, Contract: acme, IssuedOn: now.AddDate(0, 0, -45), DueOn: now.AddDate(0, 0, -15), Tasks: task(120), Payments: Payments{{Amount: 5000}}},
+		{Status: InvoiceSent, Contract: beta, IssuedOn: now.AddDate(0, 0, -120), DueOn: now.AddDate(0, 0, -90), Tasks: task(300)},
+		{Status: InvoiceSent, Contract: beta, IssuedOn: now.AddDate(0, 0, -75), DueOn: now.AddDate(0, 0, -45), Tasks: task(30), Payments: Payments{{Amount: 3000}}},
+	}
+
+	rec := buildReceivables(invoices, now)
+	ms.Len(rec.Rows, 2)
+
+	// Beta owes the most and sorts first; its paid invoice is skipped.
+	ms.Equal("Beta", rec.Rows[0].BossName)
+	ms.Equal([]int{0, 0, 0, 30000}, rec.Rows[0].Buckets)
+	ms.Equal(30000, rec.Rows[0].Overdue)
+	ms.Equal(1, rec.Rows[0].Invoices)
+
+	ms.Equal("ACME", rec.Rows[1].BossName)
+	ms.Equal([]int{6000, 7000, 0, 0}, rec.Rows[1].Buckets)
+	ms.Equal(7000, rec.Rows[1].Overdue)
+
+	ms.Equal([]int{6000, 7000, 0, 30000}, rec.Totals.Buckets)
+	ms.Equal(43000, rec.Totals.Total())
+}
+
+func (ms *ModelSuite) Test_AgingBucket() {
+	ms.Equal(0, agingBucket(0))
+	ms.Equal(0, agingBucket(30))
+	ms.Equal(1, agingBucket(31))
+	ms.Equal(2, agingBucket(90))
+	ms.Equal(3, agingBucket(91))
+}
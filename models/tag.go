@@ -0,0 +1,219 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Tag is a free-form label a user puts on tasks, across all their
+// contracts, so time can be reported by theme.
+type Tag struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t Tag) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// Tags is not required by pop and may be deleted
+type Tags []Tag
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (t *Tag) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	t.Name = strings.TrimSpace(t.Name)
+	return validate.Validate(
+		&validators.StringIsPresent{Field: t.Name, Name: "Name"},
+	), nil
+}
+
+// Input lists the tag names separated by commas, as typed into the task
+// form.
+func (ts Tags) Input() string {
+	names := make([]string, len(ts))
+	for i, t := range ts {
+		names[i] = t.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Has reports whether one of the tags has the given name, ignoring case.
+func (ts Tags) Has(name string) bool {
+	for _, t := range ts {
+		if strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTags splits a comma-separated list of tag names. Blank names and
+// repeats, ignoring case, are dropped.
+func ParseTags(s string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, n := range strings.Split(s, ",") {
+		n = strings.Join(strings.Fields(n), " ")
+		key := strings.ToLower(n)
+		if n == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, n)
+	}
+	return names
+}
+
+// TagsNamed returns unsaved tags with the given names, so a form can be
+// shown again before they are stored.
+func TagsNamed(names []string) Tags {
+	ts := Tags{}
+	for _, n := range names {
+		ts = append(ts, Tag{Name: n})
+	}
+	return ts
+}
+
+// LoadTags returns the user's tags by name.
+func LoadTags(tx *pop.Connection, userID uuid.UUID) (Tags, error) {
+	ts := Tags{}
+	err := tx.Where("user_id = ?", userID).Order("name asc").All(&ts)
+	return ts, err
+}
+
+// TaskTag puts a tag on a task.
+type TaskTag struct {
+	ID        int       `json:"id" db:"id"`
+	TaskID    int       `json:"task_id" db:"task_id"`
+	TagID     int       `json:"tag_id" db:"tag_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SetTags replaces the task's tags with the named ones, as returned by
+// ParseTags, creating the user's missing tags. Names match existing tags
+// ignoring case.
+func (t *Task) SetTags(tx *pop.Connection, userID uuid.UUID, names []string) error {
+	err := tx.RawQuery("DELETE FROM task_tags WHERE task_id = ?", t.ID).Exec()
+	if err != nil {
+		return err
+	}
+	t.Tags = Tags{}
+	for _, name := range names {
+		tag := Tag{}
+		err := tx.Where("user_id = ? AND LOWER(name) = ?", userID, strings.ToLower(name)).First(&tag)
+		if err != nil {
+			if errors.Cause(err) != sql.ErrNoRows {
+				return err
+			}
+			tag = Tag{UserID: userID, Name: name}
+			if err := tx.Create(&tag); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&TaskTag{TaskID: t.ID, TagID: tag.ID}); err != nil {
+			return err
+		}
+		t.Tags = append(t.Tags, tag)
+	}
+	return nil
+}
+
+// LoadTags loads the task's tags by name.
+func (t *Task) LoadTags(tx *pop.Connection) error {
+	t.Tags = Tags{}
+	return tx.Where("id IN (SELECT tag_id FROM task_tags WHERE task_id = ?)", t.ID).Order("name asc").All(&t.Tags)
+}
+
+// LoadTaskTags fills in the tags of each task with one query.
+func LoadTaskTags(tx *pop.Connection, tasks []Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]interface{}, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	rows := []struct {
+		TaskID int    `db:"task_id"`
+		ID     int    `db:"id"`
+		Name   string `db:"name"`
+	}{}
+	err := tx.RawQuery(`SELECT task_tags.task_id, tags.id, tags.name FROM task_tags
+		JOIN tags ON tags.id = task_tags.tag_id
+		WHERE task_tags.task_id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)
+		ORDER BY tags.name`, ids...).All(&rows)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].Tags = Tags{}
+		for _, r := range rows {
+			if r.TaskID == tasks[i].ID {
+				tasks[i].Tags = append(tasks[i].Tags, Tag{ID: r.ID, Name: r.Name})
+			}
+		}
+	}
+	return nil
+}
+
+// TasksTagged returns the tasks carrying the named tag. Their tags must be
+// loaded.
+func TasksTagged(tasks []Task, name string) []Task {
+	tagged := []Task{}
+	for _, t := range tasks {
+		if t.Tags.Has(name) {
+			tagged = append(tagged, t)
+		}
+	}
+	return tagged
+}
+
+// TagTotal is the time and earnings logged under one tag. A task with
+// several tags counts under each; untagged tasks are totalled with a null
+// TagID.
+type TagTotal struct {
+	TagID       nulls.Int `json:"tag_id" db:"tag_id"`
+	Name        string    `json:"name" db:"name"`
+	Tasks       int       `json:"tasks" db:"tasks"`
+	Minutes     int       `json:"minutes" db:"minutes"`
+	RateMinutes int       `json:"-" db:"rate_minutes"`
+}
+
+// Amount is the earnings under the tag in cents.
+func (t TagTotal) Amount() int {
+	return rateMinutesToCents(t.RateMinutes)
+}
+
+// LoadTagReport sums the tasks matching the filter per tag, by name.
+// Untagged tasks come last.
+func LoadTagReport(tx *pop.Connection, f TaskFilter) ([]TagTotal, error) {
+	where, args := f.where()
+	rows := []TagTotal{}
+	err := tx.RawQuery(`SELECT tags.id AS tag_id, COALESCE(tags.name, '') AS name,
+		COUNT(tasks.id) AS tasks,
+		COALESCE(SUM(tasks.duration), 0) AS minutes,
+		COALESCE(SUM(tasks.rate * tasks.duration), 0) AS rate_minutes
+		FROM tasks
+		JOIN contracts ON contracts.id = tasks.contract_id
+		LEFT JOIN task_tags ON task_tags.task_id = tasks.id
+		LEFT JOIN tags ON tags.id = task_tags.tag_id
+		WHERE `+where+`
+		GROUP BY tags.id, tags.name
+		ORDER BY tags.name IS NULL, tags.name`, args...).All(&rows)
+	return rows, err
+}
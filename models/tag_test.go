@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+func (ms *ModelSuite) Test_ParseTags() {
+	ms.Equal([]string{"bug fix", "Ops"}, ParseTags(" bug   fix, Ops,, ops ,"))
+	ms.Equal([]string{}, ParseTags(""))
+	ms.Equal("bug fix, Ops", TagsNamed(ParseTags("bug fix,Ops")).Input())
+	ms.True(TagsNamed([]string{"Ops"}).Has("ops"))
+}
+
+func (ms *ModelSuite) Test_Task_SetTags() {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	user := &User{Email: "tags@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+
+	first := &Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID}
+	ms.NoError(DB.Create(first))
+	second := &Task{Rate: 60, Duration: 30, StartTime: now, EndTime: now, ContractID: contract.ID}
+	ms.NoError(DB.Create(second))
+	untagged := &Task{Rate: 60, Duration: 15, StartTime: now, EndTime: now, ContractID: contract.ID}
+	ms.NoError(DB.Create(untagged))
+
+	ms.NoError(first.SetTags(DB, user.ID, ParseTags("design, ops")))
+	ms.NoError(second.SetTags(DB, user.ID, ParseTags("Ops")))
+
+	// Existing tags are reused, ignoring case.
+	tags, err := LoadTags(DB, user.ID)
+	ms.NoError(err)
+	ms.Equal("design, ops", tags.Input())
+
+	// Setting tags replaces the old ones.
+	ms.NoError(first.SetTags(DB, user.ID, ParseTags("design")))
+	ms.NoError(first.LoadTags(DB))
+	ms.Equal("design", first.Tags.Input())
+	ms.NoError(first.SetTags(DB, user.ID, ParseTags("design, ops")))
+
+	tasks := []Task{*first, *second, *untagged}
+	ms.NoError(LoadTaskTags(DB, tasks))
+	ms.Equal("design, ops", tasks[0].Tags.Input())
+	ms.Equal("ops", tasks[1].Tags.Input())
+	ms.Len(tasks[2].Tags, 0)
+	ms.Len(TasksTagged(tasks, "OPS"), 2)
+
+	report, err := LoadTagReport(DB, TaskFilter{UserID: user.ID, From: from, To: from.AddDate(0, 0, 1)})
+	ms.NoError(err)
+	ms.Len(report, 3)
+	ms.Equal("design", report[0].Name)
+	ms.Equal(90, report[0].Minutes)
+	ms.Equal("ops", report[1].Name)
+	ms.Equal(2, report[1].Tasks)
+	ms.Equal(120, report[1].Minutes)
+	ms.Equal(12000, report[1].Amount())
+	ms.False(report[2].TagID.Valid)
+	ms.Equal(15, report[2].Minutes)
+}
@@ -4,22 +4,28 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
 )
 
 // Task is used by pop to map your tasks database table to your go code.
 type Task struct {
-	ID          int       `json:"id" db:"id"`
-	Rate        int       `json:"rate" db:"rate"`
-	Description string    `json:"description" db:"description"`
-	StartTime   time.Time `json:"start_time" db:"start_time"`
-	EndTime     time.Time `json:"end_time" db:"end_time"`
-	Duration    int       `json:"duration" db:"duration"`
-	ContractID  int       `json:"-" db:"contract_id"`
-	Contract    *Contract `json:"contract" belongs_to:"contract"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          int          `json:"id" db:"id" form:"-"`
+	Rate        int          `json:"rate" db:"rate"`
+	Description string       `json:"description" db:"description"`
+	StartTime   time.Time    `json:"start_time" db:"start_time"`
+	EndTime     time.Time    `json:"end_time" db:"end_time"`
+	Duration    int          `json:"duration" db:"duration"`
+	ContractID  int          `json:"-" db:"contract_id" form:"-"`
+	Contract    *Contract    `json:"contract" belongs_to:"contract" form:"-"`
+	ExternalID  nulls.String `json:"external_id" db:"external_id"`
+	InvoiceID   nulls.Int    `json:"-" db:"invoice_id" form:"-"`
+	TimesheetID nulls.Int    `json:"-" db:"timesheet_id" form:"-"`
+	ProjectID   nulls.Int    `json:"project_id" db:"project_id" form:"-"`
+	Tags        Tags         `json:"tags,omitempty" db:"-" form:"-"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at" form:"-"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at" form:"-"`
 }
 
 // String is not required by pop and may be deleted
@@ -61,13 +67,53 @@ func (t *Task) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
 	if t.EndTime.IsZero() {
 		t.EndTime = time.Now()
 	}
-	return validate.NewErrors(), nil
+	return t.validateContract(tx)
 }
 
 // ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// Tasks on a submitted or approved timesheet cannot change.
 func (t *Task) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 	// TODO: enforce no zero duration.
-	return validate.NewErrors(), nil
+	locked, err := t.Locked(tx)
+	if err != nil {
+		return validate.NewErrors(), err
+	}
+	if locked {
+		errs := validate.NewErrors()
+		errs.Add("timesheet_id", "That task is on a submitted timesheet and cannot be changed.")
+		return errs, nil
+	}
+	return t.validateContract(tx)
+}
+
+// validateContract rejects tasks dated outside the contract's start and end
+// dates, or on a project of another contract. Time on fixed-price and
+// retainer contracts is not billed by the hour, so its rate is cleared.
+func (t *Task) validateContract(tx *pop.Connection) (*validate.Errors, error) {
+	errs := validate.NewErrors()
+	if t.ContractID == 0 {
+		return errs, nil
+	}
+	contract := &Contract{}
+	if err := tx.Find(contract, t.ContractID); err != nil {
+		return errs, err
+	}
+	if !contract.IsHourly() {
+		t.Rate = 0
+	}
+	if !contract.Covers(t.StartTime) {
+		errs.Add("start_time", "The date is outside the contract's dates ("+contract.WindowLabel()+").")
+	}
+	if t.ProjectID.Valid {
+		ok, err := tx.Where("id = ? AND contract_id = ?", t.ProjectID.Int, t.ContractID).Exists(&Project{})
+		if err != nil {
+			return errs, err
+		}
+		if !ok {
+			errs.Add("project_id", "Pick a project of this contract.")
+		}
+	}
+	return errs, nil
 }
 
 // CreateNew generates a new task setting time to Now
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// TaskFilter narrows a task listing for reports and exports. Zero values are
+// ignored; To is exclusive.
+type TaskFilter struct {
+	UserID         uuid.UUID
+	OrganizationID int
+	ContractID     int
+	BossID         int
+	ProjectID      int
+	From           time.Time
+	To             time.Time
+}
+
+// TaskRow is a flattened task with its boss, project and user, as used by
+// exports.
+type TaskRow struct {
+	ID          int       `db:"id"`
+	StartTime   time.Time `db:"start_time"`
+	EndTime     time.Time `db:"end_time"`
+	UserName    string    `db:"user_name"`
+	BossName    string    `db:"boss_name"`
+	ProjectName string    `db:"project_name"`
+	Description string    `db:"description"`
+	Duration    int       `db:"duration"`
+	Rate        int       `db:"rate"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// Amount is the earnings for the row in cents.
+func (r TaskRow) Amount() int {
+	return Earnings(r.Rate, r.Duration)
+}
+
+// where builds the SQL condition and arguments for the filter.
+func (f TaskFilter) where() (string, []interface{}) {
+	clauses := []string{}
+	args := []interface{}{}
+	if f.UserID != uuid.Nil {
+		clauses = append(clauses, "contracts.user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.OrganizationID != 0 {
+		clauses = append(clauses, "contracts.organization_id = ?")
+		args = append(args, f.OrganizationID)
+	}
+	if f.ContractID != 0 {
+		clauses = append(clauses, "tasks.contract_id = ?")
+		args = append(args, f.ContractID)
+	}
+	if f.BossID != 0 {
+		clauses = append(clauses, "contracts.boss_id = ?")
+		args = append(args, f.BossID)
+	}
+	if f.ProjectID != 0 {
+		clauses = append(clauses, "tasks.project_id = ?")
+		args = append(args, f.ProjectID)
+	}
+	if !f.From.IsZero() {
+		clauses = append(clauses, "tasks.start_time >= ?")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		clauses = append(clauses, "tasks.start_time < ?")
+		args = append(args, f.To)
+	}
+	if len(clauses) == 0 {
+		return "1 = 1", args
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+// EachRow calls fn for every task matching the filter, ordered by start time.
+// Rows are fetched in batches so large histories are never held in memory.
+func (f TaskFilter) EachRow(tx *pop.Connection, batch int, fn func(TaskRow) error) error {
+	where, args := f.where()
+	stmt := `SELECT tasks.id,
+		COALESCE(tasks.start_time, tasks.created_at) AS start_time,
+		COALESCE(tasks.end_time, tasks.created_at) AS end_time,
+		TRIM(CONCAT(users.first_name, ' ', users.last_name)) AS user_name,
+		bosses.name AS boss_name,
+		COALESCE(projects.name, '') AS project_name,
+		COALESCE(tasks.description, '') AS description,
+		COALESCE(tasks.duration, 0) AS duration, tasks.rate, tasks.updated_at
+		FROM tasks
+		JOIN contracts ON contracts.id = tasks.contract_id
+		JOIN bosses ON bosses.id = contracts.boss_id
+		JOIN users ON users.id = contracts.user_id
+		LEFT JOIN projects ON projects.id = tasks.project_id
+		WHERE ` + where + `
+		ORDER BY tasks.start_time, tasks.id
+		LIMIT ? OFFSET ?`
+
+	for offset := 0; ; offset += batch {
+		rows := []TaskRow{}
+		q := tx.RawQuery(stmt, append(args, batch, offset)...)
+		if err := q.All(&rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		if len(rows) < batch {
+			return nil
+		}
+	}
+}
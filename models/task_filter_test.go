@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_TaskFilter_Where() {
+	where, args := TaskFilter{}.where()
+	ms.Equal("1 = 1", where)
+	ms.Len(args, 0)
+
+	uid := uuid.Must(uuid.NewV4())
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := TaskFilter{UserID: uid, BossID: 3, From: from}
+	where, args = f.where()
+	ms.Equal("contracts.user_id = ? AND contracts.boss_id = ? AND tasks.start_time >= ?", where)
+	ms.Equal([]interface{}{uid, 3, from}, args)
+}
+
+func (ms *ModelSuite) Test_TaskFilter_EachRow() {
+	user := &User{Email: "export@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 40, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+
+	for i := 0; i < 5; i++ {
+		start := time.Date(2021, 3, i+1, 9, 0, 0, 0, time.UTC)
+		ms.NoError(DB.Create(&Task{Rate: 40, Duration: 30, StartTime: start, EndTime: start, ContractID: contract.ID}))
+	}
+
+	rows := []TaskRow{}
+	f := TaskFilter{UserID: user.ID, To: time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC)}
+	err = f.EachRow(DB, 2, func(r TaskRow) error {
+		rows = append(rows, r)
+		return nil
+	})
+	ms.NoError(err)
+	ms.Len(rows, 4)
+	ms.Equal("ACME", rows[0].BossName)
+	ms.Equal(2000, rows[0].Amount())
+}
+
+func (ms *ModelSuite) Test_TaskFilter_Where_Organization() {
+	where, args := TaskFilter{OrganizationID: 4, ContractID: 9}.where()
+	ms.Equal("contracts.organization_id = ? AND tasks.contract_id = ?", where)
+	ms.Equal([]interface{}{4, 9}, args)
+}
+
+func (ms *ModelSuite) Test_TaskFilter_Where_Project() {
+	where, args := TaskFilter{ContractID: 9, ProjectID: 2}.where()
+	ms.Equal("tasks.contract_id = ? AND tasks.project_id = ?", where)
+	ms.Equal([]interface{}{9, 2}, args)
+}
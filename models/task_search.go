@@ -0,0 +1,189 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gobuffalo/pop/v5"
+)
+
+// snippetWidth is roughly how many characters of a description are shown
+// around the first match.
+const snippetWidth = 160
+
+// TaskHit is a task matching a search, with its boss and contract.
+type TaskHit struct {
+	ID           int       `db:"id"`
+	ContractID   int       `db:"contract_id"`
+	StartTime    time.Time `db:"start_time"`
+	BossName     string    `db:"boss_name"`
+	ContractName string    `db:"contract_name"`
+	Description  string    `db:"description"`
+	Duration     int       `db:"duration"`
+	Score        float64   `db:"score"`
+}
+
+// Label names the hit's contract like Contract.Label.
+func (h TaskHit) Label() string {
+	if h.ContractName == "" {
+		return h.BossName
+	}
+	return h.BossName + " - " + h.ContractName
+}
+
+// SearchTerms splits a search into words, dropping the characters MySQL
+// treats as boolean operators.
+func SearchTerms(q string) []string {
+	clean := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`+-<>()~*"@`, r) {
+			return ' '
+		}
+		return r
+	}, q)
+	return strings.Fields(clean)
+}
+
+// SearchTasks finds the tasks matching the filter whose description
+// matches q, best matches first. MySQL uses the full-text index on the
+// description; other dialects fall back to matching every word with LIKE.
+func SearchTasks(tx *pop.Connection, f TaskFilter, q string, limit int) ([]TaskHit, error) {
+	terms := SearchTerms(q)
+	if len(terms) == 0 {
+		return []TaskHit{}, nil
+	}
+	if tx.Dialect.Name() == "mysql" {
+		return searchFullText(tx, f, terms, limit)
+	}
+	return searchLike(tx, f, terms, limit)
+}
+
+// searchSelect lists the columns of a TaskHit, less the score.
+const searchSelect = `SELECT tasks.id, tasks.contract_id,
+		COALESCE(tasks.start_time, tasks.created_at) AS start_time,
+		bosses.name AS boss_name, contracts.name AS contract_name,
+		COALESCE(tasks.description, '') AS description,
+		COALESCE(tasks.duration, 0) AS duration`
+
+// searchFrom joins a task to its contract and boss.
+const searchFrom = `
+		FROM tasks
+		JOIN contracts ON contracts.id = tasks.contract_id
+		JOIN bosses ON bosses.id = contracts.boss_id`
+
+func searchFullText(tx *pop.Connection, f TaskFilter, terms []string, limit int) ([]TaskHit, error) {
+	where, args := f.where()
+	q := strings.Join(terms, " ")
+	hits := []TaskHit{}
+	err := tx.RawQuery(searchSelect+`,
+		MATCH(tasks.description) AGAINST(? IN NATURAL LANGUAGE MODE) AS score`+searchFrom+`
+		WHERE `+where+` AND MATCH(tasks.description) AGAINST(? IN NATURAL LANGUAGE MODE)
+		ORDER BY score DESC, tasks.start_time DESC
+		LIMIT ?`, append(append([]interface{}{q}, args...), q, limit)...).All(&hits)
+	return hits, err
+}
+
+// likeEscaper escapes the LIKE wildcards in a search term with the
+// backslash named in the query's ESCAPE clause.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func searchLike(tx *pop.Connection, f TaskFilter, terms []string, limit int) ([]TaskHit, error) {
+	where, args := f.where()
+	for _, t := range terms {
+		where += ` AND LOWER(tasks.description) LIKE ? ESCAPE '\'`
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(t))+"%")
+	}
+	hits := []TaskHit{}
+	err := tx.RawQuery(searchSelect+`, 0 AS score`+searchFrom+`
+		WHERE `+where+`
+		ORDER BY tasks.start_time DESC
+		LIMIT ?`, append(args, limit)...).All(&hits)
+	if err != nil {
+		return nil, err
+	}
+	// Rank by how often the words appear; ties keep the newest first.
+	for i := range hits {
+		hits[i].Score = float64(countTerms(hits[i].Description, terms))
+	}
+	sort.SliceStable(hits, func(a, b int) bool { return hits[a].Score > hits[b].Score })
+	return hits, nil
+}
+
+// termsPattern matches any of the terms, ignoring case.
+func termsPattern(terms []string) *regexp.Regexp {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+func countTerms(text string, terms []string) int {
+	return len(termsPattern(terms).FindAllStringIndex(text, -1))
+}
+
+// SnippetPart is a piece of a search snippet. Match is set on the pieces
+// that matched a search term, so they can be highlighted.
+type SnippetPart struct {
+	Text  string
+	Match bool
+}
+
+// Snippet cuts the part of text around the first match of the search and
+// splits it on the matches. Long text is shortened with ellipses at word
+// boundaries.
+func Snippet(text, q string) []SnippetPart {
+	terms := SearchTerms(q)
+	text = strings.Join(strings.Fields(text), " ")
+	if len(terms) == 0 {
+		return []SnippetPart{{Text: text}}
+	}
+	re := termsPattern(terms)
+
+	if len(text) > snippetWidth {
+		start := 0
+		if loc := re.FindStringIndex(text); loc != nil && loc[0] > snippetWidth/3 {
+			start = loc[0] - snippetWidth/3
+		}
+		end := start + snippetWidth
+		if end > len(text) {
+			end, start = len(text), len(text)-snippetWidth
+		}
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		cut := text[start:end]
+		if start > 0 {
+			if i := strings.IndexByte(cut, ' '); i >= 0 {
+				cut = cut[i+1:]
+			}
+			cut = "…" + cut
+		}
+		if end < len(text) {
+			if i := strings.LastIndexByte(cut, ' '); i >= 0 {
+				cut = cut[:i]
+			}
+			cut += "…"
+		}
+		text = cut
+	}
+
+	parts := []SnippetPart{}
+	last := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		if loc[0] > last {
+			parts = append(parts, SnippetPart{Text: text[last:loc[0]]})
+		}
+		parts = append(parts, SnippetPart{Text: text[loc[0]:loc[1]], Match: true})
+		last = loc[1]
+	}
+	if last < len(text) {
+		parts = append(parts, SnippetPart{Text: text[last:]})
+	}
+	return parts
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+func (ms *ModelSuite) Test_SearchTerms() {
+	ms.Equal([]string{"db", "migration", "ACME"}, SearchTerms(`+db -migration* "ACME"`))
+	ms.Len(SearchTerms(" ( ) "), 0)
+}
+
+func (ms *ModelSuite) Test_Snippet() {
+	parts := Snippet("Ran the  migration\nfor ACME", "acme Migration")
+	ms.Equal([]SnippetPart{
+		{Text: "Ran the "},
+		{Text: "migration", Match: true},
+		{Text: " for "},
+		{Text: "ACME", Match: true},
+	}, parts)
+
+	long := strings.Repeat("word ", 60) + "migration " + strings.Repeat("word ", 60)
+	parts = Snippet(long, "migration")
+	ms.Len(parts, 3)
+	ms.True(strings.HasPrefix(parts[0].Text, "…word"))
+	ms.True(parts[1].Match)
+	ms.True(strings.HasSuffix(parts[2].Text, "word…"))
+	ms.True(len(parts[0].Text+parts[1].Text+parts[2].Text) <= snippetWidth+len("……"))
+
+	ms.Equal([]SnippetPart{{Text: "no terms"}}, Snippet("no terms", " "))
+}
+
+func (ms *ModelSuite) Test_SearchTasks() {
+	now := time.Now()
+
+	user := &User{Email: "search@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	acme := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(acme))
+	other := &Boss{Name: "Other"}
+	ms.NoError(DB.Create(other))
+	c1 := &Contract{Rate: 60, BossID: acme.ID, UserID: user.ID}
+	ms.NoError(DB.Create(c1))
+	c2 := &Contract{Rate: 60, BossID: other.ID, UserID: user.ID}
+	ms.NoError(DB.Create(c2))
+
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 30, StartTime: now, EndTime: now, ContractID: c1.ID, Description: "Database migration for billing"}))
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 30, StartTime: now, EndTime: now, ContractID: c1.ID, Description: "Migration dry run, then the real migration"}))
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 30, StartTime: now, EndTime: now, ContractID: c2.ID, Description: "Planning call"}))
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 30, StartTime: now, EndTime: now, ContractID: c2.ID, Description: "Email migration"}))
+
+	f := TaskFilter{UserID: user.ID}
+	hits, err := SearchTasks(DB, f, "migration", 10)
+	ms.NoError(err)
+	ms.Len(hits, 3)
+	ms.Equal("Migration dry run, then the real migration", hits[0].Description)
+
+	f.BossID = acme.ID
+	hits, err = SearchTasks(DB, f, "migration", 10)
+	ms.NoError(err)
+	ms.Len(hits, 2)
+	ms.Equal("ACME", hits[0].Label())
+
+	// The fallback matches every word and ranks by how often they appear.
+	hits, err = searchLike(DB, TaskFilter{UserID: user.ID}, SearchTerms("migration billing"), 10)
+	ms.NoError(err)
+	ms.Len(hits, 1)
+	hits, err = searchLike(DB, TaskFilter{UserID: user.ID}, SearchTerms("MIGRATION"), 10)
+	ms.NoError(err)
+	ms.Len(hits, 3)
+	ms.Equal(float64(2), hits[0].Score)
+
+	hits, err = SearchTasks(DB, TaskFilter{UserID: user.ID}, " ", 10)
+	ms.NoError(err)
+	ms.Len(hits, 0)
+}
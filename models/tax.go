@@ -0,0 +1,178 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Tax rounding modes. Per invoice rounds each tax once on the invoice
+// subtotal; per line rounds the tax on every task and adds the results.
+const (
+	TaxRoundInvoice = "invoice"
+	TaxRoundLine    = "line"
+)
+
+// TaxRoundings lists the rounding modes a user can choose from.
+var TaxRoundings = []string{TaxRoundInvoice, TaxRoundLine}
+
+// TaxRate is a tax a user charges, such as VAT or a state sales tax. Rate is
+// in hundredths of a percent. Inclusive rates are already part of the
+// logged amounts; exclusive rates are added on top.
+type TaxRate struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Rate      int       `json:"rate" db:"rate"`
+	Inclusive bool      `json:"inclusive" db:"inclusive"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t TaxRate) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// TaxRates is not required by pop and may be deleted
+type TaxRates []TaxRate
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (t *TaxRate) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	errs := validate.Validate(
+		&validators.StringIsPresent{Field: t.Name, Name: "Name"},
+	)
+	if t.Rate <= 0 || t.Rate > 10000 {
+		errs.Add("rate", "Rate must be between 0 and 100%.")
+	}
+	return errs, nil
+}
+
+// Label names the rate with its percentage, e.g. "VAT 20% (included)".
+func (t TaxRate) Label() string {
+	return taxLabel(t.Name, t.Rate, t.Inclusive)
+}
+
+func taxLabel(name string, rate int, inclusive bool) string {
+	s := name + " " + FormatPercent(rate)
+	if inclusive {
+		s += " (included)"
+	}
+	return s
+}
+
+// LoadTaxRates returns the user's tax rates by name.
+func LoadTaxRates(tx *pop.Connection, userID uuid.UUID) (TaxRates, error) {
+	rates := TaxRates{}
+	err := tx.Where("user_id = ?", userID).Order("name asc, id asc").All(&rates)
+	return rates, err
+}
+
+// ContractTaxRate applies a tax rate to a contract.
+type ContractTaxRate struct {
+	ID         int       `json:"id" db:"id"`
+	ContractID int       `json:"contract_id" db:"contract_id"`
+	TaxRateID  int       `json:"tax_rate_id" db:"tax_rate_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// GetTaxRates loads the tax rates applied to the contract.
+func (c *Contract) GetTaxRates(tx *pop.Connection) (TaxRates, error) {
+	rates := TaxRates{}
+	err := tx.Where("id IN (SELECT tax_rate_id FROM contract_tax_rates WHERE contract_id = ?)", c.ID).
+		Order("name asc, id asc").All(&rates)
+	return rates, err
+}
+
+// SetTaxRates replaces the contract's tax rates with the given ones, which
+// must belong to the contract's user. Unknown ids are ignored.
+func (c *Contract) SetTaxRates(tx *pop.Connection, ids []int) error {
+	err := tx.RawQuery("DELETE FROM contract_tax_rates WHERE contract_id = ?", c.ID).Exec()
+	if err != nil {
+		return err
+	}
+	owned, err := LoadTaxRates(tx, c.UserID)
+	if err != nil {
+		return err
+	}
+	for _, rate := range owned {
+		for _, id := range ids {
+			if rate.ID != id {
+				continue
+			}
+			if err := tx.Create(&ContractTaxRate{ContractID: c.ID, TaxRateID: id}); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// InvoiceTax is a tax rate copied onto an invoice when it is created, so
+// later changes to the rate do not alter issued invoices.
+type InvoiceTax struct {
+	ID        int       `json:"id" db:"id"`
+	InvoiceID int       `json:"-" db:"invoice_id"`
+	Name      string    `json:"name" db:"name"`
+	Rate      int       `json:"rate" db:"rate"`
+	Inclusive bool      `json:"inclusive" db:"inclusive"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// InvoiceTaxes is not required by pop and may be deleted
+type InvoiceTaxes []InvoiceTax
+
+// TaxLine is the tax charged for one rate, in cents.
+type TaxLine struct {
+	Name      string
+	Rate      int
+	Inclusive bool
+	Amount    int
+}
+
+// Label names the line, e.g. "VAT 20% (included)".
+func (l TaxLine) Label() string {
+	return taxLabel(l.Name, l.Rate, l.Inclusive)
+}
+
+// computeTaxes works out each tax on the given line amounts in cents.
+// Inclusive rates are backed out of the amounts first, so every tax is
+// charged on the same net base and taxes never compound.
+func computeTaxes(amounts []int, taxes InvoiceTaxes, rounding string) []TaxLine {
+	included := 0
+	for _, t := range taxes {
+		if t.Inclusive {
+			included += t.Rate
+		}
+	}
+	tax := func(amount int, rate int) float64 {
+		net := float64(amount) * 10000 / float64(10000+included)
+		return net * float64(rate) / 10000
+	}
+
+	lines := make([]TaxLine, len(taxes))
+	for i, t := range taxes {
+		lines[i] = TaxLine{Name: t.Name, Rate: t.Rate, Inclusive: t.Inclusive}
+		if rounding == TaxRoundLine {
+			for _, a := range amounts {
+				lines[i].Amount += int(math.Round(tax(a, t.Rate)))
+			}
+			continue
+		}
+		total := 0
+		for _, a := range amounts {
+			total += a
+		}
+		lines[i].Amount = int(math.Round(tax(total, t.Rate)))
+	}
+	return lines
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// TaxReportRow totals one tax across the invoices of a period, in cents.
+type TaxReportRow struct {
+	Name      string
+	Rate      int
+	Inclusive bool
+	Invoices  int
+	Taxable   int
+	Amount    int
+}
+
+// Label names the row, e.g. "VAT 20% (included)".
+func (r *TaxReportRow) Label() string {
+	return taxLabel(r.Name, r.Rate, r.Inclusive)
+}
+
+// TaxReport is the tax charged on invoices issued between From and To,
+// To being exclusive. Drafts and void invoices are left out.
+type TaxReport struct {
+	From  time.Time
+	To    time.Time
+	Rows  []*TaxReportRow
+	Total int
+}
+
+// LoadTaxReport builds the tax report for the user's invoices.
+func LoadTaxReport(tx *pop.Connection, userID uuid.UUID, from, to time.Time) (*TaxReport, error) {
+	invoices := Invoices{}
+	err := tx.Where("user_id = ? AND status NOT IN (?, ?)", userID, InvoiceDraft, InvoiceVoid).
+		Where("issued_on >= ? AND issued_on < ?", from, to).
+		Eager("Tasks", "Taxes", "Lines").Order("issued_on asc").All(&invoices)
+	if err != nil {
+		return nil, err
+	}
+	return buildTaxReport(invoices, from, to), nil
+}
+
+func buildTaxReport(invoices Invoices, from, to time.Time) *TaxReport {
+	type key struct {
+		name      string
+		rate      int
+		inclusive bool
+	}
+	rows := map[key]*TaxReportRow{}
+	rep := &TaxReport{From: from, To: to}
+
+	for i := range invoices {
+		inv := &invoices[i]
+		net := inv.Net()
+		for _, l := range inv.TaxLines() {
+			k := key{l.Name, l.Rate, l.Inclusive}
+			row, ok := rows[k]
+			if !ok {
+				row = &TaxReportRow{Name: l.Name, Rate: l.Rate, Inclusive: l.Inclusive}
+				rows[k] = row
+				rep.Rows = append(rep.Rows, row)
+			}
+			row.Invoices++
+			row.Taxable += net
+			row.Amount += l.Amount
+			rep.Total += l.Amount
+		}
+	}
+	return rep
+}
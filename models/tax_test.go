@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+func (ms *ModelSuite) Test_ComputeTaxes_Rounding() {
+	amounts := []int{1005, 1005, 1005}
+	taxes := InvoiceTaxes{{Name: "Sales tax", Rate: 1000}}
+
+	lines := computeTaxes(amounts, taxes, TaxRoundInvoice)
+	ms.Len(lines, 1)
+	ms.Equal(302, lines[0].Amount)
+
+	lines = computeTaxes(amounts, taxes, TaxRoundLine)
+	ms.Equal(303, lines[0].Amount)
+}
+
+func (ms *ModelSuite) Test_ComputeTaxes_Inclusive() {
+	taxes := InvoiceTaxes{
+		{Name: "VAT", Rate: 2000, Inclusive: true},
+		{Name: "Levy", Rate: 500},
+	}
+	lines := computeTaxes([]int{1200}, taxes, TaxRoundInvoice)
+	ms.Equal(200, lines[0].Amount)
+	ms.Equal(50, lines[1].Amount)
+	ms.Equal("VAT 20% (included)", lines[0].Label())
+	ms.Equal("Levy 5%", lines[1].Label())
+
+	inv := &Invoice{Tasks: Tasks{{Rate: 72, Duration: 10}}, Taxes: taxes}
+	ms.Equal(1200, inv.Subtotal())
+	ms.Equal(1000, inv.Net())
+	ms.Equal(250, inv.Tax())
+	ms.Equal(1250, inv.Total())
+}
+
+func (ms *ModelSuite) Test_Invoice_No_Taxes() {
+	inv := &Invoice{Tasks: Tasks{{Rate: 60, Duration: 60}}}
+	ms.Empty(inv.TaxLines())
+	ms.Equal(6000, inv.Total())
+}
+
+func (ms *ModelSuite) Test_Contract_SetTaxRates() {
+	user := &User{Email: "tax@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal(TaxRoundInvoice, user.TaxRounding)
+
+	other := &User{Email: "other-tax@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err = other.Create(DB)
+	ms.NoError(err)
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+
+	vat := &TaxRate{UserID: user.ID, Name: "VAT", Rate: 2000}
+	ms.NoError(DB.Create(vat))
+	foreign := &TaxRate{UserID: other.ID, Name: "GST", Rate: 500}
+	ms.NoError(DB.Create(foreign))
+
+	ms.NoError(contract.SetTaxRates(DB, []int{vat.ID, foreign.ID}))
+	rates, err := contract.GetTaxRates(DB)
+	ms.NoError(err)
+	ms.Len(rates, 1)
+	ms.Equal("VAT", rates[0].Name)
+
+	ms.NoError(contract.SetTaxRates(DB, nil))
+	rates, err = contract.GetTaxRates(DB)
+	ms.NoError(err)
+	ms.Empty(rates)
+
+	verrs, err = DB.ValidateAndCreate(&TaxRate{UserID: user.ID, Name: "Bad", Rate: 0})
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+}
+
+func (ms *ModelSuite) Test_BuildTaxReport() {
+	vat := InvoiceTaxes{{Name: "VAT", Rate: 2000}}
+	invoices := Invoices{
+		{Tasks: Tasks{{Rate: 60, Duration: 60}}, Taxes: vat},
+		{Tasks: Tasks{{Rate: 60, Duration: 30}}, Taxes: vat},
+		{Tasks: Tasks{{Rate: 60, Duration: 30}}},
+	}
+	rep := buildTaxReport(invoices, time.Time{}, time.Time{})
+	ms.Len(rep.Rows, 1)
+	ms.Equal(2, rep.Rows[0].Invoices)
+	ms.Equal(9000, rep.Rows[0].Taxable)
+	ms.Equal(1800, rep.Rows[0].Amount)
+	ms.Equal(1800, rep.Total)
+}
@@ -0,0 +1,271 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Timesheet statuses. A submitted timesheet locks its tasks until it is
+// reviewed; rejecting it unlocks them so they can be fixed and submitted
+// again.
+const (
+	TimesheetSubmitted = "submitted"
+	TimesheetApproved  = "approved"
+	TimesheetRejected  = "rejected"
+)
+
+// ErrNothingToSubmit is returned when a week has no tasks waiting for
+// approval.
+var ErrNothingToSubmit = errors.New("there are no tasks to submit for that week")
+
+// ErrAlreadyReviewed is returned when reviewing a timesheet twice.
+var ErrAlreadyReviewed = errors.New("timesheet has already been reviewed")
+
+// ErrCommentRequired is returned when rejecting without saying why.
+var ErrCommentRequired = errors.New("say what needs to change when rejecting")
+
+// pendingTasks matches tasks that still need to be submitted for approval.
+const pendingTasks = `tasks.invoice_id IS NULL AND (tasks.timesheet_id IS NULL
+	OR tasks.timesheet_id IN (SELECT id FROM timesheets WHERE status = 'rejected'))`
+
+// approvedTasks matches tasks that may be invoiced.
+const approvedTasks = `tasks.timesheet_id IN (SELECT id FROM timesheets WHERE status = 'approved')`
+
+// Timesheet is a week of tasks on a contract submitted for approval.
+type Timesheet struct {
+	ID          int          `json:"id" db:"id"`
+	ContractID  int          `json:"contract_id" db:"contract_id"`
+	Contract    *Contract    `json:"contract,omitempty" belongs_to:"contract"`
+	WeekStart   time.Time    `json:"week_start" db:"week_start"`
+	Status      string       `json:"status" db:"status"`
+	SubmittedAt time.Time    `json:"submitted_at" db:"submitted_at"`
+	ReviewedAt  nulls.Time   `json:"reviewed_at" db:"reviewed_at"`
+	Reviewer    nulls.String `json:"reviewer" db:"reviewer"`
+	Comment     nulls.String `json:"comment" db:"comment"`
+	Token       nulls.String `json:"-" db:"token"`
+	Tasks       Tasks        `json:"tasks,omitempty" has_many:"tasks" order_by:"start_time asc"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t Timesheet) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// Timesheets is not required by pop and may be deleted
+type Timesheets []Timesheet
+
+// WeekEnd is the Sunday ending the timesheet's week.
+func (t *Timesheet) WeekEnd() time.Time {
+	return t.WeekStart.AddDate(0, 0, 6)
+}
+
+// WeekLabel prints the timesheet's week, e.g. "Sep 6 to Sep 12, 2021".
+func (t *Timesheet) WeekLabel() string {
+	return t.WeekStart.Format("Jan 2") + " to " + t.WeekEnd().Format("Jan 2, 2006")
+}
+
+// Minutes totals the time on the timesheet.
+func (t *Timesheet) Minutes() int {
+	total := 0
+	for _, task := range t.Tasks {
+		total += task.Duration
+	}
+	return total
+}
+
+// Amount totals the earnings on the timesheet in cents.
+func (t *Timesheet) Amount() int {
+	total := 0
+	for _, task := range t.Tasks {
+		total += Earnings(task.Rate, task.Duration)
+	}
+	return total
+}
+
+// IsSubmitted reports whether the timesheet is waiting for review.
+func (t *Timesheet) IsSubmitted() bool {
+	return t.Status == TimesheetSubmitted
+}
+
+// PendingWeek is a week of tasks on a contract not yet submitted.
+type PendingWeek struct {
+	Contract  *Contract
+	WeekStart time.Time
+	Tasks     int
+	Minutes   int
+}
+
+// LoadPendingWeeks lists the weeks the user has tasks to submit in, newest
+// first.
+func LoadPendingWeeks(tx *pop.Connection, user *User) ([]PendingWeek, error) {
+	if err := user.GetContracts(tx); err != nil {
+		return nil, err
+	}
+	tasks := Tasks{}
+	err := tx.Where("contract_id IN (SELECT id FROM contracts WHERE user_id = ?)", user.ID).
+		Where(pendingTasks).All(&tasks)
+	if err != nil {
+		return nil, err
+	}
+	return pendingWeeks(tasks, user.Contracts), nil
+}
+
+// pendingWeeks groups tasks by contract and week.
+func pendingWeeks(tasks Tasks, contracts []Contract) []PendingWeek {
+	type key struct {
+		contract int
+		week     time.Time
+	}
+	byID := map[int]*Contract{}
+	for i := range contracts {
+		byID[contracts[i].ID] = &contracts[i]
+	}
+	weeks := map[key]*PendingWeek{}
+	for _, t := range tasks {
+		c, ok := byID[t.ContractID]
+		if !ok {
+			continue
+		}
+		k := key{t.ContractID, StartOfWeek(t.StartTime)}
+		w, ok := weeks[k]
+		if !ok {
+			w = &PendingWeek{Contract: c, WeekStart: k.week}
+			weeks[k] = w
+		}
+		w.Tasks++
+		w.Minutes += t.Duration
+	}
+
+	list := []PendingWeek{}
+	for _, w := range weeks {
+		list = append(list, *w)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].WeekStart.Equal(list[j].WeekStart) {
+			return list[i].WeekStart.After(list[j].WeekStart)
+		}
+		return list[i].Contract.ID < list[j].Contract.ID
+	})
+	return list
+}
+
+// SubmitTimesheet submits the contract's pending tasks in the week
+// containing week for approval.
+func SubmitTimesheet(tx *pop.Connection, contract *Contract, week, now time.Time) (*Timesheet, error) {
+	start := StartOfWeek(week)
+	end := start.AddDate(0, 0, 7)
+	where := "tasks.contract_id = ? AND tasks.start_time >= ? AND tasks.start_time < ? AND " + pendingTasks
+
+	count, err := tx.Where(where, contract.ID, start, end).Count(&Task{})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNothingToSubmit
+	}
+
+	token, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+	ts := &Timesheet{
+		ContractID:  contract.ID,
+		WeekStart:   start,
+		Status:      TimesheetSubmitted,
+		SubmittedAt: now,
+		Token:       nulls.NewString(token),
+	}
+	if err := tx.Create(ts); err != nil {
+		return nil, err
+	}
+	err = tx.RawQuery("UPDATE tasks SET timesheet_id = ? WHERE "+where, ts.ID, contract.ID, start, end).Exec()
+	return ts, err
+}
+
+// Review approves or rejects a submitted timesheet. The reviewer is the name
+// or email recorded with the decision; a rejection needs a comment.
+func (t *Timesheet) Review(tx *pop.Connection, approve bool, reviewer, comment string, now time.Time) error {
+	if !t.IsSubmitted() {
+		return ErrAlreadyReviewed
+	}
+	comment = strings.TrimSpace(comment)
+	if !approve && comment == "" {
+		return ErrCommentRequired
+	}
+
+	t.Status = TimesheetRejected
+	if approve {
+		t.Status = TimesheetApproved
+	}
+	t.ReviewedAt = nulls.NewTime(now)
+	t.Reviewer = nulls.NewString(reviewer)
+	t.Comment = nulls.String{}
+	if comment != "" {
+		t.Comment = nulls.NewString(comment)
+	}
+	// Review links work once.
+	t.Token = nulls.String{}
+	return tx.UpdateColumns(t, "status", "reviewed_at", "reviewer", "comment", "token", "updated_at")
+}
+
+// CanReview reports whether the user may approve the timesheet: a manager
+// of the contract's organization other than the person who logged it. Boss
+// contacts review through the emailed link instead.
+func (t *Timesheet) CanReview(tx *pop.Connection, userID uuid.UUID) (bool, error) {
+	contract := &Contract{}
+	if err := tx.Find(contract, t.ContractID); err != nil {
+		return false, err
+	}
+	if contract.UserID == userID || !contract.OrganizationID.Valid {
+		return false, nil
+	}
+	return tx.Where("organization_id = ? AND user_id = ? AND role IN (?, ?)",
+		contract.OrganizationID.Int, userID, RoleOwner, RoleManager).Exists(&OrganizationMember{})
+}
+
+// LoadTimesheets returns the user's timesheets, newest first.
+func LoadTimesheets(tx *pop.Connection, userID uuid.UUID) (Timesheets, error) {
+	sheets := Timesheets{}
+	err := tx.Where("contract_id IN (SELECT id FROM contracts WHERE user_id = ?)", userID).
+		Eager("Contract.Boss", "Tasks").Order("week_start desc, id desc").All(&sheets)
+	return sheets, err
+}
+
+// LoadApprovals returns the submitted timesheets the user can review as an
+// organization manager.
+func LoadApprovals(tx *pop.Connection, userID uuid.UUID) (Timesheets, error) {
+	sheets := Timesheets{}
+	err := tx.Where("status = ?", TimesheetSubmitted).
+		Where(`contract_id IN (SELECT contracts.id FROM contracts
+			JOIN organization_members ON organization_members.organization_id = contracts.organization_id
+			WHERE organization_members.user_id = ? AND organization_members.role IN (?, ?)
+			AND contracts.user_id <> ?)`, userID, RoleOwner, RoleManager, userID).
+		Eager("Contract.Boss", "Contract.User", "Tasks").Order("week_start asc, id asc").All(&sheets)
+	return sheets, err
+}
+
+// FindTimesheetByToken loads the submitted timesheet a review link points
+// to.
+func FindTimesheetByToken(tx *pop.Connection, token string) (*Timesheet, error) {
+	ts := &Timesheet{}
+	err := tx.Where("token = ? AND status = ?", token, TimesheetSubmitted).
+		Eager("Contract.Boss.Contacts", "Contract.User", "Tasks").First(ts)
+	return ts, err
+}
+
+// Locked reports whether the task, as saved, is on a timesheet that is
+// waiting for review or approved, in which case it cannot be changed.
+func (t *Task) Locked(tx *pop.Connection) (bool, error) {
+	return tx.Where("id = ? AND timesheet_id IN (SELECT id FROM timesheets WHERE status IN (?, ?))",
+		t.ID, TimesheetSubmitted, TimesheetApproved).Exists(&Task{})
+}
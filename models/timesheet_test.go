@@ -0,0 +1,129 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_Timesheet_Workflow() {
+	now := time.Now()
+
+	user := &User{Email: "timesheet@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := user.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	boss := &Boss{Name: "ACME"}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: user.ID}
+	ms.NoError(DB.Create(contract))
+	task := &Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID}
+	ms.NoError(DB.Create(task))
+	// Last week's task stays out of this week's timesheet.
+	lastWeek := now.AddDate(0, 0, -7)
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 30, StartTime: lastWeek, EndTime: lastWeek, ContractID: contract.ID}))
+
+	weeks, err := LoadPendingWeeks(DB, user)
+	ms.NoError(err)
+	ms.Len(weeks, 2)
+
+	ts, err := SubmitTimesheet(DB, contract, now, now)
+	ms.NoError(err)
+	ms.Equal(TimesheetSubmitted, ts.Status)
+	ms.True(ts.Token.Valid)
+	_, err = SubmitTimesheet(DB, contract, now, now)
+	ms.Equal(ErrNothingToSubmit, err)
+
+	ms.NoError(DB.Reload(task))
+	locked, err := task.Locked(DB)
+	ms.NoError(err)
+	ms.True(locked)
+
+	found, err := FindTimesheetByToken(DB, ts.Token.String)
+	ms.NoError(err)
+	ms.Len(found.Tasks, 1)
+
+	// A rejection needs a reason and unlocks the tasks.
+	ms.Equal(ErrCommentRequired, ts.Review(DB, false, "boss@example.com", " ", now))
+	ms.NoError(ts.Review(DB, false, "boss@example.com", "Too long", now))
+	ms.False(ts.Token.Valid)
+	ms.Equal(ErrAlreadyReviewed, ts.Review(DB, true, "boss@example.com", "", now))
+	locked, err = task.Locked(DB)
+	ms.NoError(err)
+	ms.False(locked)
+
+	ts, err = SubmitTimesheet(DB, contract, now, now)
+	ms.NoError(err)
+	ms.NoError(ts.Review(DB, true, "boss@example.com", "", now))
+
+	sheets, err := LoadTimesheets(DB, user.ID)
+	ms.NoError(err)
+	ms.Len(sheets, 2)
+
+	weeks, err = LoadPendingWeeks(DB, user)
+	ms.NoError(err)
+	ms.Len(weeks, 1)
+}
+
+func (ms *ModelSuite) Test_Timesheet_CanReview() {
+	now := time.Now()
+
+	worker := &User{Email: "worker@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err := worker.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	manager := &User{Email: "manager@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	verrs, err = manager.Create(DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	org, verrs, err := CreateOrganization(DB, "Team", manager.ID)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	_, err = org.AddMember(DB, worker.Email, RoleMember)
+	ms.NoError(err)
+
+	boss := &Boss{Name: "ACME", OrganizationID: nulls.NewInt(org.ID)}
+	ms.NoError(DB.Create(boss))
+	contract := &Contract{Rate: 60, BossID: boss.ID, UserID: worker.ID}
+	ms.NoError(DB.Create(contract))
+	ms.NoError(DB.Create(&Task{Rate: 60, Duration: 90, StartTime: now, EndTime: now, ContractID: contract.ID}))
+
+	ts, err := SubmitTimesheet(DB, contract, now, now)
+	ms.NoError(err)
+
+	ok, err := ts.CanReview(DB, manager.ID)
+	ms.NoError(err)
+	ms.True(ok)
+	ok, err = ts.CanReview(DB, worker.ID)
+	ms.NoError(err)
+	ms.False(ok)
+
+	approvals, err := LoadApprovals(DB, manager.ID)
+	ms.NoError(err)
+	ms.Len(approvals, 1)
+	approvals, err = LoadApprovals(DB, worker.ID)
+	ms.NoError(err)
+	ms.Len(approvals, 0)
+}
+
+func (ms *ModelSuite) Test_PendingWeeks() {
+	contracts := []Contract{{ID: 1}, {ID: 2}}
+	mon := time.Date(2021, 9, 6, 9, 0, 0, 0, time.UTC)
+	tasks := Tasks{
+		{ContractID: 1, Duration: 60, StartTime: mon},
+		{ContractID: 1, Duration: 30, StartTime: mon.AddDate(0, 0, 6)},
+		{ContractID: 1, Duration: 15, StartTime: mon.AddDate(0, 0, 7)},
+		{ContractID: 2, Duration: 45, StartTime: mon.AddDate(0, 0, 2)},
+		{ContractID: 3, Duration: 45, StartTime: mon},
+	}
+
+	weeks := pendingWeeks(tasks, contracts)
+	ms.Len(weeks, 3)
+	ms.Equal(time.Date(2021, 9, 13, 0, 0, 0, 0, time.UTC), weeks[0].WeekStart)
+	ms.Equal(1, weeks[1].Contract.ID)
+	ms.Equal(2, weeks[1].Tasks)
+	ms.Equal(90, weeks[1].Minutes)
+	ms.Equal(2, weeks[2].Contract.ID)
+}
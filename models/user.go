@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
@@ -16,15 +17,17 @@ import (
 
 // User is used by pop to map your users database table to your go code.
 type User struct {
-	ID                   uuid.UUID  `json:"id" db:"id"`
-	Email                string     `json:"email" db:"email"`
-	FirstName            string     `json:"first_name" db:"first_name" form:"firstname"`
-	LastName             string     `json:"last_name" db:"last_name" form:"lastname"`
-	Contracts            []Contract `json:"contracts,omitempty" has_many:"contracts"`
-	PasswordHash         string     `json:"-" db:"password_hash"`
-	Password             string     `json:"-" db:"-"`
-	PasswordConfirmation string     `json:"-" db:"-"`
-	Roles                string     `json:"roles" db:"roles"`
+	ID                   uuid.UUID    `json:"id" db:"id"`
+	Email                string       `json:"email" db:"email"`
+	FirstName            string       `json:"first_name" db:"first_name" form:"firstname"`
+	LastName             string       `json:"last_name" db:"last_name" form:"lastname"`
+	Contracts            []Contract   `json:"contracts,omitempty" has_many:"contracts"`
+	PasswordHash         string       `json:"-" db:"password_hash"`
+	Password             string       `json:"-" db:"-"`
+	PasswordConfirmation string       `json:"-" db:"-"`
+	Roles                string       `json:"roles" db:"roles"`
+	CalendarToken        nulls.String `json:"-" db:"calendar_token"`
+	TaxRounding          string       `json:"tax_rounding" db:"tax_rounding"`
 }
 
 // String is not required by pop and may be deleted
@@ -42,6 +45,9 @@ func (u *User) Create(tx *pop.Connection) (*validate.Errors, error) {
 		return validate.NewErrors(), errors.WithStack(err)
 	}
 	u.PasswordHash = string(ph)
+	if u.TaxRounding == "" {
+		u.TaxRounding = TaxRoundInvoice
+	}
 	return tx.ValidateAndCreate(u)
 }
 
@@ -139,7 +145,7 @@ func (u *User) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.NewErrors(), nil
 }
 
-// GetContracts
+// GetContracts loads all the user's contracts, archived ones included.
 func (u *User) GetContracts(tx *pop.Connection) error {
 	contracts := []Contract{}
 	q := tx.Where("user_id = ?", u.ID).Eager("Boss")
@@ -151,3 +157,32 @@ func (u *User) GetContracts(tx *pop.Connection) error {
 	u.Contracts = contracts
 	return nil
 }
+
+// GetActiveContracts loads the contracts time can still be logged on,
+// leaving archived ones out.
+func (u *User) GetActiveContracts(tx *pop.Connection) error {
+	contracts := []Contract{}
+	q := tx.Where("user_id = ? AND archived_at IS NULL", u.ID).Eager("Boss")
+	if err := q.Order("updated_at desc").All(&contracts); err != nil {
+		return err
+	}
+	u.Contracts = contracts
+	return nil
+}
+
+// ResetCalendarToken issues a new secret for the calendar feed, replacing
+// any previous one.
+func (u *User) ResetCalendarToken(tx *pop.Connection) error {
+	token, err := NewToken()
+	if err != nil {
+		return err
+	}
+	u.CalendarToken = nulls.NewString(token)
+	return tx.UpdateColumns(u, "calendar_token")
+}
+
+// RevokeCalendarToken disables the calendar feed.
+func (u *User) RevokeCalendarToken(tx *pop.Connection) error {
+	u.CalendarToken = nulls.String{}
+	return tx.UpdateColumns(u, "calendar_token")
+}
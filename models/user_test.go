@@ -3,3 +3,20 @@ package models
 func (ms *ModelSuite) Test_User() {
 	ms.Fail("This test needs to be implemented!")
 }
+
+func (ms *ModelSuite) Test_User_CalendarToken() {
+	u := &User{Email: "cal@example.com", Password: "secret", PasswordConfirmation: "secret"}
+	_, err := u.Create(DB)
+	ms.NoError(err)
+
+	ms.NoError(u.ResetCalendarToken(DB))
+	ms.True(u.CalendarToken.Valid)
+	first := u.CalendarToken.String
+
+	ms.NoError(u.ResetCalendarToken(DB))
+	ms.NotEqual(first, u.CalendarToken.String)
+
+	ms.NoError(u.RevokeCalendarToken(DB))
+	ms.NoError(DB.Reload(u))
+	ms.False(u.CalendarToken.Valid)
+}
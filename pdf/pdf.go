@@ -0,0 +1,153 @@
+// Package pdf writes simple PDF documents made of text and lines. It only
+// uses the standard fonts, so nothing needs embedding, and the output is
+// byte for byte reproducible.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a page size in points.
+type Size struct {
+	Width  float64
+	Height float64
+}
+
+// Page sizes.
+var (
+	A4     = Size{Width: 595.28, Height: 841.89}
+	Letter = Size{Width: 612, Height: 792}
+)
+
+// Document is a PDF being built in memory.
+type Document struct {
+	size  Size
+	title string
+	pages []*Page
+}
+
+// New starts an empty document with pages of the given size.
+func New(size Size) *Document {
+	return &Document{size: size}
+}
+
+// SetTitle sets the title shown by PDF readers.
+func (d *Document) SetTitle(title string) {
+	d.title = title
+}
+
+// AddPage appends a blank page and returns it.
+func (d *Document) AddPage() *Page {
+	p := &Page{size: d.size}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Pages returns the number of pages.
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// Page is a single page. Coordinates are in points from the top left corner;
+// text is positioned by its baseline.
+type Page struct {
+	size    Size
+	content bytes.Buffer
+}
+
+// Width of the page in points.
+func (p *Page) Width() float64 {
+	return p.size.Width
+}
+
+// Height of the page in points.
+func (p *Page) Height() float64 {
+	return p.size.Height
+}
+
+// Text draws s with its left edge at x.
+func (p *Page) Text(x, y float64, f Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		f+1, num(size), num(x), num(p.size.Height-y), escape(encode(s)))
+}
+
+// TextRight draws s with its right edge at x.
+func (p *Page) TextRight(x, y float64, f Font, size float64, s string) {
+	p.Text(x-TextWidth(f, size, s), y, f, size, s)
+}
+
+// Line draws a black line of the given width.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(p.size.Height-y1), num(x2), num(p.size.Height-y2))
+}
+
+// FillRect fills a rectangle with a shade of gray, 0 being black and 1 white.
+func (p *Page) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "q %s g %s %s %s %s re f Q\n",
+		num(gray), num(x), num(p.size.Height-y-h), num(w), num(h))
+}
+
+// WriteTo writes the finished document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	offsets := []int{}
+	obj := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are fixed; each page then takes two objects, the page
+	// itself followed by its content stream.
+	kids := []string{}
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (buftester) >>", escape(encode(d.title))))
+	fonts := []string{}
+	for i, name := range fontNames {
+		fonts = append(fonts, fmt.Sprintf("/F%d << /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", i+1, name))
+	}
+	obj("<< " + strings.Join(fonts, " ") + " >>")
+
+	for i, p := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font 4 0 R >> /Contents %d 0 R >>",
+			num(p.size.Width), num(p.size.Height), 6+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()))
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return b.WriteTo(w)
+}
+
+// num formats a coordinate with at most two decimals.
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// escape quotes the characters that are special inside a PDF string.
+func escape(b []byte) string {
+	var s strings.Builder
+	for _, c := range b {
+		if c == '(' || c == ')' || c == '\\' {
+			s.WriteByte('\\')
+		}
+		s.WriteByte(c)
+	}
+	return s.String()
+}
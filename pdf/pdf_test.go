@@ -0,0 +1,63 @@
+package pdf
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func Test_Document_Golden(t *testing.T) {
+	d := New(A4)
+	d.SetTitle("Sample (draft)")
+	p := d.AddPage()
+	p.Text(40, 60, HelveticaBold, 18, "Invoice")
+	p.TextRight(p.Width()-40, 60, Helvetica, 10, "Café – 12 €")
+	p.FillRect(40, 80, p.Width()-80, 16, 0.9)
+	p.Line(40, 100, p.Width()-40, 100, 0.5)
+	d.AddPage().Text(40, 60, Helvetica, 10, `Back\slash`)
+
+	var b bytes.Buffer
+	if _, err := d.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "document.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("output does not match %s, run with -update if the change is intended\n%s", golden, b.String())
+	}
+}
+
+func Test_TextWidth(t *testing.T) {
+	if w := TextWidth(Helvetica, 10, "Hi"); w != 9.44 {
+		t.Errorf("got %v, want 9.44", w)
+	}
+	if TextWidth(HelveticaBold, 10, "Hi") <= TextWidth(Helvetica, 10, "Hi") {
+		t.Error("bold text should be wider")
+	}
+}
+
+func Test_Wrap(t *testing.T) {
+	lines := Wrap(Helvetica, 10, "one two three four\nfive", 50)
+	want := []string{"one two", "three four", "five"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %q, want %q", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i, lines[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package storage keeps uploaded files under opaque keys. The application
+// talks to the Store interface so the files can live on local disk or be
+// moved to another backend later.
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when no file is stored under a key.
+var ErrNotFound = errors.New("storage: file not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or that
+// climb out of the store with "..".
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Store saves, reads and removes files by key. Keys are slash-separated
+// relative paths such as "3f/3f2a...".
+type Store interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Disk stores files in a directory on local disk.
+type Disk struct {
+	root string
+}
+
+// NewDisk returns a store keeping its files under root. The directory is
+// created on the first Put.
+func NewDisk(root string) *Disk {
+	return &Disk{root: root}
+}
+
+// path maps a key to a file under the root.
+func (d *Disk) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the contents of r under key, replacing any file already
+// there. The file is written to a temporary name first so readers never
+// see it half written.
+func (d *Disk) Put(key string, r io.Reader) error {
+	p, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".upload-")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Open returns the file stored under key. The caller closes it.
+func (d *Disk) Open(key string) (io.ReadCloser, error) {
+	p, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes the file stored under key. Deleting a missing file is
+// not an error.
+func (d *Disk) Delete(key string) error {
+	p, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Disk(t *testing.T) {
+	root, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	d := NewDisk(root)
+
+	if err := d.Put("ab/abc", strings.NewReader("receipt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put("ab/abc", strings.NewReader("signed receipt")); err != nil {
+		t.Fatal(err)
+	}
+	f, err := d.Open("ab/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "signed receipt" {
+		t.Errorf("Open = %q, want %q", b, "signed receipt")
+	}
+
+	if err := d.Delete("ab/abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Open("ab/abc"); err != ErrNotFound {
+		t.Errorf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := d.Delete("ab/abc"); err != nil {
+		t.Errorf("Delete of a missing file = %v, want nil", err)
+	}
+}
+
+func Test_Disk_InvalidKey(t *testing.T) {
+	d := NewDisk(os.TempDir())
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", `a\b`} {
+		if err := d.Put(key, strings.NewReader("x")); err != ErrInvalidKey {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := d.Open(key); err != ErrInvalidKey {
+			t.Errorf("Open(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}